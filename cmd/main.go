@@ -1,11 +1,19 @@
 package main
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"github.com/okoye-dev/flux-server/internal/config"
+	"github.com/okoye-dev/flux-server/internal/logging"
+	"github.com/okoye-dev/flux-server/internal/middleware"
+	"github.com/okoye-dev/flux-server/internal/migrations"
+	"github.com/okoye-dev/flux-server/internal/repository"
 	"github.com/okoye-dev/flux-server/internal/services"
 	"github.com/okoye-dev/flux-server/internal/transport/rest"
 )
@@ -20,49 +28,242 @@ func GetGlobalBot() *services.WhatsAppBot {
 
 func main() {
 	// Load environment variables from .env file (if it exists)
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using system environment variables")
-	}
+	envErr := godotenv.Load()
 
-	// Load configuration
+	// Load configuration and log in the configured format from here on
 	cfg := config.Load()
+	logging.Setup(cfg.Log)
+	if envErr != nil {
+		slog.Info("No .env file found, using system environment variables")
+	}
+
+	// "flux-server migrate up|down|status" manages the schema instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			fatal("Migration error", err)
+		}
+		return
+	}
+
+	// "flux-server reconcile" repairs auth accounts left without a profile, then exits
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		if err := runReconcile(cfg); err != nil {
+			fatal("Reconcile error", err)
+		}
+		return
+	}
 	
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
-		log.Fatalf("Configuration error: %v", err)
+		fatal("Configuration error", err)
+	}
+
+	// Verify access tokens with the configured JWKS keys and/or JWT secret
+	validator, err := middleware.NewTokenValidator(cfg.Supabase)
+	if err != nil {
+		fatal("Configuration error", err)
+	}
+	middleware.SetTokenValidator(validator)
+	if cfg.Supabase.JWKSURL != "" {
+		slog.Info("Verifying RS256/ES256 tokens with JWKS keys", "jwks_url", cfg.Supabase.JWKSURL)
+	}
+
+	// Refuse to start while the schema is behind the embedded migrations
+	if err := checkSchema(cfg); err != nil {
+		fatal("Schema error", err)
+	}
+
+	// Open the configured storage backend
+	store, err := repository.Open(cfg)
+	if err != nil {
+		fatal("Storage error", err)
+	}
+	slog.Info("Storage backend", "backend", cfg.Storage.Backend)
+
+	// Periodically repair auth accounts left without a profile
+	if cfg.Supabase.ReconcileInterval > 0 {
+		auth, err := services.NewSupabaseAuth(cfg.Supabase.URL, cfg.Supabase.AnonKey, cfg.Supabase.ServiceRoleKey)
+		if err != nil {
+			fatal("Reconcile error", err)
+		}
+		go services.NewReconciler(store, auth).Start(cfg.Supabase.ReconcileInterval, nil)
+		slog.Info("Account reconciliation scheduled", "interval", cfg.Supabase.ReconcileInterval.String())
 	}
 
-	// Initialize WhatsApp bot if enabled
+	// Initialize WhatsApp bot if enabled; API requests read and drop its conversations
+	var conversations services.ConversationStore
 	if cfg.WhatsApp.Enabled {
 		if cfg.WhatsApp.InstanceID == "" || cfg.WhatsApp.Token == "" {
-			log.Fatal("WhatsApp bot is enabled but missing required credentials (WHATSAPP_INSTANCE_ID or WHATSAPP_TOKEN)")
+			fatal("WhatsApp bot is enabled but missing required credentials", errors.New("WHATSAPP_INSTANCE_ID or WHATSAPP_TOKEN is not set"))
+		}
+		
+		// Farmers can delete their data from WhatsApp, which deletes their account too
+		auth, err := services.NewSupabaseAuth(cfg.Supabase.URL, cfg.Supabase.AnonKey, cfg.Supabase.ServiceRoleKey)
+		if err != nil {
+			fatal("WhatsApp bot error", err)
 		}
 		
-		log.Printf("Initializing WhatsApp bot with Instance ID: %s", cfg.WhatsApp.InstanceID)
-		globalBot = services.NewWhatsAppBot(cfg.WhatsApp.InstanceID, cfg.WhatsApp.Token)
+		slog.Info("Initializing WhatsApp bot", "instance_id", cfg.WhatsApp.InstanceID)
+		globalBot = services.NewWhatsAppBot(cfg.WhatsApp.InstanceID, cfg.WhatsApp.Token, store, auth)
+		conversations = globalBot
 		go globalBot.Start() // Start bot in a goroutine for polling
-		log.Println("WhatsApp bot started successfully and polling for messages...")
 	} else {
-		log.Println("WhatsApp bot is disabled")
+		slog.Info("WhatsApp bot is disabled")
+	}
+
+	corsOrigins := cfg.CORS.AllowedOrigins
+	if cfg.CORS.AllowPreviews {
+		corsOrigins = append(corsOrigins, cfg.CORS.PreviewOrigins...)
+	}
+	slog.Info("CORS origins", "origins", strings.Join(corsOrigins, ", "))
+	if len(cfg.Server.TrustedProxies) > 0 {
+		slog.Info("Trusting client IPs forwarded by proxies", "proxies", strings.Join(cfg.Server.TrustedProxies, ", "))
+	}
+	if cfg.RateLimit.Enabled {
+		slog.Info("Rate limits", "store", cfg.RateLimit.Store, "client", cfg.RateLimit.Client, "user", cfg.RateLimit.User)
+	} else {
+		slog.Info("Rate limiting is disabled")
+	}
+	if cfg.Metrics.Token != "" {
+		slog.Info("Prometheus metrics served at /metrics to scrapers with METRICS_TOKEN")
+	} else {
+		slog.Info("Metrics endpoint is disabled, set METRICS_TOKEN to serve /metrics")
 	}
 
 	// Create server with security middleware
 	server := &http.Server{
 		Addr:    ":" + cfg.Server.Port,
-		Handler: rest.NewSecureRouter(),
-	}
-
-	log.Printf("Starting Flux server on :%s", cfg.Server.Port)
-	log.Printf("Environment: %s", cfg.Server.Environment)
-	log.Printf("Health check available at: http://localhost:%s/health", cfg.Server.Port)
-	log.Printf("Authentication endpoints (username/password only):")
-	log.Printf("  - POST /auth/signup")
-	log.Printf("  - POST /auth/signin")
-	log.Printf("Protected endpoints:")
-	log.Printf("  - GET /profile (requires authentication)")
-	log.Printf("  - GET /protected (requires authentication)")
+		Handler: rest.NewSecureRouter(store, conversations),
+	}
+
+	slog.Info("Starting Flux server", "port", cfg.Server.Port, "environment", cfg.Server.Environment,
+		"log_level", cfg.Log.Level, "log_redact", cfg.Log.Redact)
+	slog.Info("Health check available at http://localhost:" + cfg.Server.Port + "/health")
+	slog.Debug("Authentication endpoints (username/password only)")
+	slog.Debug("POST /auth/signup")
+	slog.Debug("POST /auth/signin")
+	slog.Debug("POST /auth/refresh")
+	slog.Debug("POST /auth/otp/request, /auth/otp/verify")
+	slog.Debug("POST /auth/password/reset, /auth/password/reset/confirm")
+	slog.Debug("POST /auth/password (requires authentication)")
+	slog.Debug("POST /auth/signout, /auth/signout-all (requires authentication)")
+	slog.Debug("Catalogue endpoints")
+	slog.Debug("GET /crops, /crops/{id}, /locations, /locations/{id} (public, ?q=&match=prefix|fuzzy)")
+	slog.Debug("POST /crops, /crops/merge, /locations (admins only)")
+	slog.Debug("Protected endpoints")
+	slog.Debug("GET /profile (requires authentication)")
+	slog.Debug("GET /protected (requires authentication)")
+	slog.Debug("GET /me/export, DELETE /me (requires authentication)")
+	slog.Debug("GET/POST /farmers (admins only)")
+	slog.Debug("GET/PUT/PATCH/DELETE /farmers/{id} (admins only)")
+	slog.Debug("GET/POST /harvests (requires authentication, POST needs harvests:record)")
+	slog.Debug("GET /officer/farmers, /officer/farmers/{id} (extension officers)")
+	slog.Debug("GET/POST /officer/farmers/{id}/notes (extension officers)")
+	slog.Debug("GET /harvests/reports (extension officers and admins)")
+	slog.Debug("GET/POST /admin/api-keys, DELETE /admin/api-keys/{id} (admins only)")
+	slog.Debug("GET /admin/users, /admin/users/{id} (admins only, ?q=&role=&disabled=)")
+	slog.Debug("PUT /admin/users/{id}/role, /admin/users/{id}/location, POST /admin/users/{id}/disable, /admin/users/{id}/enable (admins only)")
+	slog.Debug("GET /admin/audit-log (admins only, ?actor_id=&target_id=&action=&request_id=&ip_address=&from=&to=)")
+	slog.Debug("GET /metrics (Prometheus, bearer METRICS_TOKEN)")
+	slog.Debug("Farmer and harvest endpoints also accept scoped API keys in the X-API-Key header")
 	
 	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Failed to start server: %v", err)
+		fatal("Failed to start server", err)
+	}
+}
+
+// fatal logs an error that stops the server from starting and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// runMigrate runs a migrate subcommand against DATABASE_URL
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrations.Usage)
+	}
+
+	runner, closeDB, err := newMigrationRunner(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	return migrations.Run(runner, args, os.Stdout)
+}
+
+// runReconcile runs one reconciliation pass and prints what it repaired
+func runReconcile(cfg *config.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if cfg.Supabase.ServiceRoleKey == "" {
+		return services.ErrServiceRoleKeyMissing
+	}
+
+	store, err := repository.Open(cfg)
+	if err != nil {
+		return err
+	}
+	auth, err := services.NewSupabaseAuth(cfg.Supabase.URL, cfg.Supabase.AnonKey, cfg.Supabase.ServiceRoleKey)
+	if err != nil {
+		return err
+	}
+
+	result, err := services.NewReconciler(store, auth).Run()
+	if err != nil {
+		return err
+	}
+	for _, failure := range result.Errors {
+		fmt.Printf("Failed %s\n", failure)
 	}
-}
\ No newline at end of file
+	fmt.Printf("Checked %d accounts: created %d profiles and %d role records, recorded %d sign-in emails, %d failed\n",
+		result.Checked, result.ProfilesCreated, result.RecordsCreated, result.EmailsRecorded, result.Failed)
+	if result.Failed > 0 {
+		return fmt.Errorf("%d account(s) could not be repaired", result.Failed)
+	}
+	return nil
+}
+
+// checkSchema returns an error when embedded migrations are pending.
+// It reads schema_migrations through DATABASE_URL, so without one startup fails
+// unless the check is skipped explicitly.
+func checkSchema(cfg *config.Config) error {
+	if cfg.Storage.Backend == repository.BackendMemory {
+		return nil
+	}
+	if cfg.Storage.SkipSchemaCheck {
+		slog.Warn("Schema check skipped (SKIP_SCHEMA_CHECK=true)")
+		return nil
+	}
+	if cfg.Storage.DatabaseURL == "" {
+		return errors.New("DATABASE_URL is required to check for pending migrations; set SKIP_SCHEMA_CHECK=true to start without the check")
+	}
+
+	runner, closeDB, err := newMigrationRunner(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if err := runner.Check(); err != nil {
+		return err
+	}
+	slog.Info("Schema is up to date")
+	return nil
+}
+
+// newMigrationRunner connects to DATABASE_URL and loads the embedded migrations
+func newMigrationRunner(cfg *config.Config) (*migrations.Runner, func() error, error) {
+	embedded, err := migrations.Embedded()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := repository.OpenPostgres(cfg.Storage.DatabaseURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	return migrations.NewRunner(db, embedded), db.Close, nil
+}
@@ -0,0 +1,9 @@
+// Package database embeds the SQL migrations so the server binary can apply them
+package database
+
+import "embed"
+
+// Migrations holds migrations/NNN_name.sql (up) and the matching migrations/NNN_name.down.sql (down)
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1,104 @@
+-- Local schema: the tables Supabase provides, for running against a plain Postgres (13+)
+-- Apply this first, then run the embedded migrations:
+--   psql "$DATABASE_URL" -f database/local/schema.sql
+--   flux-server migrate up
+
+-- Stand-ins for the Supabase auth helpers the row level security policies call.
+-- The server connects as the table owner, so the policies don't restrict it.
+CREATE SCHEMA IF NOT EXISTS auth;
+
+CREATE OR REPLACE FUNCTION auth.uid() RETURNS UUID
+    LANGUAGE sql STABLE
+    AS $$ SELECT NULLIF(current_setting('request.jwt.claim.sub', true), '')::uuid $$;
+
+CREATE OR REPLACE FUNCTION auth.role() RETURNS TEXT
+    LANGUAGE sql STABLE
+    AS $$ SELECT NULLIF(current_setting('request.jwt.claim.role', true), '') $$;
+
+-- Create roles table
+CREATE TABLE IF NOT EXISTS roles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT UNIQUE NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('farmer', 'Registered farmer'),
+    ('extension_officer', 'Extension officer assigned to a location'),
+    ('admin', 'Administrator')
+ON CONFLICT (name) DO NOTHING;
+
+-- Create user_profiles table
+-- auth_user_id points at the Supabase auth user; users still sign up through Supabase Auth,
+-- so there is no foreign key to a local auth.users table
+CREATE TABLE IF NOT EXISTS user_profiles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    auth_user_id UUID UNIQUE,
+    role_id UUID,
+    display_name TEXT,
+    phone TEXT,
+    metadata JSONB DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT fk_user_profiles_role FOREIGN KEY (role_id) REFERENCES roles(id)
+);
+
+-- Create locations table
+CREATE TABLE IF NOT EXISTS locations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    country TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create crops table
+CREATE TABLE IF NOT EXISTS crops (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    scientific_name TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create farmers table
+CREATE TABLE IF NOT EXISTS farmers (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    auth_user_id UUID,
+    name TEXT NOT NULL,
+    phone_number TEXT,
+    crop_type TEXT,
+    location_id BIGINT,
+    language TEXT DEFAULT 'en',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_farmers_auth_user_id ON farmers(auth_user_id);
+CREATE INDEX IF NOT EXISTS idx_farmers_phone_number ON farmers(phone_number);
+CREATE INDEX IF NOT EXISTS idx_farmers_location_id ON farmers(location_id);
+
+-- Create extension_officers table
+CREATE TABLE IF NOT EXISTS extension_officers (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    auth_user_id UUID,
+    name TEXT NOT NULL,
+    phone_number TEXT,
+    assigned_location_id BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_extension_officers_auth_user_id ON extension_officers(auth_user_id);
+
+-- Create farm_harvests table
+CREATE TABLE IF NOT EXISTS farm_harvests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_profile_id UUID,
+    crop_id UUID,
+    quantity NUMERIC,
+    harvested_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT fk_farm_harvests_user_profile FOREIGN KEY (user_profile_id) REFERENCES user_profiles(id) ON DELETE CASCADE,
+    CONSTRAINT fk_farm_harvests_crop FOREIGN KEY (crop_id) REFERENCES crops(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_farm_harvests_user_profile_id ON farm_harvests(user_profile_id);
+CREATE INDEX IF NOT EXISTS idx_farm_harvests_crop_id ON farm_harvests(crop_id);
@@ -0,0 +1,2 @@
+-- Rollback: Drop the users table
+DROP TABLE IF EXISTS users;
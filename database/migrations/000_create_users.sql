@@ -1,4 +1,4 @@
--- Create users table for username/password authentication
+-- Migration: Create users table for username/password authentication
 CREATE TABLE IF NOT EXISTS users (
     id TEXT PRIMARY KEY,
     username TEXT UNIQUE NOT NULL,
@@ -14,9 +14,11 @@ ALTER TABLE users ENABLE ROW LEVEL SECURITY;
 
 -- Create a policy that allows service role to access all users
 -- (This is needed for our server to authenticate users)
+DROP POLICY IF EXISTS "Service role can access all users" ON users;
 CREATE POLICY "Service role can access all users" ON users
     FOR ALL USING (auth.role() = 'service_role');
 
 -- Create a policy that allows users to read their own data
+DROP POLICY IF EXISTS "Users can read own data" ON users;
 CREATE POLICY "Users can read own data" ON users
     FOR SELECT USING (auth.uid()::text = id);
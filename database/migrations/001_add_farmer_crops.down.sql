@@ -0,0 +1,5 @@
+-- Rollback: Drop the farmer_crops junction table
+-- The seeded crops are kept, since farmers and harvests may already reference them
+DROP TABLE IF EXISTS farmer_crops;
+
+ALTER TABLE crops DROP CONSTRAINT IF EXISTS unique_crop_name;
@@ -25,10 +25,12 @@ CREATE INDEX IF NOT EXISTS idx_farmer_crops_created_at ON farmer_crops(created_a
 ALTER TABLE farmer_crops ENABLE ROW LEVEL SECURITY;
 
 -- Create policy for service role access
+DROP POLICY IF EXISTS "Service role can access all farmer_crops" ON farmer_crops;
 CREATE POLICY "Service role can access all farmer_crops" ON farmer_crops
     FOR ALL USING (auth.role() = 'service_role');
 
 -- Create policy for farmers to access their own crop data
+DROP POLICY IF EXISTS "Farmers can access own crop data" ON farmer_crops;
 CREATE POLICY "Farmers can access own crop data" ON farmer_crops
     FOR ALL USING (
         farmer_id IN (
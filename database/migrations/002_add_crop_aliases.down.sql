@@ -0,0 +1,4 @@
+-- Rollback: Drop crop_aliases and the case-insensitive crop name index
+DROP TABLE IF EXISTS crop_aliases;
+
+DROP INDEX IF EXISTS idx_crops_name_lower;
@@ -0,0 +1,48 @@
+-- Migration: Add crop_aliases so merged or alternative crop names resolve to one canonical crop
+-- e.g. "corn" and "maize" both resolve to the seeded "Maize" row
+
+CREATE TABLE IF NOT EXISTS crop_aliases (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    crop_id UUID NOT NULL,
+    alias TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    -- Foreign key constraints
+    CONSTRAINT fk_crop_aliases_crop FOREIGN KEY (crop_id) REFERENCES crops(id) ON DELETE CASCADE
+);
+
+-- Aliases are matched case-insensitively, so each lowercased alias may only point at one crop
+CREATE UNIQUE INDEX IF NOT EXISTS idx_crop_aliases_alias_lower ON crop_aliases(LOWER(alias));
+CREATE INDEX IF NOT EXISTS idx_crop_aliases_crop_id ON crop_aliases(crop_id);
+
+-- Speed up case-insensitive crop name lookups
+CREATE INDEX IF NOT EXISTS idx_crops_name_lower ON crops(LOWER(name));
+
+-- Enable Row Level Security
+ALTER TABLE crop_aliases ENABLE ROW LEVEL SECURITY;
+
+-- Create policy for service role access
+DROP POLICY IF EXISTS "Service role can access all crop_aliases" ON crop_aliases;
+CREATE POLICY "Service role can access all crop_aliases" ON crop_aliases
+    FOR ALL USING (auth.role() = 'service_role');
+
+-- Everyone may read the catalogue
+DROP POLICY IF EXISTS "Anyone can read crop_aliases" ON crop_aliases;
+CREATE POLICY "Anyone can read crop_aliases" ON crop_aliases
+    FOR SELECT USING (true);
+
+-- Seed a few common alternative names
+INSERT INTO crop_aliases (crop_id, alias)
+SELECT c.id, a.alias
+FROM crops c
+JOIN (VALUES
+    ('Maize', 'corn'),
+    ('Groundnuts', 'peanuts'),
+    ('Groundnuts', 'groundnut'),
+    ('Beans', 'cowpea'),
+    ('Tomatoes', 'tomato'),
+    ('Onions', 'onion'),
+    ('Carrots', 'carrot'),
+    ('Peppers', 'pepper')
+) AS a(crop_name, alias) ON c.name = a.crop_name
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+-- Rollback: Drop the bot feedback and visit notes tables
+DROP TABLE IF EXISTS farmer_visit_notes;
+DROP TABLE IF EXISTS farmer_feedback;
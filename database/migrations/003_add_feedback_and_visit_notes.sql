@@ -0,0 +1,48 @@
+-- Migration: Persist bot feedback and extension officer visit notes
+-- Feedback used to be logged only; officers now see it alongside the farmers in their location
+
+-- Create farmer_feedback table (feedback sent to the WhatsApp bot)
+CREATE TABLE IF NOT EXISTS farmer_feedback (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    farmer_id BIGINT,
+    phone_number TEXT,
+    message TEXT NOT NULL,
+    ai_response TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    -- Feedback from numbers that don't match a farmer is kept with a NULL farmer_id
+    CONSTRAINT fk_farmer_feedback_farmer FOREIGN KEY (farmer_id) REFERENCES farmers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_farmer_feedback_farmer_id ON farmer_feedback(farmer_id);
+CREATE INDEX IF NOT EXISTS idx_farmer_feedback_created_at ON farmer_feedback(created_at);
+
+-- Create farmer_visit_notes table (notes extension officers record about farmers)
+CREATE TABLE IF NOT EXISTS farmer_visit_notes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    farmer_id BIGINT NOT NULL,
+    officer_id BIGINT NOT NULL,
+    note TEXT NOT NULL,
+    visited_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    -- Foreign key constraints
+    CONSTRAINT fk_farmer_visit_notes_farmer FOREIGN KEY (farmer_id) REFERENCES farmers(id) ON DELETE CASCADE,
+    CONSTRAINT fk_farmer_visit_notes_officer FOREIGN KEY (officer_id) REFERENCES extension_officers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_farmer_visit_notes_farmer_id ON farmer_visit_notes(farmer_id);
+CREATE INDEX IF NOT EXISTS idx_farmer_visit_notes_officer_id ON farmer_visit_notes(officer_id);
+
+-- Enable Row Level Security
+ALTER TABLE farmer_feedback ENABLE ROW LEVEL SECURITY;
+ALTER TABLE farmer_visit_notes ENABLE ROW LEVEL SECURITY;
+
+-- Create policies for service role access
+DROP POLICY IF EXISTS "Service role can access all farmer_feedback" ON farmer_feedback;
+CREATE POLICY "Service role can access all farmer_feedback" ON farmer_feedback
+    FOR ALL USING (auth.role() = 'service_role');
+
+DROP POLICY IF EXISTS "Service role can access all farmer_visit_notes" ON farmer_visit_notes;
+CREATE POLICY "Service role can access all farmer_visit_notes" ON farmer_visit_notes
+    FOR ALL USING (auth.role() = 'service_role');
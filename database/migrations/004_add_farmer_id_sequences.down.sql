@@ -0,0 +1,13 @@
+-- Rollback: Remove the ID sequences this migration added
+-- Sequences that existed before it (identity or serial columns) are left alone
+DO $$
+DECLARE
+    tbl TEXT;
+BEGIN
+    FOREACH tbl IN ARRAY ARRAY['farmers', 'extension_officers'] LOOP
+        IF obj_description(to_regclass(tbl || '_id_seq'), 'pg_class') = 'added by 004_add_farmer_id_sequences' THEN
+            EXECUTE format('ALTER TABLE %I ALTER COLUMN id DROP DEFAULT', tbl);
+            EXECUTE format('DROP SEQUENCE %I', tbl || '_id_seq');
+        END IF;
+    END LOOP;
+END $$;
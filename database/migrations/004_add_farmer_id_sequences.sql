@@ -0,0 +1,21 @@
+-- Migration: Let the database assign farmer and extension officer IDs
+-- IDs used to be millisecond timestamps, which collide when two records are created in the same millisecond.
+-- Tables without a default get an owned sequence; either way the sequence is moved past the existing
+-- (timestamp-based) IDs, which stay valid.
+
+DO $$
+DECLARE
+    tbl TEXT;
+BEGIN
+    FOREACH tbl IN ARRAY ARRAY['farmers', 'extension_officers'] LOOP
+        IF pg_get_serial_sequence(tbl, 'id') IS NULL THEN
+            EXECUTE format('CREATE SEQUENCE IF NOT EXISTS %I', tbl || '_id_seq');
+            EXECUTE format('ALTER SEQUENCE %I OWNED BY %I.id', tbl || '_id_seq', tbl);
+            EXECUTE format('ALTER TABLE %I ALTER COLUMN id SET DEFAULT nextval(%L)', tbl, tbl || '_id_seq');
+            -- Marks the sequence so the down migration only removes what this one added
+            EXECUTE format('COMMENT ON SEQUENCE %I IS %L', tbl || '_id_seq', 'added by 004_add_farmer_id_sequences');
+        END IF;
+
+        EXECUTE format('SELECT setval(pg_get_serial_sequence(%L, ''id''), COALESCE((SELECT MAX(id) FROM %I), 0) + 1, false)', tbl, tbl);
+    END LOOP;
+END $$;
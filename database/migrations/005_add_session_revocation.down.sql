@@ -0,0 +1,3 @@
+-- Rollback: Drop the session revocation tables
+DROP TABLE IF EXISTS session_cutoffs;
+DROP TABLE IF EXISTS revoked_sessions;
@@ -0,0 +1,32 @@
+-- Migration: Server-side revocation of signed-out sessions
+-- Supabase access tokens stay valid until they expire, so signing out also records the session here
+-- and the auth middleware rejects its tokens
+
+-- Create revoked_sessions table (one row per signed-out session, kept until its tokens expire)
+CREATE TABLE IF NOT EXISTS revoked_sessions (
+    session_id UUID PRIMARY KEY,
+    auth_user_id UUID NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_sessions_expires_at ON revoked_sessions(expires_at);
+
+-- Create session_cutoffs table (tokens issued to the user at or before revoked_before are rejected)
+CREATE TABLE IF NOT EXISTS session_cutoffs (
+    auth_user_id UUID PRIMARY KEY,
+    revoked_before TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Enable Row Level Security
+ALTER TABLE revoked_sessions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE session_cutoffs ENABLE ROW LEVEL SECURITY;
+
+-- Create policies for service role access
+DROP POLICY IF EXISTS "Service role can access all revoked_sessions" ON revoked_sessions;
+CREATE POLICY "Service role can access all revoked_sessions" ON revoked_sessions
+    FOR ALL USING (auth.role() = 'service_role');
+
+DROP POLICY IF EXISTS "Service role can access all session_cutoffs" ON session_cutoffs;
+CREATE POLICY "Service role can access all session_cutoffs" ON session_cutoffs
+    FOR ALL USING (auth.role() = 'service_role');
@@ -0,0 +1,2 @@
+-- Rollback: Drop the one-time code table
+DROP TABLE IF EXISTS otp_codes;
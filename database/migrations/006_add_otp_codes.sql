@@ -0,0 +1,20 @@
+-- Migration: One-time codes for WhatsApp sign-in
+-- Farmers registered through the bot sign in to the web app with a code sent to their WhatsApp number.
+-- Only an HMAC of the code is stored, and a new request replaces the pending code.
+
+-- Create otp_codes table (at most one pending code per phone number)
+CREATE TABLE IF NOT EXISTS otp_codes (
+    phone_number VARCHAR(20) PRIMARY KEY,
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Enable Row Level Security
+ALTER TABLE otp_codes ENABLE ROW LEVEL SECURITY;
+
+-- Create policies for service role access
+DROP POLICY IF EXISTS "Service role can access all otp_codes" ON otp_codes;
+CREATE POLICY "Service role can access all otp_codes" ON otp_codes
+    FOR ALL USING (auth.role() = 'service_role');
@@ -0,0 +1,3 @@
+-- Rollback: Drop the password reset and audit log tables
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS password_reset_tokens;
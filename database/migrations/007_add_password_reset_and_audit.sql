@@ -0,0 +1,54 @@
+-- Migration: Password reset codes and the audit log
+-- Account emails are synthetic, so password reset codes are sent over WhatsApp to the phone
+-- on the user's profile. Only an HMAC of the code is stored, and a new request replaces the pending code.
+
+-- Create password_reset_tokens table (at most one pending code per user)
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    auth_user_id UUID PRIMARY KEY,
+    token_hash VARCHAR(64) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create audit_log table (security-relevant account events, e.g. password changes and resets)
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY,
+    actor_id UUID,
+    action VARCHAR(64) NOT NULL,
+    target_id UUID,
+    ip_address TEXT,
+    user_agent TEXT,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_target_id ON audit_log(target_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action);
+
+-- Copy phone numbers from farmer and officer records onto profiles created without one,
+-- so those users can receive reset codes
+UPDATE user_profiles p SET phone = f.phone_number
+FROM farmers f
+WHERE f.auth_user_id = p.auth_user_id
+  AND (p.phone IS NULL OR p.phone = '')
+  AND COALESCE(f.phone_number, '') <> '';
+
+UPDATE user_profiles p SET phone = o.phone_number
+FROM extension_officers o
+WHERE o.auth_user_id = p.auth_user_id
+  AND (p.phone IS NULL OR p.phone = '')
+  AND COALESCE(o.phone_number, '') <> '';
+
+-- Enable Row Level Security
+ALTER TABLE password_reset_tokens ENABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_log ENABLE ROW LEVEL SECURITY;
+
+-- Create policies for service role access
+DROP POLICY IF EXISTS "Service role can access all password_reset_tokens" ON password_reset_tokens;
+CREATE POLICY "Service role can access all password_reset_tokens" ON password_reset_tokens
+    FOR ALL USING (auth.role() = 'service_role');
+
+DROP POLICY IF EXISTS "Service role can access all audit_log" ON audit_log;
+CREATE POLICY "Service role can access all audit_log" ON audit_log
+    FOR ALL USING (auth.role() = 'service_role');
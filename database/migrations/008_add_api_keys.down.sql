@@ -0,0 +1,2 @@
+-- Rollback: Drop the API key table
+DROP TABLE IF EXISTS api_keys;
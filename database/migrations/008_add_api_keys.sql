@@ -0,0 +1,28 @@
+-- Migration: API keys for service-to-service calls
+-- Ingestion jobs and partners call the API with an X-API-Key header instead of a user login.
+-- Only a SHA-256 hash of each key is stored; the prefix is the key's public part, used to look it up.
+
+-- Create api_keys table
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    owner_id UUID NOT NULL,
+    prefix VARCHAR(32) NOT NULL UNIQUE,
+    key_hash VARCHAR(64) NOT NULL,
+    scopes JSONB NOT NULL DEFAULT '[]',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_owner_id ON api_keys(owner_id);
+
+-- Enable Row Level Security
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+
+-- Create policies for service role access
+DROP POLICY IF EXISTS "Service role can access all api_keys" ON api_keys;
+CREATE POLICY "Service role can access all api_keys" ON api_keys
+    FOR ALL USING (auth.role() = 'service_role');
@@ -0,0 +1,4 @@
+-- Rollback: Drop the sign-in email from profiles
+DROP INDEX IF EXISTS idx_user_profiles_phone;
+DROP INDEX IF EXISTS idx_user_profiles_email;
+ALTER TABLE user_profiles DROP COLUMN IF EXISTS email;
@@ -0,0 +1,23 @@
+-- Migration: Sign-in emails on profiles
+-- Users can sign up with a real email address instead of a synthetic username@<domain> one,
+-- and sign in with their username, email or phone number. The profile records the account's
+-- sign-in email, so usernames and phone numbers resolve to the right account whatever the
+-- synthetic domain is now.
+
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS email TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_profiles_email ON user_profiles(email);
+CREATE INDEX IF NOT EXISTS idx_user_profiles_phone ON user_profiles(phone);
+
+-- Copy the emails of existing accounts from Supabase Auth. Databases without auth.users
+-- (a plain Postgres) are filled in by `flux-server reconcile`, or at each user's next sign-in.
+DO $$
+BEGIN
+    IF to_regclass('auth.users') IS NOT NULL THEN
+        UPDATE user_profiles p SET email = lower(u.email)
+        FROM auth.users u
+        WHERE u.id = p.auth_user_id
+          AND p.email IS NULL
+          AND COALESCE(u.email, '') <> '';
+    END IF;
+END $$;
@@ -0,0 +1,2 @@
+-- Rollback: Drop the failed sign-in counters
+DROP TABLE IF EXISTS login_attempts;
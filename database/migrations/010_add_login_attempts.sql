@@ -0,0 +1,21 @@
+-- Migration: Failed sign-in counters
+-- Failed password sign-ins are counted per account and per IP address. Past a threshold the
+-- account or address is locked out, for twice as long with every further failure.
+
+-- Create login_attempts table (one counter per account, unknown login or IP address)
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key VARCHAR(320) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_locked_until ON login_attempts(locked_until);
+
+-- Enable Row Level Security
+ALTER TABLE login_attempts ENABLE ROW LEVEL SECURITY;
+
+-- Create policies for service role access
+DROP POLICY IF EXISTS "Service role can access all login_attempts" ON login_attempts;
+CREATE POLICY "Service role can access all login_attempts" ON login_attempts
+    FOR ALL USING (auth.role() = 'service_role');
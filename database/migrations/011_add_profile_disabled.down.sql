@@ -0,0 +1,3 @@
+-- Rollback: Drop the disabled flag of profiles
+DROP INDEX IF EXISTS idx_user_profiles_role_id;
+ALTER TABLE user_profiles DROP COLUMN IF EXISTS disabled_at;
@@ -0,0 +1,8 @@
+-- Migration: Disabled accounts
+-- Admins can disable an account. Supabase Auth bans it, which stops password sign-ins and
+-- token refreshes; the profile records it too, so WhatsApp code sign-ins are refused and
+-- admins can list disabled users.
+
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_user_profiles_role_id ON user_profiles(role_id);
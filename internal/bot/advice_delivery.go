@@ -2,7 +2,6 @@ package bot
 
 import (
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
@@ -27,13 +26,13 @@ func (s AdviceDeliveryScene) Start(bot *chatbot.Bot) {
 		// Get the message text
 		text, err := notification.Text()
 		if err != nil {
-			log.Printf("Error getting message text: %v", err)
+			logFor(notification).Warn("Error getting message text", "error", err)
 			return
 		}
 
 		// Check if this is from a group chat and ignore it
 		if s.isGroupChat(notification) {
-			log.Printf("Ignoring group chat message: %s", text)
+			logFor(notification).Debug("Ignoring group chat message")
 			return
 		}
 
@@ -50,7 +49,7 @@ func (s AdviceDeliveryScene) Start(bot *chatbot.Bot) {
 
 // handleAdviceRequest processes advice requests
 func (s *AdviceDeliveryScene) handleAdviceRequest(notification *chatbot.Notification) {
-	log.Printf("Processing advice request")
+	logFor(notification).Info("Processing advice request")
 	
 	// Get farmer profile from state
 	stateData := notification.GetStateData()
@@ -82,6 +81,7 @@ func (s *AdviceDeliveryScene) handleAdviceRequest(notification *chatbot.Notifica
 	notification.SetStateData(map[string]interface{}{
 		"state": STATE_WAITING_ADVICE,
 		"farmer_profile": farmerProfile,
+		conversationIDKey: conversationID(notification),
 	})
 	
 	// Send initial processing message
@@ -93,8 +93,6 @@ func (s *AdviceDeliveryScene) handleAdviceRequest(notification *chatbot.Notifica
 
 // generateAndSendAdviceWithLoading generates AI advice with loading messages
 func (s *AdviceDeliveryScene) generateAndSendAdviceWithLoading(notification *chatbot.Notification, profile FarmerProfile) {
-	log.Printf("🤖 Generating AI advice for farmer: %s", profile.Name)
-	
 	// Send only one loading message
 	notification.AnswerWithText(MSG_AI_LOADING_1)
 	time.Sleep(3 * time.Second)
@@ -105,12 +103,13 @@ func (s *AdviceDeliveryScene) generateAndSendAdviceWithLoading(notification *cha
 
 // generateAndSendAdvice generates AI advice and sends it to the farmer
 func (s *AdviceDeliveryScene) generateAndSendAdvice(notification *chatbot.Notification, profile FarmerProfile) {
-	log.Printf("🤖 Generating AI advice for farmer: %s", profile.Name)
+	logger := logFor(notification)
+	logger.Debug("Generating AI advice")
 	
 	// Fetch weather data
 	weatherData, err := s.aiService.GetWeatherData(profile.Location)
 	if err != nil {
-		log.Printf("Error fetching weather data: %v", err)
+		logger.Warn("Error fetching weather data", "error", err)
 		weatherData = &WeatherData{
 			Temperature: 25.0,
 			Humidity:    60.0,
@@ -130,7 +129,7 @@ func (s *AdviceDeliveryScene) generateAndSendAdvice(notification *chatbot.Notifi
 	
 	marketData, err := s.aiService.GetMarketData(primaryCrop, profile.Location)
 	if err != nil {
-		log.Printf("Error fetching market data: %v", err)
+		logger.Warn("Error fetching market data", "error", err)
 		marketData = &MarketData{
 			CropType: primaryCrop,
 			Price:    2.50,
@@ -153,7 +152,7 @@ func (s *AdviceDeliveryScene) generateAndSendAdvice(notification *chatbot.Notifi
 	// Call Gemini AI
 	aiResponse, err := s.aiService.CallGeminiAI(aiRequest)
 	if err != nil {
-		log.Printf("Error calling Gemini AI: %v", err)
+		logger.Error("Error calling Gemini AI", "error", err)
 		notification.AnswerWithText("❌ Sorry, I couldn't generate advice right now. Please try again later.")
 		return
 	}
@@ -170,9 +169,10 @@ func (s *AdviceDeliveryScene) generateAndSendAdvice(notification *chatbot.Notifi
 	notification.SetStateData(map[string]interface{}{
 		"state": STATE_IDLE,
 		"farmer_profile": profile,
+		conversationIDKey: conversationID(notification),
 	})
 	
-	log.Printf("✅ Advice delivered successfully to farmer: %s", profile.Name)
+	logger.Info("Advice delivered")
 }
 
 // formatAdviceMessage formats the AI response into a readable message
@@ -4,11 +4,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"strings"
 	"time"
+
+	"github.com/okoye-dev/flux-server/internal/metrics"
 )
 
 // AIService handles all AI-related operations
@@ -31,7 +32,14 @@ type Part struct {
 
 // GeminiResponse represents the response structure from Gemini API
 type GeminiResponse struct {
-	Candidates []Candidate `json:"candidates"`
+	Candidates    []Candidate   `json:"candidates"`
+	UsageMetadata UsageMetadata `json:"usageMetadata"`
+}
+
+// UsageMetadata reports the tokens a Gemini call used
+type UsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
 }
 
 // Candidate represents a candidate response from Gemini
@@ -96,6 +104,7 @@ type AIAdviceResponse struct {
 func (ai *AIService) CallGeminiAI(request AIAdviceRequest) (*AIAdviceResponse, error) {
 	apiKey := os.Getenv("API_KEY")
 	if apiKey == "" {
+		metrics.GeminiErrors.Inc("config")
 		return nil, fmt.Errorf("API_KEY environment variable not set")
 	}
 
@@ -159,17 +168,27 @@ Keep responses practical and specific to the farmer's location and crops. Use si
 
 	// Make API call to Gemini
 	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash:generateContent?key=%s", apiKey)
+	start := time.Now()
 	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
+		geminiFailed("request", start)
 		return nil, fmt.Errorf("failed to call Gemini API: %w", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		geminiFailed("status", start)
+		return nil, fmt.Errorf("Gemini API returned %s", resp.Status)
+	}
 
 	// Parse response
 	var geminiResp GeminiResponse
 	if err := json.NewDecoder(resp.Body).Decode(&geminiResp); err != nil {
+		geminiFailed("decode", start)
 		return nil, fmt.Errorf("failed to decode Gemini response: %w", err)
 	}
+	metrics.GeminiRequestDuration.ObserveSince(start, "success")
+	metrics.GeminiTokens.Add(float64(geminiResp.UsageMetadata.PromptTokenCount), "prompt")
+	metrics.GeminiTokens.Add(float64(geminiResp.UsageMetadata.CandidatesTokenCount), "completion")
 
 	// Extract AI response text
 	var aiResponse string
@@ -187,6 +206,12 @@ Keep responses practical and specific to the farmer's location and crops. Use si
 	return advice, nil
 }
 
+// geminiFailed records a failed Gemini call and how long it took
+func geminiFailed(reason string, start time.Time) {
+	metrics.GeminiRequestDuration.ObserveSince(start, "error")
+	metrics.GeminiErrors.Inc(reason)
+}
+
 // parseAIResponse parses the AI response into structured advice
 func (ai *AIService) parseAIResponse(aiResponse, cropsList string) *AIAdviceResponse {
 	// Clean up the response
@@ -293,7 +318,6 @@ func (ai *AIService) ProcessFeedback(farmerProfile FarmerProfile, feedback strin
 	response := fmt.Sprintf("Thank you for your feedback: '%s'. This information will help improve future recommendations for your %s farming in %s.", 
 		feedback, cropsList, farmerProfile.Location)
 	
-	log.Printf("✅ Feedback processed successfully")
 	return response, nil
 }
 
@@ -12,6 +12,8 @@ const (
 	CMD_STATUS    = "status"
 	CMD_HI        = "hi"
 	CMD_HEY       = "hey"
+	CMD_EXPORT    = "export"
+	CMD_DELETE    = "delete"
 )
 
 // Bot Messages
@@ -34,6 +36,8 @@ Type "help" to see all commands.`
 • "feedback" - Send feedback
 • "status" - Check your profile
 • "go" - Access our web app
+• "export" - Get a copy of your data
+• "delete" - Delete your data
 • "help" - Show this help
 • "hi" or "hey" - Greeting
 
@@ -115,6 +119,25 @@ Type "done" when you're finished adding crops.`
 
 Now, where is your farm located? (e.g., city, region, state)`
 
+	MSG_DATA_EXPORTED = `📦 Here is everything we store about you.`
+
+	MSG_NO_DATA_STORED = `ℹ️ We don't store any data about this number.`
+
+	MSG_CONFIRM_DELETION = `⚠️ *Delete your data?*
+
+This deletes your farmer profile, crops, feedback and web app account. It can't be undone.
+
+• Type "yes" to delete everything
+• Type anything else to cancel`
+
+	MSG_DATA_DELETED = `✅ Your data has been deleted.
+
+Type "register" if you'd like to start again.`
+
+	MSG_DELETION_CANCELLED = `👍 Nothing was deleted.`
+
+	MSG_DATA_REQUEST_FAILED = `❌ Sorry, I couldn't complete that right now. Please try again later.`
+
 	MSG_MARKET_INSIGHTS = `💰 *Market Insights*
 
 🌾 *Rice* in Kano markets: ₦900 per bag
@@ -153,6 +176,7 @@ const (
 	STATE_REGISTER_LANGUAGE = "register_language"
 	STATE_WAITING_ADVICE   = "waiting_advice"
 	STATE_COLLECTING_FEEDBACK = "collecting_feedback"
+	STATE_CONFIRMING_DELETION = "confirming_deletion"
 )
 
 // Demo User IDs for webapp access
@@ -0,0 +1,27 @@
+package bot
+
+import (
+	"log/slog"
+
+	"github.com/google/uuid"
+	chatbot "github.com/green-api/whatsapp-chatbot-golang"
+)
+
+// conversationIDKey is the state data key of the ID that correlates a chat's logs
+const conversationIDKey = "conversation_id"
+
+// conversationID returns the chat's conversation ID, starting a new one when the chat has none.
+// The ID stands in for the phone number in logs, so a chat can be followed without exposing it.
+func conversationID(notification *chatbot.Notification) string {
+	if id, ok := notification.GetStateData()[conversationIDKey].(string); ok && id != "" {
+		return id
+	}
+	id := uuid.NewString()
+	notification.UpdateStateData(map[string]interface{}{conversationIDKey: id})
+	return id
+}
+
+// logFor returns a logger that tags records with the chat's conversation ID
+func logFor(notification *chatbot.Notification) *slog.Logger {
+	return slog.With(conversationIDKey, conversationID(notification))
+}
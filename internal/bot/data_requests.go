@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	chatbot "github.com/green-api/whatsapp-chatbot-golang"
+)
+
+// PersonalDataStore exports and erases what is stored about a farmer's WhatsApp number
+type PersonalDataStore interface {
+	// ExportPhoneData returns the farmer's data as JSON, or nil when nothing is stored
+	ExportPhoneData(phoneNumber string) ([]byte, error)
+	// DeletePhoneData erases the farmer's data, reporting false when nothing was stored
+	DeletePhoneData(phoneNumber string) (bool, error)
+}
+
+// DataRequestScene lets farmers export or delete their data from WhatsApp
+type DataRequestScene struct {
+	dataStore PersonalDataStore
+}
+
+// NewDataRequestScene creates a new data request scene
+func NewDataRequestScene(dataStore PersonalDataStore) *DataRequestScene {
+	return &DataRequestScene{
+		dataStore: dataStore,
+	}
+}
+
+// handleExportRequest sends the farmer everything stored about them as a JSON file
+func (s *DataRequestScene) handleExportRequest(notification *chatbot.Notification) {
+	phone, ok := s.senderPhone(notification)
+	if !ok {
+		return
+	}
+
+	data, err := s.dataStore.ExportPhoneData(phone)
+	if err != nil {
+		logFor(notification).Error("Error exporting data", "error", err)
+		notification.AnswerWithText(MSG_DATA_REQUEST_FAILED)
+		return
+	}
+	if data == nil {
+		notification.AnswerWithText(MSG_NO_DATA_STORED)
+		return
+	}
+
+	// The file is uploaded from disk, so it's written to a private directory and removed after sending
+	dir, err := os.MkdirTemp("", "flux-export-")
+	if err != nil {
+		logFor(notification).Error("Error creating export directory", "error", err)
+		notification.AnswerWithText(MSG_DATA_REQUEST_FAILED)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "flux-data-export.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		logFor(notification).Error("Error writing export file", "error", err)
+		notification.AnswerWithText(MSG_DATA_REQUEST_FAILED)
+		return
+	}
+	notification.AnswerWithUploadFile(path, MSG_DATA_EXPORTED)
+}
+
+// handleDeleteRequest asks the farmer to confirm deleting their data
+func (s *DataRequestScene) handleDeleteRequest(notification *chatbot.Notification) {
+	notification.UpdateStateData(map[string]interface{}{
+		"deletion_state": STATE_CONFIRMING_DELETION,
+	})
+	notification.AnswerWithText(MSG_CONFIRM_DELETION)
+}
+
+// handleDeleteConfirmation deletes the farmer's data on "yes" and cancels on anything else
+func (s *DataRequestScene) handleDeleteConfirmation(notification *chatbot.Notification, message string) {
+	notification.UpdateStateData(map[string]interface{}{
+		"deletion_state": STATE_NONE,
+	})
+
+	if strings.ToLower(strings.TrimSpace(message)) != "yes" {
+		notification.AnswerWithText(MSG_DELETION_CANCELLED)
+		return
+	}
+
+	phone, ok := s.senderPhone(notification)
+	if !ok {
+		return
+	}
+
+	// Deleting also drops this chat's state, registration included
+	deleted, err := s.dataStore.DeletePhoneData(phone)
+	if err != nil {
+		logFor(notification).Error("Error deleting data", "error", err)
+		notification.AnswerWithText(MSG_DATA_REQUEST_FAILED)
+		return
+	}
+	if !deleted {
+		notification.AnswerWithText(MSG_NO_DATA_STORED)
+		return
+	}
+	notification.AnswerWithText(MSG_DATA_DELETED)
+}
+
+// senderPhone returns the sender's phone number, answering with an error when it's unknown
+func (s *DataRequestScene) senderPhone(notification *chatbot.Notification) (string, bool) {
+	chatID, err := notification.ChatId()
+	if err != nil {
+		logFor(notification).Warn("Error getting chat ID", "error", err)
+		notification.AnswerWithText(MSG_DATA_REQUEST_FAILED)
+		return "", false
+	}
+	return phoneFromChatID(chatID), true
+}
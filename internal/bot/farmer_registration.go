@@ -2,21 +2,27 @@ package bot
 
 import (
 	"fmt"
-	"log"
 	"strings"
 
 	chatbot "github.com/green-api/whatsapp-chatbot-golang"
 )
 
+// FarmerRegistry persists farmers who register through the bot
+type FarmerRegistry interface {
+	RegisterFarmer(phoneNumber, name string, crops []string, language string) error
+}
+
 // FarmerRegistrationScene handles farmer registration flow
 type FarmerRegistrationScene struct {
 	aiService *AIService
+	registry  FarmerRegistry
 }
 
 // NewFarmerRegistrationScene creates a new farmer registration scene
-func NewFarmerRegistrationScene(aiService *AIService) *FarmerRegistrationScene {
+func NewFarmerRegistrationScene(aiService *AIService, registry FarmerRegistry) *FarmerRegistrationScene {
 	return &FarmerRegistrationScene{
 		aiService: aiService,
+		registry:  registry,
 	}
 }
 
@@ -26,7 +32,7 @@ func (s FarmerRegistrationScene) Start(bot *chatbot.Bot) {
 		// Get the message text
 		text, err := notification.Text()
 		if err != nil {
-			log.Printf("Error getting message text: %v", err)
+			logFor(notification).Warn("Error getting message text", "error", err)
 			return
 		}
 
@@ -65,24 +71,21 @@ func (s FarmerRegistrationScene) Start(bot *chatbot.Bot) {
 
 // startRegistration initiates the registration process
 func (s *FarmerRegistrationScene) startRegistration(notification *chatbot.Notification) {
-	log.Printf("DEBUG: Starting farmer registration")
+	logFor(notification).Debug("Starting farmer registration")
 	notification.AnswerWithText("🌱 Great! Let's register you as a farmer.\n\nWhat's your full name?")
 	notification.UpdateStateData(map[string]interface{}{
 		"registration_state": STATE_REGISTER_NAME,
 	})
-	log.Printf("DEBUG: Set registration state to: %s", STATE_REGISTER_NAME)
 }
 
 // handleName processes the name input
 func (s *FarmerRegistrationScene) HandleName(notification *chatbot.Notification, name string) {
-	log.Printf("DEBUG: HandleName called with: '%s'", name)
 	if strings.TrimSpace(name) == "" {
-		log.Printf("DEBUG: Empty name provided")
 		notification.AnswerWithText("Please enter your full name.")
 		return
 	}
 	
-	log.Printf("DEBUG: Setting name to: '%s' and state to: %s", name, STATE_REGISTER_CROP)
+	logFor(notification).Debug("Registration step", "registration_state", STATE_REGISTER_CROP)
 	notification.UpdateStateData(map[string]interface{}{
 		"name": name,
 		"registration_state": STATE_REGISTER_CROP,
@@ -92,9 +95,7 @@ func (s *FarmerRegistrationScene) HandleName(notification *chatbot.Notification,
 
 // handleCrop processes the crop input
 func (s *FarmerRegistrationScene) HandleCrop(notification *chatbot.Notification, crop string) {
-	log.Printf("DEBUG: HandleCrop called with: '%s'", crop)
 	if strings.TrimSpace(crop) == "" {
-		log.Printf("DEBUG: Empty crop provided")
 		notification.AnswerWithText("Please tell me what crop you grow.")
 		return
 	}
@@ -102,7 +103,7 @@ func (s *FarmerRegistrationScene) HandleCrop(notification *chatbot.Notification,
 	// Store the first crop
 	crops := []string{crop}
 	
-	log.Printf("DEBUG: Setting first crop to: '%s' and state to: %s", crop, STATE_REGISTER_MORE_CROPS)
+	logFor(notification).Debug("Registration step", "registration_state", STATE_REGISTER_MORE_CROPS)
 	notification.UpdateStateData(map[string]interface{}{
 		"crops": crops,
 		"registration_state": STATE_REGISTER_MORE_CROPS,
@@ -112,12 +113,10 @@ func (s *FarmerRegistrationScene) HandleCrop(notification *chatbot.Notification,
 
 // handleMoreCrops processes additional crop inputs
 func (s *FarmerRegistrationScene) HandleMoreCrops(notification *chatbot.Notification, response string) {
-	log.Printf("DEBUG: HandleMoreCrops called with: '%s'", response)
-	
 	stateData := notification.GetStateData()
 	crops, ok := stateData["crops"].([]string)
 	if !ok {
-		log.Printf("DEBUG: No crops found in state, resetting")
+		logFor(notification).Warn("No crops found in registration state, resetting")
 		notification.AnswerWithText("Something went wrong. Please start registration again with 'register'.")
 		notification.UpdateStateData(map[string]interface{}{"registration_state": STATE_NONE})
 		return
@@ -135,7 +134,7 @@ func (s *FarmerRegistrationScene) HandleMoreCrops(notification *chatbot.Notifica
 	if response == "no" || response == "done" {
 		// Move to location registration
 		cropsList := strings.Join(crops, ", ")
-		log.Printf("DEBUG: Final crops list: %s, moving to location", cropsList)
+		logFor(notification).Debug("Registration step", "registration_state", STATE_REGISTER_LOCATION, "crops", len(crops))
 		notification.UpdateStateData(map[string]interface{}{
 			"registration_state": STATE_REGISTER_LOCATION,
 		})
@@ -151,7 +150,6 @@ func (s *FarmerRegistrationScene) HandleMoreCrops(notification *chatbot.Notifica
 	
 	// Add the new crop to the list
 	crops = append(crops, response)
-	log.Printf("DEBUG: Added crop '%s', total crops: %v", response, crops)
 	
 	// Update state with new crop list
 	notification.UpdateStateData(map[string]interface{}{
@@ -165,14 +163,12 @@ func (s *FarmerRegistrationScene) HandleMoreCrops(notification *chatbot.Notifica
 
 // handleLocation processes the location input
 func (s *FarmerRegistrationScene) HandleLocation(notification *chatbot.Notification, location string) {
-	log.Printf("DEBUG: HandleLocation called with: '%s'", location)
 	if strings.TrimSpace(location) == "" {
-		log.Printf("DEBUG: Empty location provided")
 		notification.AnswerWithText("Please tell me your farm location.")
 		return
 	}
 	
-	log.Printf("DEBUG: Setting location to: '%s' and state to: %s", location, STATE_REGISTER_LANGUAGE)
+	logFor(notification).Debug("Registration step", "registration_state", STATE_REGISTER_LANGUAGE)
 	notification.UpdateStateData(map[string]interface{}{
 		"location": location,
 		"registration_state": STATE_REGISTER_LANGUAGE,
@@ -182,9 +178,7 @@ func (s *FarmerRegistrationScene) HandleLocation(notification *chatbot.Notificat
 
 // handleLanguage processes the language input and completes registration
 func (s *FarmerRegistrationScene) HandleLanguage(notification *chatbot.Notification, language string) {
-	log.Printf("DEBUG: HandleLanguage called with: '%s'", language)
 	if strings.TrimSpace(language) == "" {
-		log.Printf("DEBUG: Empty language provided")
 		notification.AnswerWithText("Please tell me your preferred language.")
 		return
 	}
@@ -203,12 +197,21 @@ func (s *FarmerRegistrationScene) HandleLanguage(notification *chatbot.Notificat
 	}
 	location := stateData["location"].(string)
 	
+	phone, err := s.registerFarmer(notification, name, crops, language)
+	if err != nil {
+		logFor(notification).Error("Error saving farmer registration", "error", err)
+		notification.AnswerWithText("Sorry, we couldn't save your registration. Please try again with 'register'.")
+		notification.UpdateStateData(map[string]interface{}{"registration_state": STATE_NONE})
+		return
+	}
+	
 	// Save farmer profile
 	farmerProfile := map[string]interface{}{
 		"name": name,
 		"crops": crops,
 		"location": location,
 		"language": language,
+		"phone": phone,
 	}
 	
 	// Update state with complete profile
@@ -219,7 +222,7 @@ func (s *FarmerRegistrationScene) HandleLanguage(notification *chatbot.Notificat
 	})
 	
 	// Send completion message
-	log.Printf("DEBUG: Registration completed for %s, resetting state to NONE", name)
+	logFor(notification).Info("Farmer registration completed")
 	cropsList := strings.Join(crops, ", ")
 	completionMessage := fmt.Sprintf(`✅ Registration Complete!
 
@@ -238,6 +241,24 @@ Welcome to Farm Assistant! 🌱`, name, cropsList, location, language)
 	notification.AnswerWithText(completionMessage)
 }
 
+// registerFarmer saves the farmer against the sender's phone number so the web app
+// can sign them in with the same identity; it returns that phone number
+func (s *FarmerRegistrationScene) registerFarmer(notification *chatbot.Notification, name string, crops []string, language string) (string, error) {
+	chatID, err := notification.ChatId()
+	if err != nil {
+		return "", err
+	}
+	phone := phoneFromChatID(chatID)
+	
+	if s.registry == nil {
+		// No database configured, the registration only lives in bot state
+		logFor(notification).Warn("Farmer registry not configured, registration was only kept in bot state")
+		return phone, nil
+	}
+	
+	return phone, s.registry.RegisterFarmer(phone, name, crops, language)
+}
+
 // isGroupChat checks if the message is from a group chat
 func (s *FarmerRegistrationScene) isGroupChat(notification *chatbot.Notification) bool {
 	body := notification.Body
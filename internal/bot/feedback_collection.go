@@ -1,21 +1,27 @@
 package bot
 
 import (
-	"log"
 	"strings"
 
 	chatbot "github.com/green-api/whatsapp-chatbot-golang"
 )
 
+// FeedbackStore persists feedback farmers send to the bot
+type FeedbackStore interface {
+	SaveFeedback(phoneNumber, message, aiResponse string) error
+}
+
 // FeedbackCollectionScene handles feedback collection flow
 type FeedbackCollectionScene struct {
-	aiService *AIService
+	aiService     *AIService
+	feedbackStore FeedbackStore
 }
 
 // NewFeedbackCollectionScene creates a new feedback collection scene
-func NewFeedbackCollectionScene(aiService *AIService) *FeedbackCollectionScene {
+func NewFeedbackCollectionScene(aiService *AIService, feedbackStore FeedbackStore) *FeedbackCollectionScene {
 	return &FeedbackCollectionScene{
-		aiService: aiService,
+		aiService:     aiService,
+		feedbackStore: feedbackStore,
 	}
 }
 
@@ -25,13 +31,13 @@ func (s FeedbackCollectionScene) Start(bot *chatbot.Bot) {
 		// Get the message text
 		text, err := notification.Text()
 		if err != nil {
-			log.Printf("Error getting message text: %v", err)
+			logFor(notification).Warn("Error getting message text", "error", err)
 			return
 		}
 
 		// Check if this is from a group chat and ignore it
 		if s.isGroupChat(notification) {
-			log.Printf("Ignoring group chat message: %s", text)
+			logFor(notification).Debug("Ignoring group chat message")
 			return
 		}
 
@@ -48,11 +54,11 @@ func (s FeedbackCollectionScene) Start(bot *chatbot.Bot) {
 
 // handleFeedbackRequest processes feedback requests
 func (s *FeedbackCollectionScene) handleFeedbackRequest(notification *chatbot.Notification, message string) {
-	log.Printf("Processing feedback request: %s", message)
+	logFor(notification).Info("Processing feedback request")
 	
 	// Get farmer profile from state
 	stateData := notification.GetStateData()
-	farmerProfile, ok := stateData["farmer_profile"].(FarmerProfile)
+	farmerProfile, ok := farmerProfileFromState(stateData)
 	if !ok {
 		// If no profile found, ask to register first
 		notification.AnswerWithText("❌ Please register first using 'register' to provide feedback.")
@@ -71,6 +77,7 @@ func (s *FeedbackCollectionScene) handleFeedbackRequest(notification *chatbot.No
 	notification.SetStateData(map[string]interface{}{
 		"state": STATE_COLLECTING_FEEDBACK,
 		"farmer_profile": farmerProfile,
+		conversationIDKey: conversationID(notification),
 	})
 	
 	// Process the feedback
@@ -79,20 +86,20 @@ func (s *FeedbackCollectionScene) handleFeedbackRequest(notification *chatbot.No
 
 // processAndStoreFeedback processes and stores farmer feedback
 func (s *FeedbackCollectionScene) processAndStoreFeedback(notification *chatbot.Notification, profile FarmerProfile, feedback string) {
-	log.Printf("📝 Processing feedback from %s: %s", profile.Name, feedback)
+	logger := logFor(notification)
 	
 	// Process feedback with AI
 	aiResponse, err := s.aiService.ProcessFeedback(profile, feedback)
 	if err != nil {
-		log.Printf("Error processing feedback: %v", err)
+		logger.Error("Error processing feedback", "error", err)
 		notification.AnswerWithText("❌ Error processing your feedback. Please try again.")
 		return
 	}
 	
-	// Store feedback (in a real app, this would go to database)
-	err = s.storeFeedback(profile, feedback, aiResponse)
+	// Store feedback so extension officers can see it
+	err = s.storeFeedback(notification, profile, feedback, aiResponse)
 	if err != nil {
-		log.Printf("Error storing feedback: %v", err)
+		logger.Error("Error storing feedback", "error", err)
 		notification.AnswerWithText("❌ Error saving your feedback. Please try again.")
 		return
 	}
@@ -104,9 +111,10 @@ func (s *FeedbackCollectionScene) processAndStoreFeedback(notification *chatbot.
 	notification.SetStateData(map[string]interface{}{
 		"state": STATE_IDLE,
 		"farmer_profile": profile,
+		conversationIDKey: conversationID(notification),
 	})
 	
-	log.Printf("✅ Feedback processed and stored for farmer: %s", profile.Name)
+	logger.Info("Feedback processed and stored")
 }
 
 // extractFeedbackContent extracts feedback content from the message
@@ -143,14 +151,22 @@ func (s *FeedbackCollectionScene) extractFeedbackContent(message string) string
 	return feedback
 }
 
-// storeFeedback stores feedback in the system (dummy implementation)
-func (s *FeedbackCollectionScene) storeFeedback(profile FarmerProfile, feedback, aiResponse string) error {
-	log.Printf("💾 Storing feedback for farmer %s: %s", profile.Name, feedback)
+// storeFeedback stores feedback against the sender's phone number
+func (s *FeedbackCollectionScene) storeFeedback(notification *chatbot.Notification, profile FarmerProfile, feedback, aiResponse string) error {
+	if s.feedbackStore == nil {
+		// No database configured, the feedback is only acknowledged
+		logFor(notification).Warn("Feedback store not configured, feedback was not stored")
+		return nil
+	}
 	
-	// In a real implementation, this would save to database
-	// For now, just log the feedback
-	log.Printf("✅ Feedback stored successfully")
+	chatID, err := notification.ChatId()
+	if err != nil {
+		return err
+	}
 	
+	if err := s.feedbackStore.SaveFeedback(phoneFromChatID(chatID), feedback, aiResponse); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -168,3 +184,35 @@ func (s *FeedbackCollectionScene) isGroupChat(notification *chatbot.Notification
 	
 	return false
 }
+
+// farmerProfileFromState reads the farmer profile from bot state.
+// Registration stores it as a map while the advice flow stores a FarmerProfile.
+func farmerProfileFromState(stateData map[string]interface{}) (FarmerProfile, bool) {
+	switch profile := stateData["farmer_profile"].(type) {
+	case FarmerProfile:
+		return profile, true
+	case map[string]interface{}:
+		farmerProfile := FarmerProfile{
+			Name:     getStringFromMap(profile, "name"),
+			Location: getStringFromMap(profile, "location"),
+			Language: getStringFromMap(profile, "language"),
+			Phone:    getStringFromMap(profile, "phone"),
+		}
+		if crops, ok := profile["crops"].([]string); ok {
+			farmerProfile.Crops = crops
+		} else if crop, ok := profile["crop"].(string); ok {
+			farmerProfile.Crops = []string{crop}
+		}
+		return farmerProfile, true
+	default:
+		return FarmerProfile{}, false
+	}
+}
+
+// phoneFromChatID turns a WhatsApp chat ID ("2348012345678@c.us") into a phone number ("+2348012345678")
+func phoneFromChatID(chatID string) string {
+	if idx := strings.Index(chatID, "@"); idx >= 0 {
+		chatID = chatID[:idx]
+	}
+	return "+" + chatID
+}
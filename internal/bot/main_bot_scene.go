@@ -2,12 +2,12 @@ package bot
 
 import (
 	"fmt"
-	"log"
 	"math/rand"
 	"strings"
 	"time"
 
 	chatbot "github.com/green-api/whatsapp-chatbot-golang"
+	"github.com/okoye-dev/flux-server/internal/metrics"
 )
 
 // MainBotScene handles the main bot flow and command routing
@@ -16,15 +16,17 @@ type MainBotScene struct {
 	registrationScene      *FarmerRegistrationScene
 	adviceScene           *AdviceDeliveryScene
 	feedbackScene         *FeedbackCollectionScene
+	dataRequestScene      *DataRequestScene
 }
 
 // NewMainBotScene creates a new main bot scene
-func NewMainBotScene(aiService *AIService) *MainBotScene {
+func NewMainBotScene(aiService *AIService, feedbackStore FeedbackStore, registry FarmerRegistry, dataStore PersonalDataStore) *MainBotScene {
 	return &MainBotScene{
 		aiService:         aiService,
-		registrationScene: NewFarmerRegistrationScene(aiService),
+		registrationScene: NewFarmerRegistrationScene(aiService, registry),
 		adviceScene:      NewAdviceDeliveryScene(aiService),
-		feedbackScene:    NewFeedbackCollectionScene(aiService),
+		feedbackScene:    NewFeedbackCollectionScene(aiService, feedbackStore),
+		dataRequestScene: NewDataRequestScene(dataStore),
 	}
 }
 
@@ -33,9 +35,10 @@ func NewMainBotScene(aiService *AIService) *MainBotScene {
 func (s MainBotScene) Start(bot *chatbot.Bot) {
 	bot.IncomingMessageHandler(func(notification *chatbot.Notification) {
 		// Get the message text
+		logger := logFor(notification)
 		text, err := notification.Text()
 		if err != nil {
-			log.Printf("Error getting message text: %v", err)
+			logger.Warn("Error getting message text", "error", err)
 			return
 		}
 
@@ -48,17 +51,20 @@ func (s MainBotScene) Start(bot *chatbot.Bot) {
 		stateData := notification.GetStateData()
 		registrationState := stateData["registration_state"]
 		
-		log.Printf("DEBUG: Message '%s' - Current registration state: %v", text, registrationState)
-		
 		// If user is in the middle of registration, let the registration scene handle it
 		if registrationState != nil && registrationState != STATE_NONE {
-			log.Printf("DEBUG: User is in registration state %v, handling ongoing registration", registrationState)
+			logger.Debug("Continuing registration", "registration_state", registrationState)
 			// Handle ongoing registration directly
 			s.handleOngoingRegistration(notification, text)
 			return
 		}
 		
-		log.Printf("DEBUG: No ongoing registration, processing as normal command")
+		// A pending deletion takes the reply as its confirmation
+		if stateData["deletion_state"] == STATE_CONFIRMING_DELETION {
+			s.dataRequestScene.handleDeleteConfirmation(notification, text)
+			return
+		}
+		
 
 		// Convert to lowercase for case-insensitive matching
 		actualMessage := strings.ToLower(strings.TrimSpace(text))
@@ -72,28 +78,47 @@ func (s MainBotScene) Start(bot *chatbot.Bot) {
 func (s *MainBotScene) routeCommand(notification *chatbot.Notification, message string) {
 	
 	// Handle different commands
+	var command string
 	switch {
+	// Data requests must be asked for outright, so they're matched before looser commands
+	case strings.HasPrefix(message, CMD_EXPORT):
+		command = CMD_EXPORT
+		s.dataRequestScene.handleExportRequest(notification)
+	case strings.HasPrefix(message, CMD_DELETE):
+		command = CMD_DELETE
+		s.dataRequestScene.handleDeleteRequest(notification)
 	case strings.Contains(message, CMD_START) || message == "":
+		command = CMD_START
 		s.handleStart(notification)
 	case strings.Contains(message, CMD_HI) || strings.Contains(message, CMD_HEY):
+		command = CMD_HI
 		s.handleGreeting(notification)
 	case strings.Contains(message, CMD_REGISTER):
+		command = CMD_REGISTER
 		s.registrationScene.startRegistration(notification)
 	case strings.Contains(message, CMD_ADVICE):
+		command = CMD_ADVICE
 		s.adviceScene.handleAdviceRequest(notification)
 	case strings.Contains(message, CMD_MARKET):
+		command = CMD_MARKET
 		s.handleMarket(notification)
 	case strings.Contains(message, CMD_GO):
+		command = CMD_GO
 		s.handleGo(notification)
 	case strings.Contains(message, CMD_FEEDBACK):
+		command = CMD_FEEDBACK
 		s.feedbackScene.handleFeedbackRequest(notification, message)
 	case strings.Contains(message, CMD_HELP):
+		command = CMD_HELP
 		s.handleHelp(notification)
 	case strings.Contains(message, CMD_STATUS):
+		command = CMD_STATUS
 		s.handleStatus(notification)
 	default:
+		command = "unknown"
 		s.handleInvalidCommand(notification)
 	}
+	metrics.BotMessages.Inc(command)
 }
 
 // handleStart handles the start command
@@ -102,7 +127,7 @@ func (s *MainBotScene) handleStart(notification *chatbot.Notification) {
 	sender, err := notification.Sender()
 	if err != nil {
 		sender = "there"
-		log.Printf("Error getting sender: %v", err)
+		logFor(notification).Warn("Error getting sender", "error", err)
 	}
 	
 	welcomeMessage := fmt.Sprintf("Hey, %s! %s", sender, MSG_WELCOME)
@@ -115,7 +140,7 @@ func (s *MainBotScene) handleGreeting(notification *chatbot.Notification) {
 	sender, err := notification.Sender()
 	if err != nil {
 		sender = "there"
-		log.Printf("Error getting sender: %v", err)
+		logFor(notification).Warn("Error getting sender", "error", err)
 	}
 	
 	greeting := fmt.Sprintf("Hey %s! 👋\n\n%s", sender, MSG_WELCOME)
@@ -218,26 +243,19 @@ func (s *MainBotScene) handleOngoingRegistration(notification *chatbot.Notificat
 	stateData := notification.GetStateData()
 	currentState := stateData["registration_state"]
 
-	log.Printf("DEBUG: Handling ongoing registration - State: %v, Message: '%s'", currentState, text)
-
 	switch currentState {
 	case STATE_REGISTER_NAME:
-		log.Printf("DEBUG: Processing name input: '%s'", text)
 		s.registrationScene.HandleName(notification, text)
 	case STATE_REGISTER_CROP:
-		log.Printf("DEBUG: Processing crop input: '%s'", text)
 		s.registrationScene.HandleCrop(notification, text)
 	case STATE_REGISTER_MORE_CROPS:
-		log.Printf("DEBUG: Processing more crops input: '%s'", text)
 		s.registrationScene.HandleMoreCrops(notification, text)
 	case STATE_REGISTER_LOCATION:
-		log.Printf("DEBUG: Processing location input: '%s'", text)
 		s.registrationScene.HandleLocation(notification, text)
 	case STATE_REGISTER_LANGUAGE:
-		log.Printf("DEBUG: Processing language input: '%s'", text)
 		s.registrationScene.HandleLanguage(notification, text)
 	default:
-		log.Printf("DEBUG: Unknown registration state %v, resetting", currentState)
+		logFor(notification).Warn("Unknown registration state, resetting", "registration_state", currentState)
 		// Unknown state, reset to main menu
 		notification.UpdateStateData(map[string]interface{}{"registration_state": STATE_NONE})
 		notification.AnswerWithText("Registration reset. Type 'register' to start again.")
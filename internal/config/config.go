@@ -1,8 +1,16 @@
 package config
 
 import (
+	"net/netip"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/okoye-dev/flux-server/internal/ratelimit"
 )
 
 // Config holds all configuration for our application
@@ -10,12 +18,20 @@ type Config struct {
 	Server     ServerConfig
 	Supabase   SupabaseConfig
 	WhatsApp   WhatsAppConfig
+	Storage    StorageConfig
+	RateLimit  RateLimitConfig
+	CORS       CORSConfig
+	Log        LogConfig
+	Metrics    MetricsConfig
 }
 
 // ServerConfig holds server-related configuration
 type ServerConfig struct {
 	Port        string
 	Environment string
+	// TrustedProxies are the CIDRs or IP addresses of the reverse proxies in front of the
+	// server; only they may set the client IP through Forwarded or X-Forwarded-For
+	TrustedProxies []string
 }
 
 // SupabaseConfig holds Supabase-related configuration
@@ -24,6 +40,20 @@ type SupabaseConfig struct {
 	AnonKey         string
 	ServiceRoleKey  string
 	JWTSecret       string
+	// JWKSURL is where the public keys for RS256/ES256 tokens are fetched from; an http(s)
+	// or file:// URL. Without it only HS256 tokens signed with JWTSecret are accepted.
+	JWKSURL string
+	// JWTIssuer and JWTAudience are the iss and aud claims tokens must carry
+	JWTIssuer   string
+	JWTAudience string
+	// EmailDomain is the domain of the synthetic emails (username@domain) given to accounts
+	// that sign up without a real email address
+	EmailDomain string
+	// LegacyEmailDomains are synthetic domains earlier deployments used; accounts created
+	// with them still sign in by username and still show their username
+	LegacyEmailDomains []string
+	// ReconcileInterval runs the orphaned account repair job periodically; zero disables it
+	ReconcileInterval time.Duration
 }
 
 // WhatsAppConfig holds WhatsApp bot configuration
@@ -34,18 +64,98 @@ type WhatsAppConfig struct {
 	Enabled    bool
 }
 
-// Load loads configuration from environment variables
+// StorageConfig selects where application data is stored
+type StorageConfig struct {
+	Backend     string // "supabase" (default), "postgres" or "memory"
+	DatabaseURL string // Postgres DSN, required by the "postgres" backend and by "migrate"
+	// SkipSchemaCheck starts the server even when migrations are pending
+	SkipSchemaCheck bool
+}
+
+// RateLimitConfig configures the API rate limits. Limits are written as requests per
+// period, e.g. "120/m", "1000/h" or "5/30s"; "off" turns one off.
+type RateLimitConfig struct {
+	Enabled  bool
+	Store    string // "memory" (default, per instance) or "redis" (shared between instances)
+	RedisURL string // redis:// or rediss:// URL, required by the "redis" store
+	Client   string // Per client IP, on routes without a limit of their own
+	User     string // Per signed-in user or API key
+	// Routes are comma-separated route=limit pairs, e.g. "POST /auth/signin=10/m"; a route
+	// limit replaces the client limit on that route
+	Routes string
+}
+
+// CORSConfig configures which web apps may call the API from a browser
+type CORSConfig struct {
+	// AllowedOrigins are origins such as https://app.example.com, or patterns where * stands
+	// for part of one hostname label, e.g. https://*.example.com. "*" allows any origin,
+	// without credentials.
+	AllowedOrigins []string
+	// PreviewOrigins are patterns for preview deployments, e.g. Vercel's
+	// https://flux-farm-app-*-yourteam.vercel.app; they're allowed when AllowPreviews is set
+	PreviewOrigins []string
+	AllowPreviews  bool // Defaults to true outside production
+	// AllowCredentials lets browsers send cookies and read responses to credentialed requests
+	AllowCredentials bool
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string // Response headers browsers let the web app read
+	MaxAge           time.Duration
+}
+
+// LogConfig configures the server's structured logs
+type LogConfig struct {
+	Level  string // "debug", "info" (default), "warn" or "error"
+	Format string // "json" (default) or "text"
+	// Redact masks phone numbers and drops message bodies; turn it off only to debug locally
+	Redact bool
+}
+
+// MetricsConfig configures the Prometheus metrics endpoint
+type MetricsConfig struct {
+	// Token is the bearer token scrapers must send to read /metrics; without one the
+	// endpoint isn't served
+	Token string
+}
+
+// minMetricsTokenLength keeps the metrics token from being guessable
+const minMetricsTokenLength = 16
+
+// defaultRateLimitRoutes keeps password guessing and code requests well below the client limit
+const defaultRateLimitRoutes = "/auth/signin=10/m,/auth/signup=5/m,/auth/refresh=30/m," +
+	"/auth/otp/request=3/m,/auth/otp/verify=10/m," +
+	"/auth/password/reset=3/m,/auth/password/reset/confirm=10/m"
+
+// The settings file named by CONFIG_FILE, read once per process
+var (
+	fileOnce   sync.Once
+	fileValues map[string]string
+	fileErr    error
+)
+
+// Load loads configuration from environment variables and, for variables that aren't set,
+// from the KEY=VALUE file named by CONFIG_FILE
 func Load() *Config {
+	supabaseURL := getEnv("SUPABASE_URL", "")
+	environment := getEnv("ENVIRONMENT", "development")
+
 	return &Config{
 		Server: ServerConfig{
 			Port:        getEnv("PORT", "8080"),
-			Environment: getEnv("ENVIRONMENT", "development"),
+			Environment: environment,
+			TrustedProxies: getEnvAsList("TRUSTED_PROXIES", nil),
 		},
 		Supabase: SupabaseConfig{
-			URL:            getEnv("SUPABASE_URL", ""),
+			URL:            supabaseURL,
 			AnonKey:        getEnv("SUPABASE_ANON_KEY", ""),
 			ServiceRoleKey: getEnv("SUPABASE_SERVICE_ROLE_KEY", ""),
 			JWTSecret:      getEnv("JWT_SECRET", ""),
+			JWKSURL:        getEnv("JWKS_URL", ""),
+			JWTIssuer:      getEnv("JWT_ISSUER", supabaseURL+"/auth/v1"),
+			JWTAudience:    getEnv("JWT_AUDIENCE", "authenticated"),
+			EmailDomain:    strings.ToLower(getEnv("AUTH_EMAIL_DOMAIN", "fluxapp.com")),
+			LegacyEmailDomains: getEnvAsList("AUTH_LEGACY_EMAIL_DOMAINS", []string{"fluxapp.com"}),
+			ReconcileInterval: getEnvAsDuration("RECONCILE_INTERVAL", 0),
 		},
 		WhatsApp: WhatsAppConfig{
 			APIURL:     getEnv("API_URL", ""),
@@ -53,23 +163,193 @@ func Load() *Config {
 			Token:      getEnv("WHATSAPP_TOKEN", ""),
 			Enabled:    getEnvAsBool("WHATSAPP_ENABLED", false),
 		},
+		Storage: StorageConfig{
+			Backend:         getEnv("STORAGE_BACKEND", "supabase"),
+			DatabaseURL:     getEnv("DATABASE_URL", ""),
+			SkipSchemaCheck: getEnvAsBool("SKIP_SCHEMA_CHECK", false),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:  getEnvAsBool("RATE_LIMIT_ENABLED", true),
+			Store:    getEnv("RATE_LIMIT_STORE", "memory"),
+			RedisURL: getEnv("REDIS_URL", ""),
+			Client:   getEnv("RATE_LIMIT_CLIENT", "120/m"),
+			User:     getEnv("RATE_LIMIT_USER", "60/m"),
+			Routes:   getEnv("RATE_LIMIT_ROUTES", defaultRateLimitRoutes),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   getEnvAsList("CORS_ORIGINS", []string{"http://localhost:3000", "http://localhost:3002", "http://localhost:8080"}),
+			PreviewOrigins:   getEnvAsList("CORS_PREVIEW_ORIGINS", nil),
+			AllowPreviews:    getEnvAsBool("CORS_ALLOW_PREVIEWS", environment != "production"),
+			AllowCredentials: getEnvAsBool("CORS_ALLOW_CREDENTIALS", true),
+			AllowedMethods:   getEnvAsNames("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+			AllowedHeaders:   getEnvAsNames("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization", "X-Requested-With", "X-API-Key", "X-Request-ID"}),
+			ExposedHeaders:   getEnvAsNames("CORS_EXPOSED_HEADERS", []string{"Content-Disposition", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "X-Request-ID"}),
+			MaxAge:           getEnvAsDuration("CORS_MAX_AGE", 24*time.Hour),
+		},
+		Log: LogConfig{
+			Level:  strings.ToLower(getEnv("LOG_LEVEL", "info")),
+			Format: strings.ToLower(getEnv("LOG_FORMAT", "json")),
+			Redact: getEnvAsBool("LOG_REDACT", true),
+		},
+		Metrics: MetricsConfig{
+			Token: getEnv("METRICS_TOKEN", ""),
+		},
 	}
 }
 
 // Validate checks if required configuration is present
 func (c *Config) Validate() error {
+	if _, err := loadFile(); err != nil {
+		return &ConfigError{Field: "CONFIG_FILE", Message: "Config file can't be read: " + err.Error()}
+	}
 	if c.Supabase.URL == "" {
 		return &ConfigError{Field: "SUPABASE_URL", Message: "Supabase URL is required"}
 	}
 	if c.Supabase.AnonKey == "" {
 		return &ConfigError{Field: "SUPABASE_ANON_KEY", Message: "Supabase anon key is required"}
 	}
-	if c.Supabase.JWTSecret == "" {
-		return &ConfigError{Field: "JWT_SECRET", Message: "JWT secret is required for secure token validation"}
+	if c.Storage.Backend != "supabase" && c.Storage.Backend != "postgres" && c.Storage.Backend != "memory" {
+		return &ConfigError{Field: "STORAGE_BACKEND", Message: "Storage backend must be 'supabase', 'postgres' or 'memory'"}
+	}
+	if c.Storage.Backend == "postgres" && c.Storage.DatabaseURL == "" {
+		return &ConfigError{Field: "DATABASE_URL", Message: "Database URL is required for the postgres storage backend"}
+	}
+	if c.Supabase.ReconcileInterval > 0 && c.Supabase.ServiceRoleKey == "" {
+		return &ConfigError{Field: "SUPABASE_SERVICE_ROLE_KEY", Message: "Supabase service role key is required when RECONCILE_INTERVAL is set"}
+	}
+	if c.Supabase.EmailDomain == "" || strings.ContainsAny(c.Supabase.EmailDomain, "@ ") || !strings.Contains(c.Supabase.EmailDomain, ".") {
+		return &ConfigError{Field: "AUTH_EMAIL_DOMAIN", Message: "Auth email domain must be a domain name such as fluxapp.com"}
+	}
+	if c.Supabase.JWTSecret == "" && c.Supabase.JWKSURL == "" {
+		return &ConfigError{Field: "JWT_SECRET", Message: "JWT secret or JWKS URL is required for secure token validation"}
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if !isIPOrCIDR(proxy) {
+			return &ConfigError{Field: "TRUSTED_PROXIES", Message: "Trusted proxies must be IP addresses or CIDRs such as 10.0.0.0/8, got " + proxy}
+		}
+	}
+	if c.RateLimit.Enabled {
+		if err := c.RateLimit.validate(); err != nil {
+			return err
+		}
+	}
+	if err := c.CORS.validate(); err != nil {
+		return err
+	}
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		return &ConfigError{Field: "LOG_LEVEL", Message: "Log level must be 'debug', 'info', 'warn' or 'error'"}
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		return &ConfigError{Field: "LOG_FORMAT", Message: "Log format must be 'json' or 'text'"}
+	}
+	if c.Metrics.Token != "" && len(c.Metrics.Token) < minMetricsTokenLength {
+		return &ConfigError{Field: "METRICS_TOKEN", Message: "Metrics token must be at least 16 characters"}
+	}
+	return nil
+}
+
+// validate checks the rate limit store and limits
+func (c RateLimitConfig) validate() error {
+	switch c.Store {
+	case "memory":
+	case "redis":
+		if err := ratelimit.ValidateRedisURL(c.RedisURL); err != nil {
+			return &ConfigError{Field: "REDIS_URL", Message: "Redis URL is required for the redis rate limit store: " + err.Error()}
+		}
+	default:
+		return &ConfigError{Field: "RATE_LIMIT_STORE", Message: "Rate limit store must be 'memory' or 'redis'"}
+	}
+	if _, err := ratelimit.ParsePolicy(c.Client); err != nil {
+		return &ConfigError{Field: "RATE_LIMIT_CLIENT", Message: err.Error()}
+	}
+	if _, err := ratelimit.ParsePolicy(c.User); err != nil {
+		return &ConfigError{Field: "RATE_LIMIT_USER", Message: err.Error()}
+	}
+	if _, err := ratelimit.ParseRoutePolicies(c.Routes); err != nil {
+		return &ConfigError{Field: "RATE_LIMIT_ROUTES", Message: err.Error()}
 	}
 	return nil
 }
 
+// validate checks the CORS origins, methods, headers and max age
+func (c CORSConfig) validate() error {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				return &ConfigError{Field: "CORS_ORIGINS", Message: "CORS origin * allows any site, so it needs CORS_ALLOW_CREDENTIALS=false"}
+			}
+			continue
+		}
+		if !isOriginPattern(origin) {
+			return &ConfigError{Field: "CORS_ORIGINS", Message: "CORS origins must look like https://app.example.com or https://*.example.com, got " + origin}
+		}
+	}
+	for _, origin := range c.PreviewOrigins {
+		if !isOriginPattern(origin) {
+			return &ConfigError{Field: "CORS_PREVIEW_ORIGINS", Message: "CORS preview origins must look like https://flux-*-yourteam.vercel.app, got " + origin}
+		}
+	}
+	for _, method := range c.AllowedMethods {
+		if !isToken(method) {
+			return &ConfigError{Field: "CORS_ALLOWED_METHODS", Message: "CORS methods must be HTTP methods such as GET, got " + method}
+		}
+	}
+	for _, header := range c.AllowedHeaders {
+		if !isToken(header) {
+			return &ConfigError{Field: "CORS_ALLOWED_HEADERS", Message: "CORS headers must be header names such as Authorization, got " + header}
+		}
+	}
+	for _, header := range c.ExposedHeaders {
+		if !isToken(header) {
+			return &ConfigError{Field: "CORS_EXPOSED_HEADERS", Message: "CORS exposed headers must be header names such as Retry-After, got " + header}
+		}
+	}
+	if c.MaxAge < 0 {
+		return &ConfigError{Field: "CORS_MAX_AGE", Message: "CORS max age can't be negative"}
+	}
+	return nil
+}
+
+// isOriginPattern reports whether value is a scheme and host, optionally with a port, where
+// a single * may stand for part of the hostname
+func isOriginPattern(value string) bool {
+	star := strings.Index(value, "*")
+	if strings.Count(value, "*") > 1 {
+		return false
+	}
+	u, err := url.Parse(strings.Replace(value, "*", "x", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return false
+	}
+	// An origin is nothing but scheme://host[:port]
+	if u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" || strings.HasSuffix(value, "/") {
+		return false
+	}
+	hostStart := len(u.Scheme) + len("://")
+	return star == -1 || (star >= hostStart && star < hostStart+len(u.Hostname()))
+}
+
+// isToken reports whether value is an HTTP token, as method and header names are
+func isToken(value string) bool {
+	for _, c := range value {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", c)) {
+			return false
+		}
+	}
+	return value != ""
+}
+
+// isIPOrCIDR reports whether value is an IP address or a CIDR
+func isIPOrCIDR(value string) bool {
+	if _, err := netip.ParsePrefix(value); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(value)
+	return err == nil
+}
+
 // ConfigError represents a configuration error
 type ConfigError struct {
 	Field   string
@@ -80,9 +360,28 @@ func (e *ConfigError) Error() string {
 	return e.Message
 }
 
+// loadFile reads the settings file named by CONFIG_FILE, if any, the first time it's called
+func loadFile() (map[string]string, error) {
+	fileOnce.Do(func() {
+		if path := os.Getenv("CONFIG_FILE"); path != "" {
+			fileValues, fileErr = godotenv.Read(path)
+		}
+	})
+	return fileValues, fileErr
+}
+
+// lookupEnv gets an environment variable, falling back to its value in the settings file
+func lookupEnv(key string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	values, _ := loadFile()
+	return values[key]
+}
+
 // getEnv gets an environment variable with a fallback value
 func getEnv(key, fallback string) string {
-	if value := os.Getenv(key); value != "" {
+	if value := lookupEnv(key); value != "" {
 		return value
 	}
 	return fallback
@@ -90,7 +389,7 @@ func getEnv(key, fallback string) string {
 
 // getEnvAsInt gets an environment variable as integer with a fallback value
 func getEnvAsInt(key string, fallback int) int {
-	if value := os.Getenv(key); value != "" {
+	if value := lookupEnv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
 			return intValue
 		}
@@ -100,10 +399,50 @@ func getEnvAsInt(key string, fallback int) int {
 
 // getEnvAsBool gets an environment variable as boolean with a fallback value
 func getEnvAsBool(key string, fallback bool) bool {
-	if value := os.Getenv(key); value != "" {
+	if value := lookupEnv(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
 			return boolValue
 		}
 	}
 	return fallback
 }
+
+// getEnvAsDuration gets an environment variable as a duration (e.g. "1h") with a fallback value
+func getEnvAsDuration(key string, fallback time.Duration) time.Duration {
+	if value := lookupEnv(key); value != "" {
+		if durationValue, err := time.ParseDuration(value); err == nil {
+			return durationValue
+		}
+	}
+	return fallback
+}
+
+// getEnvAsNames gets a comma-separated environment variable as a list, keeping its case, with a fallback value
+func getEnvAsNames(key string, fallback []string) []string {
+	value := lookupEnv(key)
+	if value == "" {
+		return fallback
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvAsList gets a comma-separated environment variable as a lowercased list with a fallback value
+func getEnvAsList(key string, fallback []string) []string {
+	value := lookupEnv(key)
+	if value == "" {
+		return fallback
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
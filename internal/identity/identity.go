@@ -0,0 +1,167 @@
+// Package identity maps what users sign in with, a username, an email address or a phone
+// number, onto Supabase Auth accounts. Accounts are keyed by email; users who sign up
+// without a real address get a synthetic one, username@<domain>, with a configurable domain.
+package identity
+
+import (
+	"errors"
+	"strings"
+)
+
+// Kind is the type of a login identifier
+type Kind string
+
+const (
+	KindUsername Kind = "username"
+	KindEmail    Kind = "email"
+	KindPhone    Kind = "phone"
+)
+
+// Identifier is a parsed login identifier
+type Identifier struct {
+	Kind Kind
+	// Value is the username as typed, the email lowercased or the phone number as typed
+	Value string
+}
+
+// Identifier errors
+var (
+	ErrEmpty           = errors.New("identifier is empty")
+	ErrInvalidEmail    = errors.New("invalid email address")
+	ErrInvalidUsername = errors.New("usernames can't contain @ or spaces, or be a phone number")
+)
+
+// Parse classifies an identifier: anything with an @ is an email address, digits with an
+// optional leading + (spaces, dashes and brackets allowed) are a phone number, and
+// anything else is a username
+func Parse(raw string) (Identifier, error) {
+	value := strings.TrimSpace(raw)
+	switch {
+	case value == "":
+		return Identifier{}, ErrEmpty
+	case strings.Contains(value, "@"):
+		email := strings.ToLower(value)
+		if !ValidEmail(email) {
+			return Identifier{}, ErrInvalidEmail
+		}
+		return Identifier{Kind: KindEmail, Value: email}, nil
+	case looksLikePhone(value):
+		return Identifier{Kind: KindPhone, Value: value}, nil
+	default:
+		return Identifier{Kind: KindUsername, Value: value}, nil
+	}
+}
+
+// ValidEmail does the basic shape check Supabase Auth would otherwise reject an address for
+func ValidEmail(email string) bool {
+	local, domain, ok := strings.Cut(email, "@")
+	return ok && local != "" && !strings.ContainsAny(email, " \t\r\n") &&
+		!strings.Contains(domain, "@") && strings.Contains(domain, ".") &&
+		!strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
+}
+
+// ValidUsername reports whether a username can't be mistaken for another kind of identifier
+func ValidUsername(username string) error {
+	if strings.ContainsAny(username, "@ \t\r\n") || looksLikePhone(username) {
+		return ErrInvalidUsername
+	}
+	return nil
+}
+
+// Mask hides most of an identifier, for records such as the audit log that shouldn't
+// hold users' contact details: phone numbers keep their last four digits, emails the
+// first letter and the domain, and usernames the first letter
+func Mask(raw string) string {
+	id, err := Parse(raw)
+	if err != nil {
+		return "***"
+	}
+	switch id.Kind {
+	case KindPhone:
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, id.Value)
+		return "***" + digits[len(digits)-4:]
+	case KindEmail:
+		local, domain, _ := strings.Cut(id.Value, "@")
+		return firstRune(local) + "***@" + domain
+	default:
+		return firstRune(id.Value) + "***"
+	}
+}
+
+// firstRune returns the first character of a non-empty string
+func firstRune(value string) string {
+	for _, r := range value {
+		return string(r)
+	}
+	return ""
+}
+
+// looksLikePhone reports whether a value is a phone number rather than a username
+func looksLikePhone(value string) bool {
+	digits := 0
+	for i, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r == '+' && i == 0:
+		case r == ' ' || r == '-' || r == '(' || r == ')':
+		default:
+			return false
+		}
+	}
+	return digits >= 7
+}
+
+// Domain builds and recognises synthetic account emails
+type Domain struct {
+	current string
+	legacy  []string
+}
+
+// NewDomain creates a Domain that gives new accounts username@current and still recognises
+// the synthetic emails of accounts created with any of the legacy domains
+func NewDomain(current string, legacy ...string) *Domain {
+	d := &Domain{current: strings.ToLower(current)}
+	for _, domain := range legacy {
+		if domain = strings.ToLower(domain); domain != "" && domain != d.current {
+			d.legacy = append(d.legacy, domain)
+		}
+	}
+	return d
+}
+
+// Email returns the synthetic email for a username. Supabase stores emails lowercased.
+func (d *Domain) Email(username string) string {
+	return strings.ToLower(username) + "@" + d.current
+}
+
+// LegacyEmails returns the synthetic emails a username had under the legacy domains
+func (d *Domain) LegacyEmails(username string) []string {
+	emails := make([]string, 0, len(d.legacy))
+	for _, domain := range d.legacy {
+		emails = append(emails, strings.ToLower(username)+"@"+domain)
+	}
+	return emails
+}
+
+// Username returns the username of a synthetic email, and false for real addresses
+func (d *Domain) Username(email string) (string, bool) {
+	local, domain, ok := strings.Cut(strings.ToLower(email), "@")
+	if !ok || local == "" {
+		return "", false
+	}
+	if domain == d.current {
+		return local, true
+	}
+	for _, legacy := range d.legacy {
+		if domain == legacy {
+			return local, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,129 @@
+// Package logging sets up the server's structured logs. Records are JSON (or text) from
+// log/slog, and phone numbers and message bodies are redacted unless configured otherwise.
+package logging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/okoye-dev/flux-server/internal/config"
+)
+
+// Attribute keys with a meaning for redaction; log phone numbers and texts under them
+const (
+	PhoneKey = "phone"   // Masked down to its last digits
+	TextKey  = "text"    // Message bodies and other free text, dropped entirely
+	ChatKey  = "chat_id" // WhatsApp chat IDs contain the phone number
+)
+
+// phoneKeys and textKeys are the attribute keys redacted, including the ones older code uses
+var (
+	phoneKeys = map[string]bool{PhoneKey: true, "phone_number": true, ChatKey: true, "sender": true}
+	textKeys  = map[string]bool{TextKey: true, "message_text": true, "body": true, "feedback": true}
+)
+
+// phoneCandidate finds words containing a long run of digits; those that are nothing but
+// the digits are phone numbers or WhatsApp chat IDs, while UUIDs and the like are left alone
+var phoneCandidate = regexp.MustCompile(`[+\w-]*\d{9,15}[\w-]*`)
+
+// ErrInvalidLevel is returned for log levels ParseLevel can't read
+var ErrInvalidLevel = errors.New("log level must be debug, info, warn or error")
+
+// ParseLevel reads a log level name
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, ErrInvalidLevel
+	}
+	return level, nil
+}
+
+// Setup makes a logger for the configuration the default for both log/slog and the
+// standard log package, so remaining log.Printf calls are structured and redacted too
+func Setup(cfg config.LogConfig) *slog.Logger {
+	logger := slog.New(NewHandler(os.Stderr, cfg))
+	slog.SetDefault(logger)
+	log.SetFlags(0)
+	return logger
+}
+
+// NewHandler creates a slog handler writing the configured format and level to w
+func NewHandler(w io.Writer, cfg config.LogConfig) slog.Handler {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		level = slog.LevelInfo
+	}
+	options := &slog.HandlerOptions{Level: level}
+	if cfg.Redact {
+		options.ReplaceAttr = redact
+	}
+	if cfg.Format == "text" {
+		return slog.NewTextHandler(w, options)
+	}
+	return slog.NewJSONHandler(w, options)
+}
+
+// redact masks phone numbers and drops message bodies, whether they're logged under a
+// known key or appear in the message or another string
+func redact(_ []string, attr slog.Attr) slog.Attr {
+	switch {
+	case phoneKeys[attr.Key]:
+		return slog.String(attr.Key, maskPhone(attr.Value.String()))
+	case textKeys[attr.Key]:
+		return slog.String(attr.Key, "[redacted]")
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		if value := attr.Value.String(); phoneCandidate.MatchString(value) {
+			return slog.String(attr.Key, RedactPhones(value))
+		}
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			return slog.String(attr.Key, RedactPhones(err.Error()))
+		}
+	}
+	return attr
+}
+
+// RedactPhones masks the phone numbers and chat IDs in a string
+func RedactPhones(value string) string {
+	return phoneCandidate.ReplaceAllStringFunc(value, func(word string) string {
+		digits := strings.TrimPrefix(word, "+")
+		if len(digits) > 15 || strings.Trim(digits, "0123456789") != "" {
+			return word
+		}
+		return maskPhone(word)
+	})
+}
+
+// maskPhone keeps the last four digits of a phone number, enough to tell numbers apart
+// when debugging without identifying anyone
+func maskPhone(phone string) string {
+	digits := strings.TrimFunc(phone, func(r rune) bool { return r < '0' || r > '9' })
+	if len(digits) <= 4 {
+		return "***"
+	}
+	return "***" + digits[len(digits)-4:]
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying a logger, e.g. one with the request ID
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the context's logger, or the default one
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,43 @@
+package metrics
+
+// Default is the registry of the server's metrics, served at /metrics
+var Default = NewRegistry()
+
+// geminiBuckets cover Gemini calls, which take seconds rather than milliseconds
+var geminiBuckets = []float64{0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60}
+
+// The server's metrics. Label values are kept to small fixed sets, such as route patterns
+// rather than paths, so the number of series stays bounded.
+var (
+	HTTPRequests = Default.NewCounter("flux_http_requests_total",
+		"HTTP requests served, by method, route pattern and status code.",
+		"method", "route", "status")
+	HTTPRequestDuration = Default.NewHistogram("flux_http_request_duration_seconds",
+		"Time taken to serve HTTP requests, by method, route pattern and status code.",
+		DefaultBuckets, "method", "route", "status")
+
+	AuthAttempts = Default.NewCounter("flux_auth_attempts_total",
+		"Authentication attempts by method (password, whatsapp_code, refresh, token, api_key) and result (success, failure).",
+		"method", "result")
+	RateLimitRejections = Default.NewCounter("flux_rate_limit_rejections_total",
+		"Requests rejected with 429 by the rate limiter, by limit (client, route, user, apikey).",
+		"limit")
+
+	BotMessages = Default.NewCounter("flux_bot_messages_total",
+		"WhatsApp bot messages routed to a command, by command.",
+		"command")
+
+	GeminiRequestDuration = Default.NewHistogram("flux_gemini_request_duration_seconds",
+		"Time taken by Gemini API calls, by result (success, error).",
+		geminiBuckets, "result")
+	GeminiErrors = Default.NewCounter("flux_gemini_errors_total",
+		"Failed Gemini API calls, by reason (config, request, status, decode).",
+		"reason")
+	GeminiTokens = Default.NewCounter("flux_gemini_tokens_total",
+		"Tokens used by Gemini API calls, by type (prompt, completion).",
+		"type")
+
+	DatabaseQueryDuration = Default.NewHistogram("flux_db_query_duration_seconds",
+		"Time taken by database queries, by storage backend (supabase, postgres) and operation.",
+		DefaultBuckets, "backend", "operation")
+)
@@ -2,20 +2,27 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/okoye-dev/flux-server/internal/config"
+	"github.com/okoye-dev/flux-server/internal/identity"
+	"github.com/okoye-dev/flux-server/internal/metrics"
 )
 
 // UserContextKey is the key used to store user information in the request context
 type UserContextKey string
 
 const (
-	UserIDKey    UserContextKey = "user_id"
-	UserEmailKey UserContextKey = "user_email"
+	UserIDKey      UserContextKey = "user_id"
+	UserEmailKey   UserContextKey = "user_email"
+	ClaimsKey      UserContextKey = "claims"
+	AccessTokenKey UserContextKey = "access_token"
 )
 
 // SupabaseClaims represents the JWT claims from Supabase
@@ -27,11 +34,51 @@ type SupabaseClaims struct {
 	Sub   string `json:"sub"`
 	Email string `json:"email"`
 	Role  string `json:"role"`
+	// SessionID identifies the sign-in; refreshed tokens keep it
+	SessionID string `json:"session_id"`
+	// Phone is the account's confirmed phone number; WhatsApp sign-in sets it
+	Phone string `json:"phone"`
 	jwt.RegisteredClaims
 }
 
-// AuthMiddleware validates Supabase JWT tokens and adds user info to context
+// GetExpirationTime returns the exp claim. The Exp field takes the claim from the
+// embedded RegisteredClaims, whose method would otherwise find no expiry to check.
+func (c *SupabaseClaims) GetExpirationTime() (*jwt.NumericDate, error) {
+	if c.Exp == 0 {
+		return nil, nil
+	}
+	return jwt.NewNumericDate(time.Unix(c.Exp, 0)), nil
+}
+
+// GetIssuedAt returns the iat claim, which the Iat field likewise takes
+func (c *SupabaseClaims) GetIssuedAt() (*jwt.NumericDate, error) {
+	if c.Iat == 0 {
+		return nil, nil
+	}
+	return jwt.NewNumericDate(time.Unix(c.Iat, 0)), nil
+}
+
+// SessionChecker reports whether a token's session was signed out
+type SessionChecker interface {
+	SessionRevoked(userID, sessionID string, issuedAt time.Time) (bool, error)
+}
+
+// AuthMiddleware validates Supabase JWT tokens and adds user info to context.
+// It doesn't check for signed-out sessions; use NewAuthMiddleware for that.
 func AuthMiddleware(next http.Handler) http.Handler {
+	return NewAuthMiddleware(nil)(next)
+}
+
+// NewAuthMiddleware validates Supabase JWT tokens, rejects tokens of signed-out
+// sessions when sessions is not nil, and adds user info to context
+func NewAuthMiddleware(sessions SessionChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return authenticate(sessions, next)
+	}
+}
+
+// authenticate is the handler behind AuthMiddleware and NewAuthMiddleware
+func authenticate(sessions SessionChecker, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Get the Authorization header
 		authHeader := r.Header.Get("Authorization")
@@ -49,55 +96,144 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		// Extract the token
 		token := strings.TrimPrefix(authHeader, "Bearer ")
 
-		// Get Supabase configuration from environment
-		supabaseURL := os.Getenv("SUPABASE_URL")
-		supabaseAnonKey := os.Getenv("SUPABASE_ANON_KEY")
-
-		if supabaseURL == "" || supabaseAnonKey == "" {
+		// Parse and validate the JWT token
+		claims, err := validateSupabaseToken(token)
+		if errors.Is(err, errTokenValidationUnconfigured) {
 			http.Error(w, "Supabase configuration missing", http.StatusInternalServerError)
 			return
 		}
-
-		// Parse and validate the JWT token
-		claims, err := validateSupabaseToken(token, supabaseURL, supabaseAnonKey)
 		if err != nil {
+			metrics.AuthAttempts.Inc("token", "failure")
 			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
 			return
 		}
 
-		// Extract username from email (remove @fluxapp.com)
-		username := claims.Email
-		if strings.HasSuffix(username, "@fluxapp.com") {
-			username = strings.TrimSuffix(username, "@fluxapp.com")
+		// Reject tokens whose session was signed out before they expired
+		if sessions != nil {
+			revoked, err := sessions.SessionRevoked(claims.Sub, claims.SessionID, time.Unix(claims.Iat, 0))
+			if err != nil {
+				http.Error(w, "Failed to check session", http.StatusInternalServerError)
+				return
+			}
+			if revoked {
+				metrics.AuthAttempts.Inc("token", "failure")
+				http.Error(w, "Session has been signed out", http.StatusUnauthorized)
+				return
+			}
 		}
+		metrics.AuthAttempts.Inc("token", "success")
 
 		// Add user information to the request context
 		ctx := context.WithValue(r.Context(), UserIDKey, claims.Sub)
-		ctx = context.WithValue(ctx, UserEmailKey, username) // Using username for compatibility
+		ctx = context.WithValue(ctx, UserEmailKey, contextEmail(claims.Email))
+		ctx = context.WithValue(ctx, ClaimsKey, claims)
+		ctx = context.WithValue(ctx, AccessTokenKey, token)
 
 		// Continue with the next handler
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// validateSupabaseToken validates a Supabase JWT token
-func validateSupabaseToken(tokenString, supabaseURL, supabaseAnonKey string) (*SupabaseClaims, error) {
-	// Get JWT secret from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		return nil, fmt.Errorf("JWT_SECRET not configured")
+// ParseToken validates a Supabase JWT token with the configured TokenValidator
+func ParseToken(tokenString string) (*SupabaseClaims, error) {
+	return validateSupabaseToken(tokenString)
+}
+
+// TokenValidator verifies Supabase access tokens: RS256/ES256 tokens against the keys of
+// a JWKS, and HS256 tokens against the shared JWT secret, whichever are configured
+type TokenValidator struct {
+	secret   []byte
+	jwks     *JWKS
+	issuer   string
+	audience string
+	// domain recognises synthetic account emails, whose username GetUserEmail returns
+	domain *identity.Domain
+}
+
+// NewTokenValidator creates a token validator from the Supabase configuration
+func NewTokenValidator(cfg config.SupabaseConfig) (*TokenValidator, error) {
+	v := &TokenValidator{
+		secret:   []byte(cfg.JWTSecret),
+		issuer:   cfg.JWTIssuer,
+		audience: cfg.JWTAudience,
+		domain:   identity.NewDomain(cfg.EmailDomain, cfg.LegacyEmailDomains...),
+	}
+	if cfg.JWKSURL != "" {
+		jwks, err := NewJWKS(cfg.JWKSURL)
+		if err != nil {
+			return nil, err
+		}
+		v.jwks = jwks
+	}
+	return v, nil
+}
+
+var (
+	validatorMu      sync.Mutex
+	defaultValidator *TokenValidator
+)
+
+// SetTokenValidator sets the validator the auth middleware and ParseToken use. Until it
+// is called, or after it is called with nil, one is created from the environment on first use.
+func SetTokenValidator(v *TokenValidator) {
+	validatorMu.Lock()
+	defer validatorMu.Unlock()
+	defaultValidator = v
+}
+
+// tokenValidator returns the validator set by SetTokenValidator, or one from the environment
+func tokenValidator() (*TokenValidator, error) {
+	validatorMu.Lock()
+	defer validatorMu.Unlock()
+
+	if defaultValidator == nil {
+		v, err := NewTokenValidator(config.Load().Supabase)
+		if err != nil {
+			return nil, err
+		}
+		defaultValidator = v
+	}
+	return defaultValidator, nil
+}
+
+// errTokenValidationUnconfigured means neither a JWT secret nor a JWKS URL is set
+var errTokenValidationUnconfigured = errors.New("JWT_SECRET or JWKS_URL not configured")
+
+// validateSupabaseToken validates a Supabase JWT token with the configured TokenValidator
+func validateSupabaseToken(tokenString string) (*SupabaseClaims, error) {
+	v, err := tokenValidator()
+	if err != nil {
+		return nil, err
+	}
+	return v.Validate(tokenString)
+}
+
+// Validate verifies a token's signature and checks its issuer, audience and role
+func (v *TokenValidator) Validate(tokenString string) (*SupabaseClaims, error) {
+	var methods []string
+	if v.jwks != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg())
+	}
+	if len(v.secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errTokenValidationUnconfigured
 	}
 
 	// Parse and validate the JWT token
 	token, err := jwt.ParseWithClaims(tokenString, &SupabaseClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Check the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		// Asymmetric tokens name the JWKS key they were signed with
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			return v.secret, nil
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+			kid, _ := token.Header["kid"].(string)
+			return v.jwks.Key(kid)
+		default:
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		
-		// Use the JWT secret for verification
-		return []byte(jwtSecret), nil
-	})
+	}, jwt.WithValidMethods(methods), jwt.WithExpirationRequired())
 
 	if err != nil {
 		return nil, err
@@ -105,12 +241,12 @@ func validateSupabaseToken(tokenString, supabaseURL, supabaseAnonKey string) (*S
 
 	if claims, ok := token.Claims.(*SupabaseClaims); ok && token.Valid {
 		// Additional validation for Supabase tokens
-		if claims.Iss != supabaseURL+"/auth/v1" {
+		if claims.Iss != v.issuer {
 			return nil, fmt.Errorf("invalid issuer")
 		}
 		
 		// Validate audience
-		if claims.Aud != "authenticated" {
+		if claims.Aud != v.audience {
 			return nil, fmt.Errorf("invalid audience")
 		}
 		
@@ -131,12 +267,38 @@ func GetUserID(r *http.Request) (string, bool) {
 	return userID, ok
 }
 
-// GetUserEmail extracts the user email from the request context
+// contextEmail is what GetUserEmail returns for a token's email: the username for
+// synthetic emails, for compatibility, and the address itself for real ones
+func contextEmail(email string) string {
+	v, err := tokenValidator()
+	if err != nil {
+		return email
+	}
+	if username, ok := v.domain.Username(email); ok {
+		return username
+	}
+	return email
+}
+
+// GetUserEmail extracts the user email from the request context; for accounts with a
+// synthetic email it is their username
 func GetUserEmail(r *http.Request) (string, bool) {
 	userEmail, ok := r.Context().Value(UserEmailKey).(string)
 	return userEmail, ok
 }
 
+// GetClaims extracts the validated token claims from the request context
+func GetClaims(r *http.Request) (*SupabaseClaims, bool) {
+	claims, ok := r.Context().Value(ClaimsKey).(*SupabaseClaims)
+	return claims, ok
+}
+
+// GetAccessToken extracts the caller's bearer token from the request context
+func GetAccessToken(r *http.Request) (string, bool) {
+	token, ok := r.Context().Value(AccessTokenKey).(string)
+	return token, ok
+}
+
 // OptionalAuthMiddleware is similar to AuthMiddleware but doesn't require authentication
 // It adds user info to context if a valid token is provided, but doesn't fail if no token is provided
 func OptionalAuthMiddleware(next http.Handler) http.Handler {
@@ -150,15 +312,7 @@ func OptionalAuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		token := strings.TrimPrefix(authHeader, "Bearer ")
-		supabaseURL := os.Getenv("SUPABASE_URL")
-		supabaseAnonKey := os.Getenv("SUPABASE_ANON_KEY")
-
-		if supabaseURL == "" || supabaseAnonKey == "" {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		claims, err := validateSupabaseToken(token, supabaseURL, supabaseAnonKey)
+		claims, err := validateSupabaseToken(token)
 		
 		// If token is invalid, continue without user context
 		if err != nil {
@@ -166,15 +320,9 @@ func OptionalAuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Extract username from email (remove @fluxapp.com)
-		username := claims.Email
-		if strings.HasSuffix(username, "@fluxapp.com") {
-			username = strings.TrimSuffix(username, "@fluxapp.com")
-		}
-
 		// Add user information to context if token is valid
 		ctx := context.WithValue(r.Context(), UserIDKey, claims.Sub)
-		ctx = context.WithValue(ctx, UserEmailKey, username)
+		ctx = context.WithValue(ctx, UserEmailKey, contextEmail(claims.Email))
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
\ No newline at end of file
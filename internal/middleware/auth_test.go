@@ -0,0 +1,335 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/okoye-dev/flux-server/internal/config"
+)
+
+const (
+	testIssuer   = "https://project.supabase.co/auth/v1"
+	testAudience = "authenticated"
+	testSecret   = "test-jwt-secret"
+)
+
+// testKey is a signing key and the kid its public half is published under
+type testKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+}
+
+func newRSAKey(t *testing.T, kid string) testKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{kid: kid, method: jwt.SigningMethodRS256, private: key}
+}
+
+func newECKey(t *testing.T, kid string) testKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{kid: kid, method: jwt.SigningMethodES256, private: key}
+}
+
+// jwk encodes the public half of the key as a JSON Web Key
+func (k testKey) jwk() map[string]string {
+	encode := func(n *big.Int) string { return base64.RawURLEncoding.EncodeToString(n.Bytes()) }
+	switch public := k.private.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": k.kid, "use": "sig", "n": encode(public.N), "e": encode(big.NewInt(int64(public.E)))}
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		return map[string]string{"kty": "EC", "kid": k.kid, "use": "sig", "crv": "P-256",
+			"x": base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, size))),
+			"y": base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, size)))}
+	}
+	panic("unsupported key type")
+}
+
+// writeJWKS publishes the keys' public halves in a key set file
+func writeJWKS(t *testing.T, path string, keys ...testKey) {
+	t.Helper()
+	set := struct {
+		Keys []map[string]string `json:"keys"`
+	}{}
+	for _, key := range keys {
+		set.Keys = append(set.Keys, key.jwk())
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// validClaims are the claims of a token the validator accepts, changed by each case as needed
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"sub":  "6f1c2a9e-5b7d-4c1e-9a3f-2d8b7e6c5a41",
+		"iss":  testIssuer,
+		"aud":  testAudience,
+		"role": "authenticated",
+		"iat":  now.Unix(),
+		"exp":  now.Add(time.Hour).Unix(),
+	}
+}
+
+func signJWKS(t *testing.T, key testKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	signed, err := token.SignedString(key.private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func signHS(t *testing.T, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func newTestValidator(t *testing.T, jwksPath, secret string) *TokenValidator {
+	t.Helper()
+	cfg := config.SupabaseConfig{JWTSecret: secret, JWTIssuer: testIssuer, JWTAudience: testAudience}
+	if jwksPath != "" {
+		cfg.JWKSURL = "file://" + jwksPath
+	}
+	v, err := NewTokenValidator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestTokenValidatorValidate(t *testing.T) {
+	rsaKey := newRSAKey(t, "rsa-1")
+	ecKey := newECKey(t, "ec-1")
+	unpublished := newRSAKey(t, "rsa-1") // Same kid as a published key, different key
+
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksPath, rsaKey, ecKey)
+
+	with := func(key string, value any) jwt.MapClaims {
+		claims := validClaims()
+		claims[key] = value
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   func(t *testing.T) string
+		jwks    bool
+		secret  string
+		wantErr bool
+	}{
+		{
+			name:  "RS256 signed with a published key",
+			token: func(t *testing.T) string { return signJWKS(t, rsaKey, validClaims()) },
+			jwks:  true,
+		},
+		{
+			name:  "ES256 signed with a published key",
+			token: func(t *testing.T) string { return signJWKS(t, ecKey, validClaims()) },
+			jwks:  true,
+		},
+		{
+			name:   "HS256 signed with the JWT secret",
+			token:  func(t *testing.T) string { return signHS(t, testSecret, validClaims()) },
+			secret: testSecret,
+		},
+		{
+			name:   "HS256 accepted alongside a JWKS",
+			token:  func(t *testing.T) string { return signHS(t, testSecret, validClaims()) },
+			jwks:   true,
+			secret: testSecret,
+		},
+		{
+			name:    "HS256 signed with another secret",
+			token:   func(t *testing.T) string { return signHS(t, "another-secret", validClaims()) },
+			secret:  testSecret,
+			wantErr: true,
+		},
+		{
+			name:    "HS256 without a JWT secret configured",
+			token:   func(t *testing.T) string { return signHS(t, testSecret, validClaims()) },
+			jwks:    true,
+			wantErr: true,
+		},
+		{
+			name:    "RS256 without a JWKS configured",
+			token:   func(t *testing.T) string { return signJWKS(t, rsaKey, validClaims()) },
+			secret:  testSecret,
+			wantErr: true,
+		},
+		{
+			name:    "RS256 signed with an unpublished key under a published kid",
+			token:   func(t *testing.T) string { return signJWKS(t, unpublished, validClaims()) },
+			jwks:    true,
+			wantErr: true,
+		},
+		{
+			name:    "RS256 with an unknown kid",
+			token:   func(t *testing.T) string { return signJWKS(t, newRSAKey(t, "rsa-unknown"), validClaims()) },
+			jwks:    true,
+			wantErr: true,
+		},
+		{
+			name:    "wrong issuer",
+			token:   func(t *testing.T) string { return signJWKS(t, rsaKey, with("iss", "https://evil.example/auth/v1")) },
+			jwks:    true,
+			wantErr: true,
+		},
+		{
+			name:    "wrong audience",
+			token:   func(t *testing.T) string { return signJWKS(t, ecKey, with("aud", "anon")) },
+			jwks:    true,
+			wantErr: true,
+		},
+		{
+			name:    "wrong audience on an HS256 token",
+			token:   func(t *testing.T) string { return signHS(t, testSecret, with("aud", "service_role")) },
+			secret:  testSecret,
+			wantErr: true,
+		},
+		{
+			name: "expired RS256 token",
+			token: func(t *testing.T) string {
+				return signJWKS(t, rsaKey, with("exp", time.Now().Add(-time.Minute).Unix()))
+			},
+			jwks:    true,
+			wantErr: true,
+		},
+		{
+			name: "expired HS256 token",
+			token: func(t *testing.T) string {
+				return signHS(t, testSecret, with("exp", time.Now().Add(-time.Minute).Unix()))
+			},
+			secret:  testSecret,
+			wantErr: true,
+		},
+		{
+			name: "token without an expiry",
+			token: func(t *testing.T) string {
+				claims := validClaims()
+				delete(claims, "exp")
+				return signHS(t, testSecret, claims)
+			},
+			secret:  testSecret,
+			wantErr: true,
+		},
+		{
+			name:    "not an authenticated user",
+			token:   func(t *testing.T) string { return signJWKS(t, rsaKey, with("role", "anon")) },
+			jwks:    true,
+			wantErr: true,
+		},
+		{
+			name: "unsigned token",
+			token: func(t *testing.T) string {
+				signed, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return signed
+			},
+			jwks:    true,
+			secret:  testSecret,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := ""
+			if tt.jwks {
+				path = jwksPath
+			}
+			v := newTestValidator(t, path, tt.secret)
+
+			claims, err := v.Validate(tt.token(t))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Validate() accepted the token, claims %+v", claims)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if claims.Sub != validClaims()["sub"] {
+				t.Errorf("Validate() sub = %q, want %q", claims.Sub, validClaims()["sub"])
+			}
+		})
+	}
+}
+
+func TestTokenValidatorKeyRotation(t *testing.T) {
+	oldKey := newRSAKey(t, "key-2024")
+	newKey := newECKey(t, "key-2025")
+
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksPath, oldKey)
+	v := newTestValidator(t, jwksPath, "")
+
+	steps := []struct {
+		name    string
+		publish []testKey // Rewrites the key set before the step when set
+		key     testKey
+		wantErr bool
+	}{
+		{name: "old key before rotation", key: oldKey},
+		{name: "new key not published yet", key: newKey, wantErr: true},
+		{name: "new key once published", publish: []testKey{oldKey, newKey}, key: newKey},
+		{name: "old key still published", key: oldKey},
+		{name: "new key after the old one is retired", publish: []testKey{newKey}, key: newKey},
+		{name: "old key after it is retired", key: oldKey, wantErr: true},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if step.publish != nil {
+				writeJWKS(t, jwksPath, step.publish...)
+				// Let the cache refetch now rather than after jwksMinRefreshInterval
+				v.jwks.mu.Lock()
+				v.jwks.lastAttempt = time.Time{}
+				v.jwks.expiresAt = time.Time{}
+				v.jwks.mu.Unlock()
+			}
+
+			_, err := v.Validate(signJWKS(t, step.key, validClaims()))
+			if step.wantErr && err == nil {
+				t.Fatal("Validate() accepted the token")
+			}
+			if !step.wantErr && err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+		})
+	}
+}
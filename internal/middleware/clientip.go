@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIPKey is the context key holding the client IP resolved by ClientIPResolver
+const ClientIPKey UserContextKey = "client_ip"
+
+// ClientIPResolver works out the client IP of requests that may have come through
+// reverse proxies. Forwarding headers are only believed when they were added by a trusted
+// proxy: the client is the right-most address in the chain that isn't one, since anything
+// to its left was written by the client itself.
+type ClientIPResolver struct {
+	trusted []netip.Prefix
+}
+
+// NewClientIPResolver creates a resolver trusting the proxies in the given CIDRs or IP
+// addresses. With none, forwarding headers are ignored and the peer address is used.
+func NewClientIPResolver(trustedProxies []string) (*ClientIPResolver, error) {
+	resolver := &ClientIPResolver{}
+	for _, proxy := range trustedProxies {
+		prefix, err := parsePrefix(proxy)
+		if err != nil {
+			return nil, err
+		}
+		resolver.trusted = append(resolver.trusted, prefix)
+	}
+	return resolver, nil
+}
+
+// parsePrefix reads a CIDR, or a single IP address as a prefix of one address
+func parsePrefix(value string) (netip.Prefix, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("trusted proxy %q is not a CIDR such as 10.0.0.0/8", value)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("trusted proxy %q is not an IP address or CIDR", value)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Middleware resolves each request's client IP once and stores it in the context, so the
+// rate limiter, logs and audit log all see the same address
+func (c *ClientIPResolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), ClientIPKey, c.Resolve(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Resolve returns the client IP of a request. The Forwarded header is preferred over
+// X-Forwarded-For, and X-Real-IP is used when a trusted proxy sent neither.
+func (c *ClientIPResolver) Resolve(r *http.Request) string {
+	peer, ok := parseHost(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !c.isTrusted(peer) {
+		return peer.String()
+	}
+
+	hops, ok := forwardedFor(r.Header)
+	if !ok {
+		hops, ok = forwardedHeaderList(r.Header, "X-Forwarded-For")
+	}
+	if !ok {
+		if realIP, valid := parseHost(r.Header.Get("X-Real-IP")); valid {
+			return realIP.String()
+		}
+		return peer.String()
+	}
+
+	// Walk back from the proxy that sent the request until an address isn't a trusted proxy
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, valid := parseHost(hops[i])
+		if !valid {
+			// Garbage left of a trusted proxy: the last address we can vouch for is the client
+			break
+		}
+		client = hop
+		if !c.isTrusted(hop) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// isTrusted reports whether an address belongs to a trusted proxy
+func (c *ClientIPResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range c.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor returns the for= addresses of the Forwarded header (RFC 7239), nearest
+// proxy last; ok is false when the header is absent
+func forwardedFor(header http.Header) ([]string, bool) {
+	elements, ok := forwardedHeaderList(header, "Forwarded")
+	if !ok {
+		return nil, false
+	}
+	hops := make([]string, 0, len(elements))
+	for _, element := range elements {
+		hop := ""
+		for _, pair := range strings.Split(element, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+			if strings.EqualFold(key, "for") {
+				hop = strings.Trim(value, `"`)
+			}
+		}
+		// An element without for= still marks a hop, just not one we can vouch for
+		hops = append(hops, hop)
+	}
+	return hops, true
+}
+
+// forwardedHeaderList joins every instance of a comma-separated header into one list;
+// ok is false when the header is absent
+func forwardedHeaderList(header http.Header, name string) ([]string, bool) {
+	values := header.Values(name)
+	if len(values) == 0 {
+		return nil, false
+	}
+	var list []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			list = append(list, strings.TrimSpace(item))
+		}
+	}
+	return list, true
+}
+
+// parseHost reads an IP address with or without a port, as in RemoteAddr, X-Forwarded-For
+// and Forwarded ("[2001:db8::1]:4711")
+func parseHost(value string) (netip.Addr, bool) {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	// Mapped IPv4 and zoned addresses are normalised so each client has a single address
+	return addr.Unmap().WithZone(""), true
+}
+
+// ClientIP returns the client IP of a request, for rate limiting, logging and auditing.
+// Behind ClientIPResolver.Middleware this is the resolved address; otherwise forwarding
+// headers aren't trusted and the peer address is used.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(ClientIPKey).(string); ok {
+		return ip
+	}
+	return (&ClientIPResolver{}).Resolve(r)
+}
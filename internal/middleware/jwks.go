@@ -0,0 +1,260 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JWKS cache limits
+const (
+	// jwksDefaultTTL is how long fetched keys are trusted when the response has no max-age
+	jwksDefaultTTL = 10 * time.Minute
+	// jwksMaxTTL caps a max-age, so rotated keys are picked up within a day regardless
+	jwksMaxTTL = 24 * time.Hour
+	// jwksMinRefreshInterval limits refetches for unknown key IDs, so tokens with made-up
+	// key IDs can't make every request fetch the key set
+	jwksMinRefreshInterval = 30 * time.Second
+)
+
+// JWKS holds the public keys of a JSON Web Key Set, fetched from an http(s) URL or read
+// from a file:// URL. Keys are refetched once they expire, and early when a token names
+// a key ID the cache doesn't have, which is how a rotated signing key shows up.
+type JWKS struct {
+	url    string
+	client *http.Client
+
+	// fetchMu lets one request refresh the keys while the others wait for the result
+	fetchMu sync.Mutex
+
+	mu          sync.RWMutex
+	keys        map[string]any // Keyed by key ID; *rsa.PublicKey or *ecdsa.PublicKey
+	expiresAt   time.Time
+	lastAttempt time.Time
+}
+
+// jsonWebKey is a key of a JSON Web Key Set; only the fields of RSA and EC public keys are read
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// NewJWKS creates a key set cache for an http(s) or file:// URL; keys are fetched on first use
+func NewJWKS(rawURL string) (*JWKS, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWKS URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" && parsed.Scheme != "file" {
+		return nil, fmt.Errorf("JWKS URL must use http, https or file, got %q", rawURL)
+	}
+
+	return &JWKS{
+		url:    rawURL,
+		client: &http.Client{Timeout: 10 * time.Second},
+		keys:   map[string]any{},
+	}, nil
+}
+
+// Key returns the public key with the given key ID. A token without a key ID matches the
+// set's only key. Expired keys are still used when refreshing them fails.
+func (j *JWKS) Key(kid string) (any, error) {
+	if key, fresh := j.cachedKey(kid); key != nil && fresh {
+		return key, nil
+	}
+
+	j.refresh(kid)
+
+	if key, _ := j.cachedKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no JWKS key with ID %q", kid)
+}
+
+// cachedKey looks up a key and reports whether the cache is still fresh
+func (j *JWKS) cachedKey(kid string) (any, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	fresh := time.Now().Before(j.expiresAt)
+	if kid == "" {
+		if len(j.keys) != 1 {
+			return nil, fresh
+		}
+		for _, key := range j.keys {
+			return key, fresh
+		}
+	}
+	return j.keys[kid], fresh
+}
+
+// refresh refetches the key set unless another request already did, or the last attempt
+// was within jwksMinRefreshInterval. A failed attempt keeps the previous keys.
+func (j *JWKS) refresh(kid string) {
+	j.fetchMu.Lock()
+	defer j.fetchMu.Unlock()
+
+	j.mu.RLock()
+	_, known := j.keys[kid]
+	fresh := time.Now().Before(j.expiresAt)
+	recent := time.Since(j.lastAttempt) < jwksMinRefreshInterval
+	j.mu.RUnlock()
+	if (fresh && known) || recent {
+		return
+	}
+
+	keys, ttl, err := j.fetch()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.lastAttempt = time.Now()
+	if err != nil {
+		slog.Error("Failed to refresh JWKS", "url", j.url, "error", err)
+		return
+	}
+	j.keys = keys
+	j.expiresAt = time.Now().Add(ttl)
+}
+
+// fetch reads the key set and how long it may be cached
+func (j *JWKS) fetch() (map[string]any, time.Duration, error) {
+	var body []byte
+	ttl := jwksDefaultTTL
+
+	if strings.HasPrefix(j.url, "file://") {
+		data, err := os.ReadFile(strings.TrimPrefix(j.url, "file://"))
+		if err != nil {
+			return nil, 0, err
+		}
+		body = data
+	} else {
+		resp, err := j.client.Get(j.url)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, 0, fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		body, err = io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return nil, 0, err
+		}
+		if maxAge, ok := cacheMaxAge(resp.Header.Get("Cache-Control")); ok {
+			ttl = min(max(maxAge, jwksMinRefreshInterval), jwksMaxTTL)
+		}
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &set); err != nil {
+		return nil, 0, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := map[string]any{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Skip keys of types we don't verify with rather than rejecting the whole set
+			slog.Warn("Skipping JWKS key", "kid", jwk.Kid, "error", err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, ttl, nil
+}
+
+// publicKey decodes an RSA or EC public key
+func (k jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeKeyParam(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeKeyParam(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, fmt.Errorf("RSA exponent too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeKeyParam(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeKeyParam(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// decodeKeyParam decodes a base64url encoded big-endian integer
+func decodeKeyParam(value string) (*big.Int, error) {
+	if value == "" {
+		return nil, fmt.Errorf("missing key parameter")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// cacheMaxAge reads the max-age directive of a Cache-Control header
+func cacheMaxAge(header string) (time.Duration, bool) {
+	for _, directive := range strings.Split(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(directive), "=")
+		if !ok || !strings.EqualFold(name, "max-age") {
+			continue
+		}
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	return 0, false
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/logging"
+)
+
+// RequestIDHeader carries the ID that correlates a request's logs and audit events
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey is the context key holding the request ID set by RequestIDMiddleware
+const RequestIDKey UserContextKey = "request_id"
+
+// maxRequestIDLength bounds the client-supplied request IDs that are logged and stored
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware gives each request an ID: the client's or proxy's X-Request-ID when
+// it's usable, otherwise a random one. The ID is returned in the response, stored in the
+// context and attached to the context's logger.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestIDFromHeader(r)
+		w.Header().Set(RequestIDHeader, id)
+
+		logger := logging.FromContext(r.Context()).With("request_id", id)
+		ctx := context.WithValue(r.Context(), RequestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(logging.NewContext(ctx, logger)))
+	})
+}
+
+// RequestID returns the request's ID. Behind RequestIDMiddleware this is the ID in the
+// response; otherwise it's read from the header or made up.
+func RequestID(r *http.Request) string {
+	if id, ok := r.Context().Value(RequestIDKey).(string); ok {
+		return id
+	}
+	return requestIDFromHeader(r)
+}
+
+// requestIDFromHeader returns the request's X-Request-ID if it is short printable ASCII,
+// or a random ID
+func requestIDFromHeader(r *http.Request) string {
+	id := r.Header.Get(RequestIDHeader)
+	if id == "" || len(id) > maxRequestIDLength {
+		return uuid.NewString()
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return uuid.NewString()
+		}
+	}
+	return id
+}
+
+// AccessLogMiddleware logs every request once it's served, with its route, status and
+// duration. route returns the pattern the request matched, which unlike the path holds no
+// IDs. Server errors are logged as errors and client errors as warnings.
+func AccessLogMiddleware(route func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			level := slog.LevelInfo
+			switch {
+			case recorder.status >= 500:
+				level = slog.LevelError
+			case recorder.status >= 400:
+				level = slog.LevelWarn
+			}
+			logging.FromContext(r.Context()).LogAttrs(r.Context(), level, "Request served",
+				slog.String("method", r.Method),
+				slog.String("route", route(r)),
+				slog.Int("status", recorder.status),
+				slog.Int64("bytes", recorder.bytes),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("client_ip", ClientIP(r)),
+			)
+		})
+	}
+}
+
+// statusRecorder remembers the status code and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(data []byte) (int, error) {
+	s.wroteHeader = true
+	n, err := s.ResponseWriter.Write(data)
+	s.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/okoye-dev/flux-server/internal/metrics"
+)
+
+// unmatchedRoute labels requests that matched no route, so stray paths don't add series
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware counts requests and their latency by method, route and status. route
+// returns the pattern the request matched, keeping IDs in paths out of the labels.
+func MetricsMiddleware(route func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			pattern := route(r)
+			if pattern == "" {
+				pattern = unmatchedRoute
+			}
+			method := metricsMethod(r.Method)
+			status := strconv.Itoa(recorder.status)
+			metrics.HTTPRequests.Inc(method, pattern, status)
+			metrics.HTTPRequestDuration.ObserveSince(start, method, pattern, status)
+		})
+	}
+}
+
+// metricsMethod returns the method as a label, lumping together nonstandard methods
+// since clients can send any
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "OTHER"
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/okoye-dev/flux-server/internal/metrics"
+	"github.com/okoye-dev/flux-server/internal/ratelimit"
+)
+
+// Rate limit response headers, following the IETF RateLimit header fields draft
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RateLimitPolicyHeader    = "RateLimit-Policy"
+)
+
+// storeErrorLogInterval throttles logging while the rate limit store is unreachable
+const storeErrorLogInterval = time.Minute
+
+// RateLimitPolicies are the limits a RateLimiter applies; zero policies are off
+type RateLimitPolicies struct {
+	Client ratelimit.Policy // Per client IP, across all routes without their own policy
+	User   ratelimit.Policy // Per signed-in user or API key
+	// Routes are per client IP on a route, keyed by ServeMux pattern ("/auth/signin") or
+	// method and pattern ("POST /crops"); they replace the client policy on that route
+	Routes map[string]ratelimit.Policy
+}
+
+// RateLimiter limits requests with token buckets per client IP, per route and per
+// authenticated caller. Requests are let through when the store fails, so an unreachable
+// Redis doesn't take the API down. A nil RateLimiter limits nothing.
+type RateLimiter struct {
+	store    ratelimit.Store
+	policies RateLimitPolicies
+	limited  func(w http.ResponseWriter) // Writes the 429 response body
+
+	mu           sync.Mutex
+	lastStoreLog time.Time
+}
+
+// NewRateLimiter creates a rate limiter; limited writes the response to rejected requests
+// once the rate limit headers are set
+func NewRateLimiter(store ratelimit.Store, policies RateLimitPolicies, limited func(w http.ResponseWriter)) *RateLimiter {
+	return &RateLimiter{store: store, policies: policies, limited: limited}
+}
+
+// PerClient limits requests by client IP. The route's policy applies when it has one,
+// otherwise the client policy; route returns the pattern the request matched, if any.
+func (l *RateLimiter) PerClient(route func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := ClientIP(r)
+			key, policy := "client:"+ip, l.policies.Client
+			if spec, routePolicy, ok := l.routePolicy(r.Method, route(r)); ok {
+				key, policy = "route:"+spec+":"+ip, routePolicy
+			}
+			if l.allow(w, r, key, policy) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// PerUser limits requests by the authenticated caller: each API key has its own bucket,
+// separate from its owner's. It must run after authentication.
+func (l *RateLimiter) PerUser(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := ""
+		if apiKey, ok := GetAPIKey(r); ok {
+			key = "apikey:" + apiKey.ID
+		} else if userID, ok := GetUserID(r); ok {
+			key = "user:" + userID
+		}
+		if key == "" || l.allow(w, r, key, l.policies.User) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// routePolicy finds the policy configured for a route, preferring one for the method
+func (l *RateLimiter) routePolicy(method, pattern string) (string, ratelimit.Policy, bool) {
+	if pattern == "" {
+		return "", ratelimit.Policy{}, false
+	}
+	// Patterns registered with a method ("POST /crops") match either form as well
+	path := pattern
+	if _, p, ok := strings.Cut(pattern, " "); ok {
+		path = p
+	}
+	for _, spec := range []string{method + " " + path, path} {
+		if policy, ok := l.policies.Routes[spec]; ok {
+			return spec, policy, true
+		}
+	}
+	return "", ratelimit.Policy{}, false
+}
+
+// allow takes a token for the key and sets the rate limit headers, answering 429 when
+// the bucket is empty
+func (l *RateLimiter) allow(w http.ResponseWriter, r *http.Request, key string, policy ratelimit.Policy) bool {
+	if policy.IsZero() {
+		return true
+	}
+
+	decision, err := l.store.Take(r.Context(), key, policy)
+	if err != nil {
+		l.logStoreError(err)
+		return true
+	}
+
+	setRateLimitHeaders(w.Header(), policy, decision)
+	if !decision.Allowed {
+		// Keys start with the kind of limit, e.g. "route:POST /auth/signin:1.2.3.4"
+		limit, _, _ := strings.Cut(key, ":")
+		metrics.RateLimitRejections.Inc(limit)
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(decision.RetryAfter)))
+		l.limited(w)
+		return false
+	}
+	return true
+}
+
+// setRateLimitHeaders describes the bucket closest to running out, since a request passes
+// through several limits, or the one that rejected it
+func setRateLimitHeaders(header http.Header, policy ratelimit.Policy, decision ratelimit.Decision) {
+	if current := header.Get(RateLimitRemainingHeader); current != "" && decision.Allowed {
+		if remaining, err := strconv.Atoi(current); err == nil && remaining <= decision.Remaining {
+			return
+		}
+	}
+	header.Set(RateLimitLimitHeader, strconv.Itoa(decision.Limit))
+	header.Set(RateLimitRemainingHeader, strconv.Itoa(decision.Remaining))
+	header.Set(RateLimitResetHeader, strconv.Itoa(ceilSeconds(decision.Reset)))
+	header.Set(RateLimitPolicyHeader, strconv.Itoa(policy.Limit)+";w="+strconv.Itoa(ceilSeconds(policy.Period)))
+}
+
+// logStoreError logs store failures at most once per storeErrorLogInterval
+func (l *RateLimiter) logStoreError(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if time.Since(l.lastStoreLog) < storeErrorLogInterval {
+		return
+	}
+	l.lastStoreLog = time.Now()
+	slog.Error("Rate limit store error, letting requests through", "error", err)
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
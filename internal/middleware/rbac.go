@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Role names from the roles table
+const (
+	RoleFarmer           = "farmer"
+	RoleExtensionOfficer = "extension_officer"
+	RoleAdmin            = "admin"
+)
+
+// UserRoleKey is the context key holding the caller's resolved role name
+const UserRoleKey UserContextKey = "user_role"
+
+// Permission is an action a route can require
+type Permission string
+
+// Permissions that routes can declare
+const (
+	PermRecordHarvests   Permission = "harvests:record"
+	PermViewReports      Permission = "harvests:reports"
+	PermOfficerWorkspace Permission = "officer:workspace"
+	PermManageCatalogue  Permission = "catalogue:manage"
+	PermManageAPIKeys    Permission = "api_keys:manage"
+	PermManageUsers      Permission = "users:manage"
+	PermViewAuditLog     Permission = "audit_log:read"
+	// Every farmer record holds personal data, so only admins and API keys may read or
+	// manage them all; officers see their location's farmers through their workspace
+	PermReadFarmers  Permission = "farmers:read"
+	PermWriteFarmers Permission = "farmers:write"
+	// Signed-in users read their own harvests without a permission; API keys need this scope
+	PermReadHarvests Permission = "harvests:read"
+)
+
+// RolePermissions is the permission matrix: which permissions each role is granted
+var RolePermissions = map[string][]Permission{
+	RoleFarmer: {
+		PermRecordHarvests,
+	},
+	RoleExtensionOfficer: {
+		PermViewReports,
+		PermOfficerWorkspace,
+	},
+	RoleAdmin: {
+		PermRecordHarvests,
+		PermViewReports,
+		PermReadFarmers,
+		PermWriteFarmers,
+		PermManageCatalogue,
+		PermManageAPIKeys,
+		PermManageUsers,
+		PermViewAuditLog,
+	},
+}
+
+// HasPermission reports whether a role is granted a permission
+func HasPermission(role string, permission Permission) bool {
+	for _, granted := range RolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleResolver looks up the role name of an authenticated user.
+// An empty role with a nil error means the user has no role.
+type RoleResolver interface {
+	ResolveRole(authUserID string) (string, error)
+}
+
+// AuthorizationErrors writes the responses for rejected requests,
+// so the middleware can reuse the transport layer's error envelope
+type AuthorizationErrors struct {
+	Unauthorized func(w http.ResponseWriter)
+	Forbidden    func(w http.ResponseWriter)
+	Internal     func(w http.ResponseWriter, err error)
+}
+
+// RoleAuthorizer resolves caller roles with a short-lived cache and guards routes by role or permission
+type RoleAuthorizer struct {
+	resolver RoleResolver
+	ttl      time.Duration
+	errors   AuthorizationErrors
+
+	mu        sync.Mutex
+	entries   map[string]roleCacheEntry
+	nextSweep time.Time
+}
+
+// roleSweepInterval is how often expired roles are dropped from the cache
+const roleSweepInterval = time.Minute
+
+// roleCacheEntry is a cached role lookup
+type roleCacheEntry struct {
+	role      string
+	expiresAt time.Time
+}
+
+// NewRoleAuthorizer creates a role authorizer that caches resolved roles for ttl
+func NewRoleAuthorizer(resolver RoleResolver, ttl time.Duration, errors AuthorizationErrors) *RoleAuthorizer {
+	return &RoleAuthorizer{
+		resolver: resolver,
+		ttl:      ttl,
+		errors:   errors,
+		entries:  map[string]roleCacheEntry{},
+	}
+}
+
+// RequireRole only lets callers with one of the given roles through.
+// It must run after AuthMiddleware.
+func (a *RoleAuthorizer) RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return a.require(func(role string) bool {
+		for _, allowed := range roles {
+			if role == allowed {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// RequirePermission only lets callers whose role is granted the permission through.
+// It must run after AuthMiddleware.
+func (a *RoleAuthorizer) RequirePermission(permission Permission) func(http.Handler) http.Handler {
+	return a.require(func(role string) bool {
+		return HasPermission(role, permission)
+	})
+}
+
+// Role returns the role of a user, using the cache when the entry is still fresh
+func (a *RoleAuthorizer) Role(authUserID string) (string, error) {
+	now := time.Now()
+
+	a.mu.Lock()
+	entry, ok := a.entries[authUserID]
+	a.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.role, nil
+	}
+
+	role, err := a.resolver.ResolveRole(authUserID)
+	if err != nil {
+		return "", err
+	}
+
+	a.mu.Lock()
+	if now.After(a.nextSweep) {
+		a.sweep(now)
+	}
+	a.entries[authUserID] = roleCacheEntry{role: role, expiresAt: now.Add(a.ttl)}
+	a.mu.Unlock()
+
+	return role, nil
+}
+
+// Invalidate drops a user's cached role, e.g. after their role changes
+func (a *RoleAuthorizer) Invalidate(authUserID string) {
+	a.mu.Lock()
+	delete(a.entries, authUserID)
+	a.mu.Unlock()
+}
+
+// sweep drops expired roles, so users who stop calling don't stay cached; the caller holds the lock
+func (a *RoleAuthorizer) sweep(now time.Time) {
+	for authUserID, entry := range a.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(a.entries, authUserID)
+		}
+	}
+	a.nextSweep = now.Add(roleSweepInterval)
+}
+
+// require builds a middleware that resolves the caller's role and checks it with allowed
+func (a *RoleAuthorizer) require(allowed func(role string) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := GetUserID(r)
+			if !ok || userID == "" {
+				a.errors.Unauthorized(w)
+				return
+			}
+
+			role, err := a.Role(userID)
+			if err != nil {
+				a.errors.Internal(w, err)
+				return
+			}
+
+			if role == "" || !allowed(role) {
+				a.errors.Forbidden(w)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserRoleKey, role)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetUserRole extracts the caller's role from the request context, once RequireRole or RequirePermission has run
+func GetUserRole(r *http.Request) (string, bool) {
+	role, ok := r.Context().Value(UserRoleKey).(string)
+	return role, ok
+}
@@ -2,7 +2,12 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/okoye-dev/flux-server/internal/config"
 )
 
 // SecurityHeadersMiddleware adds security headers to responses
@@ -24,98 +29,79 @@ func SecurityHeadersMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// CORSMiddleware handles Cross-Origin Resource Sharing
-func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
+// CORSMiddleware handles Cross-Origin Resource Sharing. Only allowed origins get CORS
+// headers, so other sites can neither read responses nor send credentialed requests.
+func CORSMiddleware(cfg config.CORSConfig) func(http.Handler) http.Handler {
+	origins := cfg.AllowedOrigins
+	if cfg.AllowPreviews {
+		origins = append(slices.Clip(origins), cfg.PreviewOrigins...)
+	}
+	anyOrigin := slices.Contains(origins, "*")
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge / time.Second))
+	
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
 			
-			// Check if origin is allowed
-			allowed := false
-			for _, allowedOrigin := range allowedOrigins {
-				if allowedOrigin == "*" || allowedOrigin == origin {
-					allowed = true
-					break
-				}
+			// Responses differ by origin, so caches must keep them apart
+			if !anyOrigin {
+				w.Header().Add("Vary", "Origin")
 			}
 			
+			allowed := origin != "" && (anyOrigin || originAllowed(origins, origin))
 			if allowed {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
-			}
-			
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
-			w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
-			
-			// Handle preflight requests
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-			
-			next.ServeHTTP(w, r)
-		})
-	}
-}
-
-// RateLimitMiddleware provides basic rate limiting
-// In production, use a proper rate limiting solution like Redis
-func RateLimitMiddleware(requestsPerMinute int) func(http.Handler) http.Handler {
-	// Simple in-memory rate limiter (not suitable for production with multiple instances)
-	requestCounts := make(map[string][]time.Time)
-	
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			clientIP := getClientIP(r)
-			now := time.Now()
-			
-			// Clean old requests (older than 1 minute)
-			if requests, exists := requestCounts[clientIP]; exists {
-				var validRequests []time.Time
-				for _, reqTime := range requests {
-					if now.Sub(reqTime) < time.Minute {
-						validRequests = append(validRequests, reqTime)
+				if anyOrigin {
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+				} else {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					if cfg.AllowCredentials {
+						w.Header().Set("Access-Control-Allow-Credentials", "true")
 					}
 				}
-				requestCounts[clientIP] = validRequests
+				if exposed != "" && !preflight {
+					w.Header().Set("Access-Control-Expose-Headers", exposed)
+				}
 			}
 			
-			// Check if client has exceeded rate limit
-			if len(requestCounts[clientIP]) >= requestsPerMinute {
-				http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			// Answer preflight requests here; without the headers above the browser blocks the request
+			if preflight {
+				if allowed {
+					w.Header().Set("Access-Control-Allow-Methods", methods)
+					w.Header().Set("Access-Control-Allow-Headers", headers)
+					w.Header().Set("Access-Control-Max-Age", maxAge)
+				}
+				w.WriteHeader(http.StatusNoContent)
 				return
 			}
 			
-			// Add current request
-			requestCounts[clientIP] = append(requestCounts[clientIP], now)
-			
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
-// getClientIP extracts the real client IP from the request
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header (for load balancers/proxies)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// X-Forwarded-For can contain multiple IPs, take the first one
-		if idx := len(xff); idx > 0 {
-			for i, c := range xff {
-				if c == ',' {
-					idx = i
-					break
-				}
+// originAllowed reports whether an origin matches one of the allowed origins, where * in
+// a pattern stands for one or more letters, digits or hyphens within a hostname label
+func originAllowed(patterns []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range patterns {
+		prefix, suffix, wildcard := strings.Cut(pattern, "*")
+		if !wildcard {
+			if pattern == origin {
+				return true
 			}
-			return xff[:idx]
+			continue
+		}
+		if len(origin) <= len(prefix)+len(suffix) || !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+			continue
+		}
+		label := origin[len(prefix) : len(origin)-len(suffix)]
+		if strings.Trim(label, "abcdefghijklmnopqrstuvwxyz0123456789-") == "" {
+			return true
 		}
 	}
-	
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
-	}
-	
-	// Fallback to RemoteAddr
-	return r.RemoteAddr
+	return false
 }
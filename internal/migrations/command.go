@@ -0,0 +1,84 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// Usage describes the migrate subcommand
+const Usage = "usage: flux-server migrate up | down [steps] | status"
+
+// Run executes the migrate subcommand given its arguments, e.g. ["down", "2"]
+func Run(runner *Runner, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(Usage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := runner.Up()
+		for _, migration := range applied {
+			fmt.Fprintf(out, "Applied %03d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "Schema is up to date")
+		}
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("steps must be a positive number\n%s", Usage)
+			}
+			steps = n
+		}
+
+		rolledBack, err := runner.Down(steps)
+		for _, migration := range rolledBack {
+			fmt.Fprintf(out, "Rolled back %03d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(rolledBack) == 0 {
+			fmt.Fprintln(out, "No migrations to roll back")
+		}
+		return nil
+
+	case "status":
+		statuses, err := runner.Status()
+		if err != nil {
+			return err
+		}
+
+		table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			state := "pending"
+			if status.Missing {
+				state = "applied (not in this binary)"
+			} else if status.Applied {
+				state = "applied"
+			}
+
+			appliedAt := "-"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(table, "%03d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+		return table.Flush()
+
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], Usage)
+	}
+}
@@ -0,0 +1,333 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/okoye-dev/flux-server/database"
+)
+
+// lockID keys the Postgres advisory lock that keeps two runners from migrating at once
+const lockID = 4_021_557_301
+
+var (
+	// ErrNoDownMigration is returned when rolling back a migration that has no .down.sql file
+	ErrNoDownMigration = errors.New("migration has no down file")
+	// ErrUnknownMigration is returned when rolling back a version this binary doesn't embed
+	ErrUnknownMigration = errors.New("applied migration is not embedded in this binary")
+	// ErrSchemaBehind is returned by Check when embedded migrations have not been applied
+	ErrSchemaBehind = errors.New("database schema is behind")
+)
+
+// fileName matches NNN_name.sql and NNN_name.down.sql
+var fileName = regexp.MustCompile(`^(\d+)_(.+?)(\.down)?\.sql$`)
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string // Empty when the migration can't be rolled back
+}
+
+// Status reports whether a migration has been applied
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+	Missing   bool // Applied to the database but not embedded in this binary
+}
+
+// Embedded loads the migrations compiled into the binary from database/migrations
+func Embedded() ([]Migration, error) {
+	files, err := fs.Sub(database.Migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return Load(files)
+}
+
+// Load reads the migrations in the root of fsys, ordered by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		contents, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %s: version %d is already used by %s", entry.Name(), version, migration.Name)
+		}
+
+		if match[3] == "" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %03d_%s has a down file but no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Runner applies migrations to a Postgres database and records them in schema_migrations.
+// Each migration runs in its own transaction together with its schema_migrations row.
+type Runner struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewRunner creates a runner for the given migrations
+func NewRunner(db *sql.DB, migrations []Migration) *Runner {
+	return &Runner{db: db, migrations: migrations}
+}
+
+// Up applies every pending migration in order and returns the ones it applied
+func (r *Runner) Up() ([]Migration, error) {
+	if err := r.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	pending, err := r.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range pending {
+		ran, err := r.apply(migration)
+		if err != nil {
+			return applied, fmt.Errorf("migration %03d_%s: %w", migration.Version, migration.Name, err)
+		}
+		if ran {
+			applied = append(applied, migration)
+		}
+	}
+	return applied, nil
+}
+
+// Down rolls back the latest steps applied migrations and returns the ones it rolled back
+func (r *Runner) Down(steps int) ([]Migration, error) {
+	if err := r.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	var rolledBack []Migration
+	for i := 0; i < steps; i++ {
+		migration, err := r.rollbackLatest()
+		if err != nil {
+			return rolledBack, err
+		}
+		if migration == nil {
+			break
+		}
+		rolledBack = append(rolledBack, *migration)
+	}
+	return rolledBack, nil
+}
+
+// Status lists every embedded migration, plus applied versions this binary doesn't know about
+func (r *Runner) Status() ([]Status, error) {
+	applied, err := r.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, migration := range r.migrations {
+		status := Status{Migration: migration}
+		if row, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = row.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range applied {
+		statuses = append(statuses, row)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Pending returns the embedded migrations that have not been applied yet
+func (r *Runner) Pending() ([]Migration, error) {
+	applied, err := r.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range r.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Check returns ErrSchemaBehind when any embedded migration is still pending
+func (r *Runner) Check() error {
+	pending, err := r.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migration(s), starting with %03d_%s; run 'flux-server migrate up'",
+			ErrSchemaBehind, len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
+
+// ensureTable creates schema_migrations if it doesn't exist yet
+func (r *Runner) ensureTable() error {
+	_, err := r.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	)`)
+	return err
+}
+
+// applied reads schema_migrations, treating a missing table as nothing applied
+func (r *Runner) applied() (map[int64]Status, error) {
+	var exists bool
+	if err := r.db.QueryRow("SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, err
+	}
+	applied := map[int64]Status{}
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := r.db.Query("SELECT version, name, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status Status
+		if err := rows.Scan(&status.Version, &status.Name, &status.AppliedAt); err != nil {
+			return nil, err
+		}
+		status.Applied = true
+		status.Missing = true
+		applied[status.Version] = status
+	}
+	return applied, rows.Err()
+}
+
+// apply runs one migration unless another runner applied it first; it reports whether it ran
+func (r *Runner) apply(migration Migration) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", lockID); err != nil {
+		return false, err
+	}
+
+	var done bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", migration.Version).Scan(&done); err != nil {
+		return false, err
+	}
+	if done {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(migration.Up); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// rollbackLatest rolls back the most recently applied migration, or returns nil when none is applied
+func (r *Runner) rollbackLatest() (*Migration, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", lockID); err != nil {
+		return nil, err
+	}
+
+	var version int64
+	var name string
+	err = tx.QueryRow("SELECT version, name FROM schema_migrations ORDER BY version DESC LIMIT 1").Scan(&version, &name)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	migration := r.find(version)
+	if migration == nil {
+		return nil, fmt.Errorf("migration %03d_%s: %w", version, name, ErrUnknownMigration)
+	}
+	if migration.Down == "" {
+		return nil, fmt.Errorf("migration %03d_%s: %w", version, name, ErrNoDownMigration)
+	}
+
+	if _, err := tx.Exec(migration.Down); err != nil {
+		return nil, fmt.Errorf("migration %03d_%s: %w", version, name, err)
+	}
+	if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = $1", version); err != nil {
+		return nil, err
+	}
+	return migration, tx.Commit()
+}
+
+// find returns the embedded migration with a version
+func (r *Runner) find(version int64) *Migration {
+	for i := range r.migrations {
+		if r.migrations[i].Version == version {
+			return &r.migrations[i]
+		}
+	}
+	return nil
+}
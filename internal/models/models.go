@@ -8,7 +8,7 @@ import (
 
 // Farmer represents a farmer in the system
 type Farmer struct {
-	ID          int64      `json:"id" db:"id"`
+	ID          int64      `json:"id,omitempty" db:"id"` // Omitted when zero so the database assigns it
 	AuthUserID  *uuid.UUID `json:"auth_user_id" db:"auth_user_id"` // Links to auth.users.id
 	Name        string     `json:"name" db:"name"`
 	PhoneNumber string     `json:"phone_number" db:"phone_number"`
@@ -20,7 +20,7 @@ type Farmer struct {
 
 // ExtensionOfficer represents an extension officer in the system
 type ExtensionOfficer struct {
-	ID                  int64      `json:"id" db:"id"`
+	ID                  int64      `json:"id,omitempty" db:"id"` // Omitted when zero so the database assigns it
 	AuthUserID          *uuid.UUID `json:"auth_user_id" db:"auth_user_id"` // Links to auth.users.id
 	Name                string     `json:"name" db:"name"`
 	PhoneNumber         string     `json:"phone_number" db:"phone_number"`
@@ -41,9 +41,11 @@ type UserProfile struct {
 	AuthUserID  *uuid.UUID      `json:"auth_user_id" db:"auth_user_id"` // Links to auth.users.id
 	RoleID      *uuid.UUID      `json:"role_id" db:"role_id"`           // FK to roles.id
 	DisplayName *string         `json:"display_name" db:"display_name"`
+	Email       *string         `json:"email" db:"email"` // The auth account's sign-in email, lowercased
 	Phone       *string         `json:"phone" db:"phone"`
 	Metadata    map[string]any  `json:"metadata" db:"metadata"`
 	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	DisabledAt  *time.Time      `json:"disabled_at" db:"disabled_at"` // Set while an admin has disabled the account
 }
 
 // Location represents a geographical location
@@ -88,6 +90,7 @@ type AuthUser struct {
 	PhoneConfirmedAt  *time.Time             `json:"phone_confirmed_at" db:"phone_confirmed_at"`
 	CreatedAt         time.Time              `json:"created_at" db:"created_at"`
 	LastSignInAt      *time.Time             `json:"last_sign_in_at" db:"last_sign_in_at"`
+	BannedUntil       *time.Time             `json:"banned_until" db:"banned_until"`
 	RawUserMetaData   map[string]any         `json:"raw_user_meta_data" db:"raw_user_meta_data"`
 	UserMetadata      map[string]any         `json:"user_metadata" db:"user_metadata"`
 }
@@ -158,4 +161,198 @@ type CreateFarmerCropRequest struct {
 type AddCropsToFarmerRequest struct {
 	FarmerID int64       `json:"farmer_id" validate:"required"`
 	CropIDs  []uuid.UUID `json:"crop_ids" validate:"required,min=1"`
-}
\ No newline at end of file
+}
+// CreateFarmerRequest represents the request to create (or fully replace) a farmer
+type CreateFarmerRequest struct {
+	Name        string   `json:"name" validate:"required"`
+	PhoneNumber string   `json:"phone_number,omitempty"`
+	CropType    string   `json:"crop_type,omitempty"`
+	LocationID  int64    `json:"location_id,omitempty"`
+	Language    string   `json:"language,omitempty"` // Defaults to "en"
+	Crops       []string `json:"crops,omitempty"`    // Crop names linked through farmer_crops
+}
+
+// UpdateFarmerRequest represents a partial update to a farmer
+type UpdateFarmerRequest struct {
+	Name        *string   `json:"name,omitempty"`
+	PhoneNumber *string   `json:"phone_number,omitempty"`
+	CropType    *string   `json:"crop_type,omitempty"`
+	LocationID  *int64    `json:"location_id,omitempty"`
+	Language    *string   `json:"language,omitempty"`
+	Crops       *[]string `json:"crops,omitempty"` // Replaces the farmer's crops when present
+}
+
+// HarvestReportEntry represents the aggregated harvest quantity for one crop within a group
+type HarvestReportEntry struct {
+	CropID        uuid.UUID `json:"crop_id"`
+	CropName      string    `json:"crop_name"`
+	Season        string    `json:"season,omitempty"`
+	LocationID    *int64    `json:"location_id,omitempty"`
+	TotalQuantity float64   `json:"total_quantity"`
+	HarvestCount  int       `json:"harvest_count"`
+}
+
+// CropAlias maps an alternative spelling or local name onto a canonical crop
+type CropAlias struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	CropID    uuid.UUID `json:"crop_id" db:"crop_id"` // FK to crops.id
+	Alias     string    `json:"alias" db:"alias"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// CreateCropRequest represents the request to add a crop to the catalogue
+type CreateCropRequest struct {
+	Name           string  `json:"name" validate:"required"`
+	ScientificName *string `json:"scientific_name,omitempty"`
+}
+
+// CreateLocationRequest represents the request to add a location to the catalogue
+type CreateLocationRequest struct {
+	Name    string  `json:"name" validate:"required"`
+	Country *string `json:"country,omitempty"`
+}
+
+// MergeCropsRequest represents the request to fold duplicate crops into one canonical crop
+type MergeCropsRequest struct {
+	CanonicalID  uuid.UUID   `json:"canonical_id" validate:"required"`
+	DuplicateIDs []uuid.UUID `json:"duplicate_ids" validate:"required,min=1"`
+	Aliases      []string    `json:"aliases,omitempty"` // Extra names that should resolve to the canonical crop
+}
+
+// CropMergeResult summarises the rows rewritten by a crop merge
+type CropMergeResult struct {
+	Crop               Crop     `json:"crop"`
+	MergedCropIDs      []string `json:"merged_crop_ids"`
+	FarmerCropsMoved   int      `json:"farmer_crops_moved"`
+	FarmerCropsRemoved int      `json:"farmer_crops_removed"`
+	HarvestsMoved      int      `json:"harvests_moved"`
+	AliasesAdded       []string `json:"aliases_added"`
+}
+
+// FarmerFeedback represents feedback a farmer sent to the WhatsApp bot
+type FarmerFeedback struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	FarmerID    *int64    `json:"farmer_id" db:"farmer_id"` // FK to farmers.id, NULL for unknown numbers
+	PhoneNumber string    `json:"phone_number" db:"phone_number"`
+	Message     string    `json:"message" db:"message"`
+	AIResponse  *string   `json:"ai_response" db:"ai_response"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// FarmerVisitNote represents an extension officer's note about a farmer visit
+type FarmerVisitNote struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	FarmerID  int64     `json:"farmer_id" db:"farmer_id"`   // FK to farmers.id
+	OfficerID int64     `json:"officer_id" db:"officer_id"` // FK to extension_officers.id
+	Note      string    `json:"note" db:"note"`
+	VisitedAt time.Time `json:"visited_at" db:"visited_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// CreateVisitNoteRequest represents the request to annotate a farmer with a visit note
+type CreateVisitNoteRequest struct {
+	Note      string     `json:"note" validate:"required"`
+	VisitedAt *time.Time `json:"visited_at,omitempty"` // Defaults to now
+}
+
+// OfficerFarmerSummary represents a farmer as seen from an extension officer's workspace
+type OfficerFarmerSummary struct {
+	FarmerWithCrops
+	RecentHarvests []FarmHarvestWithDetails `json:"recent_harvests"`
+	RecentFeedback []FarmerFeedback         `json:"recent_feedback"`
+	VisitNotes     []FarmerVisitNote        `json:"visit_notes,omitempty"`
+}
+
+// RevokedSession is a signed-out session whose access tokens must be rejected until they expire
+type RevokedSession struct {
+	SessionID  uuid.UUID `json:"session_id" db:"session_id"`
+	AuthUserID uuid.UUID `json:"auth_user_id" db:"auth_user_id"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	RevokedAt  time.Time `json:"revoked_at" db:"revoked_at"`
+}
+
+// SessionCutoff rejects every token a user was issued at or before RevokedBefore (sign out everywhere)
+type SessionCutoff struct {
+	AuthUserID    uuid.UUID `json:"auth_user_id" db:"auth_user_id"`
+	RevokedBefore time.Time `json:"revoked_before" db:"revoked_before"`
+}
+
+// PasswordResetToken is a single-use password reset code sent over WhatsApp; only its hash is stored
+type PasswordResetToken struct {
+	AuthUserID uuid.UUID `json:"auth_user_id" db:"auth_user_id"`
+	TokenHash  string    `json:"token_hash" db:"token_hash"`
+	Attempts   int       `json:"attempts" db:"attempts"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// AuditEvent records a security-relevant action, who performed it and from where
+type AuditEvent struct {
+	ID        uuid.UUID      `json:"id" db:"id"`
+	ActorID   *uuid.UUID     `json:"actor_id" db:"actor_id"`   // Nil when the caller wasn't signed in
+	Action    string         `json:"action" db:"action"`       // e.g. "password.changed"
+	TargetID  *uuid.UUID     `json:"target_id" db:"target_id"` // The auth user the action applied to
+	IPAddress string         `json:"ip_address" db:"ip_address"`
+	UserAgent string         `json:"user_agent" db:"user_agent"`
+	RequestID string         `json:"request_id" db:"request_id"` // Ties together the events of one request
+	Details   map[string]any `json:"details" db:"details"`
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+}
+
+// OTPCode is a one-time sign-in code sent to a farmer's WhatsApp number; only its hash is stored
+type OTPCode struct {
+	PhoneNumber string    `json:"phone_number" db:"phone_number"`
+	CodeHash    string    `json:"code_hash" db:"code_hash"`
+	Attempts    int       `json:"attempts" db:"attempts"`
+	ExpiresAt   time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// APIKey lets a service call the API without a user login; only a hash of the key is stored
+type APIKey struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	OwnerID    uuid.UUID  `json:"owner_id" db:"owner_id"` // The auth user requests made with the key act as
+	Prefix     string     `json:"prefix" db:"prefix"`     // The key's public part, used to look it up
+	KeyHash    string     `json:"key_hash" db:"key_hash"`
+	Scopes     []string   `json:"scopes" db:"scopes"` // e.g. "farmers:read"
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedBy  *uuid.UUID `json:"created_by" db:"created_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// CreateAPIKeyRequest represents the request to create an API key
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required"`
+	OwnerID   uuid.UUID  `json:"owner_id" validate:"required"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Defaults to 90 days from now
+}
+
+// LoginAttempt counts the recent failed sign-ins of an account or IP address
+type LoginAttempt struct {
+	Key           string     `json:"key" db:"key"` // "account:<auth user id>", "login:<identifier>" or "ip:<address>"
+	Failures      int        `json:"failures" db:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at" db:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until" db:"locked_until"` // Sign-ins are refused until then
+}
+
+// PersonalDataExport bundles everything stored about a user, for data subject access requests
+type PersonalDataExport struct {
+	ExportedAt      time.Time                `json:"exported_at"`
+	Profile         *UserProfile             `json:"profile"`                     // Nil for farmers who only use the WhatsApp bot
+	Officer         *ExtensionOfficer        `json:"extension_officer,omitempty"` // Set for extension officers
+	Farmers         []FarmerDataExport       `json:"farmers"`
+	Harvests        []FarmHarvestWithDetails `json:"harvests"`
+	Feedback        []FarmerFeedback         `json:"feedback"`
+	BotConversation map[string]any           `json:"bot_conversation"` // The WhatsApp bot's state for the user's number
+}
+
+// FarmerDataExport is a farmer record with its crops and the visit notes extension officers wrote about it
+type FarmerDataExport struct {
+	Farmer
+	Crops      []Crop            `json:"crops"`
+	VisitNotes []FarmerVisitNote `json:"visit_notes"`
+}
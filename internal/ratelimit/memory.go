@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"hash/maphash"
+	"math"
+	"sync"
+	"time"
+)
+
+// Memory store tuning
+const (
+	// memoryShards spreads keys over independently locked maps, so concurrent requests
+	// for different clients rarely wait on each other
+	memoryShards = 64
+	// memorySweepInterval is how often a shard drops buckets that have refilled
+	memorySweepInterval = time.Minute
+	// memoryShardKeys triggers an early sweep when a shard grows past it
+	memoryShardKeys = 10000
+)
+
+// memoryBucket is a token bucket; fullAt is when it will have refilled, after which it is
+// indistinguishable from a new bucket and can be dropped
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	fullAt  time.Time
+}
+
+type memoryShard struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	nextSweep time.Time
+}
+
+// MemoryStore keeps token buckets in process memory. It's safe for concurrent use, and
+// buckets are evicted once they have refilled, so idle clients don't accumulate. Limits
+// aren't shared between instances; use RedisStore for that.
+type MemoryStore struct {
+	seed   maphash.Seed
+	shards [memoryShards]memoryShard
+	now    func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{seed: maphash.MakeSeed(), now: time.Now}
+	for i := range s.shards {
+		s.shards[i].buckets = map[string]*memoryBucket{}
+	}
+	return s
+}
+
+// Take removes a token from the key's bucket
+func (s *MemoryStore) Take(_ context.Context, key string, policy Policy) (Decision, error) {
+	now := s.now()
+	shard := &s.shards[maphash.String(s.seed, key)%memoryShards]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if now.After(shard.nextSweep) || len(shard.buckets) > memoryShardKeys {
+		shard.sweep(now)
+	}
+
+	capacity := float64(policy.Limit)
+	bucket, ok := shard.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: capacity, updated: now}
+		shard.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.updated).Seconds()
+	bucket.tokens = math.Min(capacity, bucket.tokens+math.Max(elapsed, 0)*policy.rate())
+	bucket.updated = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	decision := decide(policy, allowed, bucket.tokens)
+	bucket.fullAt = now.Add(decision.Reset)
+	return decision, nil
+}
+
+// sweep drops the buckets that have refilled; the caller holds the shard lock
+func (shard *memoryShard) sweep(now time.Time) {
+	for key, bucket := range shard.buckets {
+		if !now.Before(bucket.fullAt) {
+			delete(shard.buckets, key)
+		}
+	}
+	shard.nextSweep = now.Add(memorySweepInterval)
+}
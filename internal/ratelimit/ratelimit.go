@@ -0,0 +1,136 @@
+// Package ratelimit implements token bucket rate limits. Buckets live in a Store: in
+// memory for a single instance, or in Redis so several instances share the same limits.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy allows Limit requests per Period. A bucket holds up to Limit tokens and refills
+// evenly over Period, so an idle client may burst up to Limit requests at once.
+type Policy struct {
+	Limit  int
+	Period time.Duration
+}
+
+// IsZero reports whether the policy is unset, i.e. rate limiting is off
+func (p Policy) IsZero() bool {
+	return p.Limit == 0
+}
+
+// String formats the policy the way ParsePolicy reads it
+func (p Policy) String() string {
+	if p.IsZero() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", p.Limit, p.Period)
+}
+
+// rate is the refill rate in tokens per second
+func (p Policy) rate() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+// Decision is the outcome of taking a token from a bucket
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int           // Whole tokens left after this request
+	Reset     time.Duration // Until the bucket is full again
+	// RetryAfter is how long to wait before a token is available; zero when allowed
+	RetryAfter time.Duration
+}
+
+// Store holds token buckets by key
+type Store interface {
+	// Take removes a token from the key's bucket, creating a full bucket for new keys
+	Take(ctx context.Context, key string, policy Policy) (Decision, error)
+}
+
+// decide builds the decision for a bucket left with tokens after a request
+func decide(policy Policy, allowed bool, tokens float64) Decision {
+	rate := policy.rate()
+	decision := Decision{
+		Allowed:   allowed,
+		Limit:     policy.Limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(policy.Limit) - tokens) / rate),
+	}
+	if !allowed {
+		decision.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return decision
+}
+
+// seconds converts fractional seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Max(s, 0) * float64(time.Second))
+}
+
+// ErrInvalidPolicy is returned for policies ParsePolicy can't read
+var ErrInvalidPolicy = errors.New(`rate limit policies look like "60/m": a number of requests per s, m, h or a duration such as 30s`)
+
+// ParsePolicy reads a policy written as requests/period, e.g. "60/m", "1000/h" or "5/30s".
+// "off" turns rate limiting off and returns the zero policy.
+func ParsePolicy(value string) (Policy, error) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, "off") {
+		return Policy{}, nil
+	}
+
+	count, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return Policy{}, ErrInvalidPolicy
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || limit < 1 {
+		return Policy{}, ErrInvalidPolicy
+	}
+
+	var duration time.Duration
+	switch period = strings.TrimSpace(period); period {
+	case "s":
+		duration = time.Second
+	case "m":
+		duration = time.Minute
+	case "h":
+		duration = time.Hour
+	case "d":
+		duration = 24 * time.Hour
+	default:
+		duration, err = time.ParseDuration(period)
+		if err != nil || duration < time.Second {
+			return Policy{}, ErrInvalidPolicy
+		}
+	}
+	return Policy{Limit: limit, Period: duration}, nil
+}
+
+// ParseRoutePolicies reads comma-separated route=policy pairs, e.g.
+// "POST /auth/signin=10/m,/auth/otp/request=3/m". Routes are ServeMux patterns, optionally
+// preceded by a method.
+func ParseRoutePolicies(value string) (map[string]Policy, error) {
+	policies := map[string]Policy{}
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		route, spec, ok := strings.Cut(entry, "=")
+		route = strings.Join(strings.Fields(route), " ")
+		if !ok || route == "" {
+			return nil, fmt.Errorf("rate limit route %q must look like POST /auth/signin=10/m", strings.TrimSpace(entry))
+		}
+		policy, err := ParsePolicy(spec)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit for %s: %w", route, err)
+		}
+		policies[route] = policy
+	}
+	return policies, nil
+}
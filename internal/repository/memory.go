@@ -0,0 +1,1373 @@
+package repository
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+)
+
+// memoryDB holds every table of the in-memory store behind one lock
+type memoryDB struct {
+	mu sync.RWMutex
+	// txMu serialises transactions so each one can roll back to its own snapshot
+	txMu sync.Mutex
+	memoryTables
+}
+
+// memoryTables are the rows of every table
+type memoryTables struct {
+	profiles    map[uuid.UUID]models.UserProfile
+	roles       map[uuid.UUID]models.Role
+	farmers     map[int64]models.Farmer
+	officers    map[int64]models.ExtensionOfficer
+	crops       map[uuid.UUID]models.Crop
+	cropAliases map[uuid.UUID]models.CropAlias
+	farmerCrops map[uuid.UUID]models.FarmerCrop
+	harvests    map[uuid.UUID]models.FarmHarvest
+	feedback    map[uuid.UUID]models.FarmerFeedback
+	visitNotes  map[uuid.UUID]models.FarmerVisitNote
+	locations   map[uuid.UUID]models.Location
+	revoked     map[uuid.UUID]models.RevokedSession
+	cutoffs     map[uuid.UUID]models.SessionCutoff
+	otpCodes    map[string]models.OTPCode
+	resets      map[uuid.UUID]models.PasswordResetToken
+	audit       []models.AuditEvent
+	apiKeys     map[uuid.UUID]models.APIKey
+	logins      map[string]models.LoginAttempt
+}
+
+// NewMemoryStore creates a store that keeps everything in process memory.
+// It is seeded with the roles and crop catalogue from the SQL migrations, for tests and local demos.
+func NewMemoryStore() *Store {
+	db := &memoryDB{memoryTables: memoryTables{
+		profiles:    map[uuid.UUID]models.UserProfile{},
+		roles:       map[uuid.UUID]models.Role{},
+		farmers:     map[int64]models.Farmer{},
+		officers:    map[int64]models.ExtensionOfficer{},
+		crops:       map[uuid.UUID]models.Crop{},
+		cropAliases: map[uuid.UUID]models.CropAlias{},
+		farmerCrops: map[uuid.UUID]models.FarmerCrop{},
+		harvests:    map[uuid.UUID]models.FarmHarvest{},
+		feedback:    map[uuid.UUID]models.FarmerFeedback{},
+		visitNotes:  map[uuid.UUID]models.FarmerVisitNote{},
+		locations:   map[uuid.UUID]models.Location{},
+		revoked:     map[uuid.UUID]models.RevokedSession{},
+		cutoffs:     map[uuid.UUID]models.SessionCutoff{},
+		otpCodes:    map[string]models.OTPCode{},
+		resets:      map[uuid.UUID]models.PasswordResetToken{},
+		apiKeys:     map[uuid.UUID]models.APIKey{},
+		logins:      map[string]models.LoginAttempt{},
+	}}
+	db.seed()
+
+	store := &Store{
+		Profiles:    &memoryProfiles{db: db},
+		Roles:       &memoryRoles{db: db},
+		Farmers:     &memoryFarmers{db: db},
+		Officers:    &memoryOfficers{db: db},
+		Crops:       &memoryCrops{db: db},
+		FarmerCrops: &memoryFarmerCrops{db: db},
+		Harvests:    &memoryHarvests{db: db},
+		Feedback:    &memoryFeedback{db: db},
+		VisitNotes:  &memoryVisitNotes{db: db},
+		Locations:   &memoryLocations{db: db},
+		Sessions:    &memorySessions{db: db},
+		OTPCodes:    &memoryOTPCodes{db: db},
+		Resets:      &memoryResets{db: db},
+		Audit:       &memoryAudit{db: db},
+		APIKeys:     &memoryAPIKeys{db: db},
+		Logins:      &memoryLogins{db: db},
+	}
+	store.transact = func(fn func(tx *Store) error) error {
+		return db.transact(store, fn)
+	}
+	return store
+}
+
+// transact runs fn and restores every table to its prior state when fn fails.
+// Transactions are serialised, but a rollback also discards writes made outside
+// the transaction in the meantime, which is fine for tests and local demos.
+func (db *memoryDB) transact(store *Store, fn func(tx *Store) error) error {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+
+	snapshot := db.snapshot()
+
+	// Nested Transact calls on tx join this transaction
+	tx := *store
+	tx.transact = nil
+	if err := fn(&tx); err != nil {
+		db.mu.Lock()
+		db.memoryTables = snapshot
+		db.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// snapshot copies every table
+func (db *memoryDB) snapshot() memoryTables {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return memoryTables{
+		profiles:    maps.Clone(db.profiles),
+		roles:       maps.Clone(db.roles),
+		farmers:     maps.Clone(db.farmers),
+		officers:    maps.Clone(db.officers),
+		crops:       maps.Clone(db.crops),
+		cropAliases: maps.Clone(db.cropAliases),
+		farmerCrops: maps.Clone(db.farmerCrops),
+		harvests:    maps.Clone(db.harvests),
+		feedback:    maps.Clone(db.feedback),
+		visitNotes:  maps.Clone(db.visitNotes),
+		locations:   maps.Clone(db.locations),
+		revoked:     maps.Clone(db.revoked),
+		cutoffs:     maps.Clone(db.cutoffs),
+		otpCodes:    maps.Clone(db.otpCodes),
+		resets:      maps.Clone(db.resets),
+		audit:       slices.Clone(db.audit),
+		apiKeys:     maps.Clone(db.apiKeys),
+		logins:      maps.Clone(db.logins),
+	}
+}
+
+// seed loads the reference data the migrations insert
+func (db *memoryDB) seed() {
+	now := time.Now()
+	for _, name := range []string{"farmer", "extension_officer", "admin"} {
+		id := uuid.New()
+		db.roles[id] = models.Role{ID: id, Name: name, CreatedAt: now}
+	}
+
+	crops := [][2]string{
+		{"Maize", "Zea mays"}, {"Rice", "Oryza sativa"}, {"Wheat", "Triticum aestivum"},
+		{"Sorghum", "Sorghum bicolor"}, {"Millet", "Pennisetum glaucum"}, {"Beans", "Phaseolus vulgaris"},
+		{"Groundnuts", "Arachis hypogaea"}, {"Cassava", "Manihot esculenta"}, {"Sweet Potato", "Ipomoea batatas"},
+		{"Tomatoes", "Solanum lycopersicum"}, {"Onions", "Allium cepa"}, {"Cabbage", "Brassica oleracea"},
+		{"Carrots", "Daucus carota"}, {"Peppers", "Capsicum annuum"}, {"Okra", "Abelmoschus esculentus"},
+	}
+	cropIDs := map[string]uuid.UUID{}
+	for _, crop := range crops {
+		id := uuid.New()
+		scientificName := crop[1]
+		db.crops[id] = models.Crop{ID: id, Name: crop[0], ScientificName: &scientificName, CreatedAt: now}
+		cropIDs[crop[0]] = id
+	}
+
+	aliases := [][2]string{
+		{"Maize", "corn"}, {"Groundnuts", "peanuts"}, {"Groundnuts", "groundnut"}, {"Beans", "cowpea"},
+		{"Tomatoes", "tomato"}, {"Onions", "onion"}, {"Carrots", "carrot"}, {"Peppers", "pepper"},
+	}
+	for _, alias := range aliases {
+		id := uuid.New()
+		db.cropAliases[id] = models.CropAlias{ID: id, CropID: cropIDs[alias[0]], Alias: alias[1], CreatedAt: now}
+	}
+}
+
+// memoryProfiles implements ProfileRepository
+type memoryProfiles struct {
+	db *memoryDB
+}
+
+func (r *memoryProfiles) Create(profile models.UserProfile) (*models.UserProfile, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.profiles[profile.ID] = profile
+	return &profile, nil
+}
+
+func (r *memoryProfiles) GetByAuthUserID(authUserID string) (*models.UserProfile, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, profile := range r.db.profiles {
+		if profile.AuthUserID != nil && profile.AuthUserID.String() == authUserID {
+			return &profile, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryProfiles) FindByDisplayName(displayName string) (*models.UserProfile, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var found *models.UserProfile
+	for _, profile := range r.db.profiles {
+		if profile.DisplayName != nil && *profile.DisplayName == displayName && (found == nil || profile.CreatedAt.Before(found.CreatedAt)) {
+			match := profile
+			found = &match
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+func (r *memoryProfiles) FindByEmail(email string) (*models.UserProfile, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, profile := range r.db.profiles {
+		if profile.Email != nil && strings.EqualFold(*profile.Email, email) {
+			return &profile, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryProfiles) FindByPhone(phoneNumbers []string) (*models.UserProfile, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var found *models.UserProfile
+	for _, profile := range r.db.profiles {
+		for _, phoneNumber := range phoneNumbers {
+			if profile.Phone != nil && *profile.Phone == phoneNumber && (found == nil || profile.CreatedAt.Before(found.CreatedAt)) {
+				match := profile
+				found = &match
+			}
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+func (r *memoryProfiles) SetEmail(id uuid.UUID, email string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	profile, ok := r.db.profiles[id]
+	if !ok {
+		return ErrNotFound
+	}
+	profile.Email = &email
+	r.db.profiles[id] = profile
+	return nil
+}
+
+func (r *memoryProfiles) ListByIDs(ids []uuid.UUID) ([]models.UserProfile, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var profiles []models.UserProfile
+	for _, id := range ids {
+		if profile, ok := r.db.profiles[id]; ok {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles, nil
+}
+
+func (r *memoryProfiles) Search(filter ProfileFilter, page, perPage int) ([]models.UserProfile, int64, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	profiles := where(values(r.db.profiles), func(profile models.UserProfile) bool {
+		if filter.RoleID != nil && (profile.RoleID == nil || *profile.RoleID != *filter.RoleID) {
+			return false
+		}
+		if filter.Disabled != nil && (profile.DisabledAt != nil) != *filter.Disabled {
+			return false
+		}
+		return filter.Query == "" ||
+			(profile.DisplayName != nil && hasFoldPrefix(*profile.DisplayName, filter.Query)) ||
+			(profile.Email != nil && hasFoldPrefix(*profile.Email, filter.Query)) ||
+			(profile.Phone != nil && hasFoldPrefix(*profile.Phone, filter.Query))
+	})
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].CreatedAt.After(profiles[j].CreatedAt) })
+	return paginate(profiles, page, perPage)
+}
+
+func (r *memoryProfiles) SetRole(id, roleID uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	profile, ok := r.db.profiles[id]
+	if !ok {
+		return ErrNotFound
+	}
+	profile.RoleID = &roleID
+	r.db.profiles[id] = profile
+	return nil
+}
+
+func (r *memoryProfiles) SetDisabled(id uuid.UUID, disabledAt *time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	profile, ok := r.db.profiles[id]
+	if !ok {
+		return ErrNotFound
+	}
+	profile.DisabledAt = disabledAt
+	r.db.profiles[id] = profile
+	return nil
+}
+
+// Delete mirrors the ON DELETE CASCADE foreign key of farm_harvests
+func (r *memoryProfiles) Delete(id uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.profiles[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.db.profiles, id)
+
+	for harvestID, harvest := range r.db.harvests {
+		if harvest.UserProfileID != nil && *harvest.UserProfileID == id {
+			delete(r.db.harvests, harvestID)
+		}
+	}
+	return nil
+}
+
+// memoryRoles implements RoleRepository
+type memoryRoles struct {
+	db *memoryDB
+}
+
+func (r *memoryRoles) Get(id uuid.UUID) (*models.Role, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	role, ok := r.db.roles[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &role, nil
+}
+
+func (r *memoryRoles) GetByName(name string) (*models.Role, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, role := range r.db.roles {
+		if role.Name == name {
+			return &role, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryRoles) List() ([]models.Role, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	roles := values(r.db.roles)
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+// memoryFarmers implements FarmerRepository
+type memoryFarmers struct {
+	db *memoryDB
+}
+
+func (r *memoryFarmers) List(page, perPage int) ([]models.Farmer, int64, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	farmers := values(r.db.farmers)
+	sort.Slice(farmers, func(i, j int) bool { return farmers[i].CreatedAt.After(farmers[j].CreatedAt) })
+	return paginate(farmers, page, perPage)
+}
+
+func (r *memoryFarmers) ListByLocation(locationID int64, page, perPage int) ([]models.Farmer, int64, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	farmers := where(values(r.db.farmers), func(farmer models.Farmer) bool { return farmer.LocationID == locationID })
+	sort.Slice(farmers, func(i, j int) bool { return farmers[i].Name < farmers[j].Name })
+	return paginate(farmers, page, perPage)
+}
+
+func (r *memoryFarmers) ListByAuthUserIDs(authUserIDs []uuid.UUID) ([]models.Farmer, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	wanted := map[uuid.UUID]bool{}
+	for _, id := range authUserIDs {
+		wanted[id] = true
+	}
+	return where(values(r.db.farmers), func(farmer models.Farmer) bool {
+		return farmer.AuthUserID != nil && wanted[*farmer.AuthUserID]
+	}), nil
+}
+
+func (r *memoryFarmers) Get(id int64) (*models.Farmer, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	farmer, ok := r.db.farmers[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &farmer, nil
+}
+
+func (r *memoryFarmers) FindByPhone(phoneNumbers []string) (*models.Farmer, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var found *models.Farmer
+	for _, farmer := range r.db.farmers {
+		for _, phoneNumber := range phoneNumbers {
+			if farmer.PhoneNumber == phoneNumber && (found == nil || farmer.CreatedAt.Before(found.CreatedAt)) {
+				match := farmer
+				found = &match
+			}
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+func (r *memoryFarmers) ListByPhone(phoneNumbers []string) ([]models.Farmer, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	farmers := where(values(r.db.farmers), func(farmer models.Farmer) bool {
+		return slices.Contains(phoneNumbers, farmer.PhoneNumber)
+	})
+	sort.Slice(farmers, func(i, j int) bool { return farmers[i].CreatedAt.Before(farmers[j].CreatedAt) })
+	return farmers, nil
+}
+
+func (r *memoryFarmers) Create(farmer models.Farmer) (*models.Farmer, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if farmer.ID == 0 {
+		farmer.ID = nextID(r.db.farmers)
+	}
+	r.db.farmers[farmer.ID] = farmer
+	return &farmer, nil
+}
+
+func (r *memoryFarmers) Update(id int64, update FarmerUpdate) (*models.Farmer, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	farmer, ok := r.db.farmers[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if update.Name != nil {
+		farmer.Name = *update.Name
+	}
+	if update.PhoneNumber != nil {
+		farmer.PhoneNumber = *update.PhoneNumber
+	}
+	if update.CropType != nil {
+		farmer.CropType = *update.CropType
+	}
+	if update.LocationID != nil {
+		farmer.LocationID = *update.LocationID
+	}
+	if update.Language != nil {
+		farmer.Language = *update.Language
+	}
+	if update.AuthUserID != nil {
+		farmer.AuthUserID = update.AuthUserID
+	}
+
+	r.db.farmers[id] = farmer
+	return &farmer, nil
+}
+
+// Delete mirrors the ON DELETE CASCADE foreign keys of the SQL schema
+func (r *memoryFarmers) Delete(id int64) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.farmers[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.db.farmers, id)
+
+	for linkID, link := range r.db.farmerCrops {
+		if link.FarmerID == id {
+			delete(r.db.farmerCrops, linkID)
+		}
+	}
+	for feedbackID, feedback := range r.db.feedback {
+		if feedback.FarmerID != nil && *feedback.FarmerID == id {
+			delete(r.db.feedback, feedbackID)
+		}
+	}
+	for noteID, note := range r.db.visitNotes {
+		if note.FarmerID == id {
+			delete(r.db.visitNotes, noteID)
+		}
+	}
+	return nil
+}
+
+// memoryOfficers implements OfficerRepository
+type memoryOfficers struct {
+	db *memoryDB
+}
+
+func (r *memoryOfficers) Create(officer models.ExtensionOfficer) (*models.ExtensionOfficer, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if officer.ID == 0 {
+		officer.ID = nextID(r.db.officers)
+	}
+	r.db.officers[officer.ID] = officer
+	return &officer, nil
+}
+
+func (r *memoryOfficers) GetByAuthUserID(authUserID string) (*models.ExtensionOfficer, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, officer := range r.db.officers {
+		if officer.AuthUserID != nil && officer.AuthUserID.String() == authUserID {
+			return &officer, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryOfficers) SetAssignedLocation(id int64, locationID *int64) (*models.ExtensionOfficer, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	officer, ok := r.db.officers[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	officer.AssignedLocationID = locationID
+	r.db.officers[id] = officer
+	return &officer, nil
+}
+
+// Delete mirrors the ON DELETE CASCADE foreign key of farmer_visit_notes
+func (r *memoryOfficers) Delete(id int64) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.officers[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.db.officers, id)
+
+	for noteID, note := range r.db.visitNotes {
+		if note.OfficerID == id {
+			delete(r.db.visitNotes, noteID)
+		}
+	}
+	return nil
+}
+
+// memoryCrops implements CropRepository
+type memoryCrops struct {
+	db *memoryDB
+}
+
+func (r *memoryCrops) List(prefix string, page, perPage int) ([]models.Crop, int64, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	crops := where(values(r.db.crops), func(crop models.Crop) bool {
+		return hasFoldPrefix(crop.Name, prefix) || (crop.ScientificName != nil && hasFoldPrefix(*crop.ScientificName, prefix))
+	})
+	sort.Slice(crops, func(i, j int) bool { return crops[i].Name < crops[j].Name })
+	return paginate(crops, page, perPage)
+}
+
+func (r *memoryCrops) All() ([]models.Crop, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return values(r.db.crops), nil
+}
+
+func (r *memoryCrops) Get(id uuid.UUID) (*models.Crop, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	crop, ok := r.db.crops[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &crop, nil
+}
+
+func (r *memoryCrops) FindByName(name string) (*models.Crop, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, crop := range r.db.crops {
+		if strings.EqualFold(crop.Name, name) {
+			return &crop, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryCrops) Create(crop models.Crop) (*models.Crop, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.crops[crop.ID] = crop
+	return &crop, nil
+}
+
+func (r *memoryCrops) Delete(id uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.crops[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.db.crops, id)
+	return nil
+}
+
+func (r *memoryCrops) AllAliases() ([]models.CropAlias, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return values(r.db.cropAliases), nil
+}
+
+func (r *memoryCrops) FindAlias(alias string) (*models.CropAlias, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, existing := range r.db.cropAliases {
+		if strings.EqualFold(existing.Alias, alias) {
+			return &existing, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryCrops) CreateAlias(alias models.CropAlias) (*models.CropAlias, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.cropAliases[alias.ID] = alias
+	return &alias, nil
+}
+
+func (r *memoryCrops) MoveAliases(fromCropID, toCropID uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, alias := range r.db.cropAliases {
+		if alias.CropID == fromCropID {
+			alias.CropID = toCropID
+			r.db.cropAliases[id] = alias
+		}
+	}
+	return nil
+}
+
+// memoryFarmerCrops implements FarmerCropRepository
+type memoryFarmerCrops struct {
+	db *memoryDB
+}
+
+func (r *memoryFarmerCrops) CropsForFarmer(farmerID int64) ([]models.Crop, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	links := where(values(r.db.farmerCrops), func(link models.FarmerCrop) bool { return link.FarmerID == farmerID })
+	sort.Slice(links, func(i, j int) bool { return links[i].CreatedAt.Before(links[j].CreatedAt) })
+
+	var crops []models.Crop
+	for _, link := range links {
+		if crop, ok := r.db.crops[link.CropID]; ok {
+			crops = append(crops, crop)
+		}
+	}
+	return crops, nil
+}
+
+func (r *memoryFarmerCrops) ListByCrop(cropID uuid.UUID) ([]models.FarmerCrop, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return where(values(r.db.farmerCrops), func(link models.FarmerCrop) bool { return link.CropID == cropID }), nil
+}
+
+func (r *memoryFarmerCrops) Create(link models.FarmerCrop) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	// unique_farmer_crop
+	for _, existing := range r.db.farmerCrops {
+		if existing.FarmerID == link.FarmerID && existing.CropID == link.CropID {
+			return ErrConflict
+		}
+	}
+	r.db.farmerCrops[link.ID] = link
+	return nil
+}
+
+func (r *memoryFarmerCrops) SetCrop(linkID, cropID uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	link, ok := r.db.farmerCrops[linkID]
+	if !ok {
+		return ErrNotFound
+	}
+	link.CropID = cropID
+	r.db.farmerCrops[linkID] = link
+	return nil
+}
+
+func (r *memoryFarmerCrops) Delete(linkID uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.farmerCrops[linkID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.db.farmerCrops, linkID)
+	return nil
+}
+
+func (r *memoryFarmerCrops) DeleteByFarmer(farmerID int64) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, link := range r.db.farmerCrops {
+		if link.FarmerID == farmerID {
+			delete(r.db.farmerCrops, id)
+		}
+	}
+	return nil
+}
+
+// memoryHarvests implements HarvestRepository
+type memoryHarvests struct {
+	db *memoryDB
+}
+
+func (r *memoryHarvests) Create(harvest models.FarmHarvest) (*models.FarmHarvest, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.harvests[harvest.ID] = harvest
+	return &harvest, nil
+}
+
+func (r *memoryHarvests) ListByProfile(profileID uuid.UUID, filter HarvestFilter, page, perPage int) ([]models.FarmHarvestWithDetails, int64, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return paginate(r.newestFirst(profileID, filter), page, perPage)
+}
+
+func (r *memoryHarvests) RecentByProfile(profileID uuid.UUID, limit int) ([]models.FarmHarvestWithDetails, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	harvests := r.newestFirst(profileID, HarvestFilter{})
+	if len(harvests) > limit {
+		harvests = harvests[:limit]
+	}
+	return harvests, nil
+}
+
+func (r *memoryHarvests) List(filter HarvestFilter) ([]models.FarmHarvestWithDetails, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var harvests []models.FarmHarvestWithDetails
+	for _, harvest := range r.db.harvests {
+		if matchesHarvestFilter(harvest, filter) {
+			harvests = append(harvests, r.withCrop(harvest))
+		}
+	}
+	return harvests, nil
+}
+
+func (r *memoryHarvests) ListByLocation(locationID int64, filter HarvestFilter) ([]models.FarmHarvestWithDetails, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	authUserIDs := map[uuid.UUID]bool{}
+	for _, farmer := range r.db.farmers {
+		if farmer.AuthUserID != nil && farmer.LocationID == locationID {
+			authUserIDs[*farmer.AuthUserID] = true
+		}
+	}
+
+	var harvests []models.FarmHarvestWithDetails
+	for _, harvest := range r.db.harvests {
+		if harvest.UserProfileID == nil || !matchesHarvestFilter(harvest, filter) {
+			continue
+		}
+		profile, ok := r.db.profiles[*harvest.UserProfileID]
+		if ok && profile.AuthUserID != nil && authUserIDs[*profile.AuthUserID] {
+			harvests = append(harvests, r.withCrop(harvest))
+		}
+	}
+	return harvests, nil
+}
+
+func (r *memoryHarvests) MoveCrop(fromCropID, toCropID uuid.UUID) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	moved := 0
+	for id, harvest := range r.db.harvests {
+		if harvest.CropID != nil && *harvest.CropID == fromCropID {
+			cropID := toCropID
+			harvest.CropID = &cropID
+			r.db.harvests[id] = harvest
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+// newestFirst returns a profile's harvests matching the filter, latest harvest first; callers hold the lock
+func (r *memoryHarvests) newestFirst(profileID uuid.UUID, filter HarvestFilter) []models.FarmHarvestWithDetails {
+	var harvests []models.FarmHarvestWithDetails
+	for _, harvest := range r.db.harvests {
+		if harvest.UserProfileID != nil && *harvest.UserProfileID == profileID && matchesHarvestFilter(harvest, filter) {
+			harvests = append(harvests, r.withCrop(harvest))
+		}
+	}
+	sort.Slice(harvests, func(i, j int) bool {
+		return timeOrZero(harvests[i].HarvestedAt).After(timeOrZero(harvests[j].HarvestedAt))
+	})
+	return harvests
+}
+
+// withCrop embeds the harvested crop like the "crop:crops(*)" select does; callers hold the lock
+func (r *memoryHarvests) withCrop(harvest models.FarmHarvest) models.FarmHarvestWithDetails {
+	details := models.FarmHarvestWithDetails{FarmHarvest: harvest}
+	if harvest.CropID != nil {
+		if crop, ok := r.db.crops[*harvest.CropID]; ok {
+			details.Crop = &crop
+		}
+	}
+	return details
+}
+
+// matchesHarvestFilter applies a HarvestFilter to one harvest
+func matchesHarvestFilter(harvest models.FarmHarvest, filter HarvestFilter) bool {
+	if filter.CropID != nil && (harvest.CropID == nil || *harvest.CropID != *filter.CropID) {
+		return false
+	}
+	if filter.From != nil && (harvest.HarvestedAt == nil || harvest.HarvestedAt.Before(*filter.From)) {
+		return false
+	}
+	if filter.To != nil && (harvest.HarvestedAt == nil || harvest.HarvestedAt.After(*filter.To)) {
+		return false
+	}
+	return true
+}
+
+// memoryFeedback implements FeedbackRepository
+type memoryFeedback struct {
+	db *memoryDB
+}
+
+func (r *memoryFeedback) Create(feedback models.FarmerFeedback) (*models.FarmerFeedback, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.feedback[feedback.ID] = feedback
+	return &feedback, nil
+}
+
+func (r *memoryFeedback) RecentByFarmer(farmerID int64, limit int) ([]models.FarmerFeedback, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	feedback := where(values(r.db.feedback), func(item models.FarmerFeedback) bool {
+		return item.FarmerID != nil && *item.FarmerID == farmerID
+	})
+	sort.Slice(feedback, func(i, j int) bool { return feedback[i].CreatedAt.After(feedback[j].CreatedAt) })
+	if len(feedback) > limit {
+		feedback = feedback[:limit]
+	}
+	return feedback, nil
+}
+
+func (r *memoryFeedback) ListByFarmerOrPhone(farmerIDs []int64, phoneNumbers []string) ([]models.FarmerFeedback, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	feedback := where(values(r.db.feedback), func(item models.FarmerFeedback) bool {
+		return (item.FarmerID != nil && slices.Contains(farmerIDs, *item.FarmerID)) || slices.Contains(phoneNumbers, item.PhoneNumber)
+	})
+	sort.Slice(feedback, func(i, j int) bool { return feedback[i].CreatedAt.After(feedback[j].CreatedAt) })
+	return feedback, nil
+}
+
+func (r *memoryFeedback) DeleteByPhone(phoneNumbers []string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, item := range r.db.feedback {
+		if slices.Contains(phoneNumbers, item.PhoneNumber) {
+			delete(r.db.feedback, id)
+		}
+	}
+	return nil
+}
+
+// memoryVisitNotes implements VisitNoteRepository
+type memoryVisitNotes struct {
+	db *memoryDB
+}
+
+func (r *memoryVisitNotes) Create(note models.FarmerVisitNote) (*models.FarmerVisitNote, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.visitNotes[note.ID] = note
+	return &note, nil
+}
+
+func (r *memoryVisitNotes) ListByFarmer(farmerID int64) ([]models.FarmerVisitNote, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	notes := where(values(r.db.visitNotes), func(note models.FarmerVisitNote) bool { return note.FarmerID == farmerID })
+	sort.Slice(notes, func(i, j int) bool { return notes[i].VisitedAt.After(notes[j].VisitedAt) })
+	return notes, nil
+}
+
+// memoryLocations implements LocationRepository
+type memoryLocations struct {
+	db *memoryDB
+}
+
+func (r *memoryLocations) List(prefix string, page, perPage int) ([]models.Location, int64, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	locations := where(values(r.db.locations), func(location models.Location) bool {
+		return hasFoldPrefix(location.Name, prefix) || (location.Country != nil && hasFoldPrefix(*location.Country, prefix))
+	})
+	sort.Slice(locations, func(i, j int) bool { return locations[i].Name < locations[j].Name })
+	return paginate(locations, page, perPage)
+}
+
+func (r *memoryLocations) All() ([]models.Location, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	return values(r.db.locations), nil
+}
+
+func (r *memoryLocations) Get(id uuid.UUID) (*models.Location, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	location, ok := r.db.locations[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &location, nil
+}
+
+func (r *memoryLocations) Create(location models.Location) (*models.Location, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.locations[location.ID] = location
+	return &location, nil
+}
+
+// memorySessions implements SessionRepository
+type memorySessions struct {
+	db *memoryDB
+}
+
+func (r *memorySessions) Revoke(session models.RevokedSession) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.revoked[session.SessionID]; !ok {
+		r.db.revoked[session.SessionID] = session
+	}
+	return nil
+}
+
+func (r *memorySessions) IsRevoked(sessionID uuid.UUID) (bool, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	_, ok := r.db.revoked[sessionID]
+	return ok, nil
+}
+
+func (r *memorySessions) SetCutoff(cutoff models.SessionCutoff) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if existing, ok := r.db.cutoffs[cutoff.AuthUserID]; ok && existing.RevokedBefore.After(cutoff.RevokedBefore) {
+		return nil
+	}
+	r.db.cutoffs[cutoff.AuthUserID] = cutoff
+	return nil
+}
+
+func (r *memorySessions) GetCutoff(authUserID uuid.UUID) (*models.SessionCutoff, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	cutoff, ok := r.db.cutoffs[authUserID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &cutoff, nil
+}
+
+func (r *memorySessions) DeleteExpired(now time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for sessionID, session := range r.db.revoked {
+		if session.ExpiresAt.Before(now) {
+			delete(r.db.revoked, sessionID)
+		}
+	}
+	return nil
+}
+
+// memoryOTPCodes implements OTPRepository
+type memoryOTPCodes struct {
+	db *memoryDB
+}
+
+func (r *memoryOTPCodes) Save(code models.OTPCode) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.otpCodes[code.PhoneNumber] = code
+	return nil
+}
+
+func (r *memoryOTPCodes) Get(phoneNumber string) (*models.OTPCode, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	code, ok := r.db.otpCodes[phoneNumber]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &code, nil
+}
+
+func (r *memoryOTPCodes) RecordAttempt(phoneNumber string) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	code, ok := r.db.otpCodes[phoneNumber]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	code.Attempts++
+	r.db.otpCodes[phoneNumber] = code
+	return code.Attempts, nil
+}
+
+func (r *memoryOTPCodes) Delete(phoneNumber string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.otpCodes, phoneNumber)
+	return nil
+}
+
+// memoryResets implements PasswordResetRepository
+type memoryResets struct {
+	db *memoryDB
+}
+
+func (r *memoryResets) Save(token models.PasswordResetToken) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.resets[token.AuthUserID] = token
+	return nil
+}
+
+func (r *memoryResets) Get(authUserID uuid.UUID) (*models.PasswordResetToken, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	token, ok := r.db.resets[authUserID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &token, nil
+}
+
+func (r *memoryResets) RecordAttempt(authUserID uuid.UUID) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	token, ok := r.db.resets[authUserID]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	token.Attempts++
+	r.db.resets[authUserID] = token
+	return token.Attempts, nil
+}
+
+func (r *memoryResets) Delete(authUserID uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.resets, authUserID)
+	return nil
+}
+
+// memoryAudit implements AuditRepository
+type memoryAudit struct {
+	db *memoryDB
+}
+
+func (r *memoryAudit) Create(event models.AuditEvent) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.audit = append(r.db.audit, event)
+	return nil
+}
+
+func (r *memoryAudit) List(filter AuditFilter, page, perPage int) ([]models.AuditEvent, int64, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	events := where(r.db.audit, func(event models.AuditEvent) bool {
+		if filter.ActorID != nil && (event.ActorID == nil || *event.ActorID != *filter.ActorID) {
+			return false
+		}
+		if filter.TargetID != nil && (event.TargetID == nil || *event.TargetID != *filter.TargetID) {
+			return false
+		}
+		if filter.From != nil && event.CreatedAt.Before(*filter.From) {
+			return false
+		}
+		if filter.To != nil && event.CreatedAt.After(*filter.To) {
+			return false
+		}
+		return strings.HasPrefix(event.Action, filter.Action) &&
+			(filter.RequestID == "" || event.RequestID == filter.RequestID) &&
+			(filter.IPAddress == "" || event.IPAddress == filter.IPAddress)
+	})
+	sort.SliceStable(events, func(i, j int) bool { return events[i].CreatedAt.After(events[j].CreatedAt) })
+	return paginate(events, page, perPage)
+}
+
+// memoryAPIKeys implements APIKeyRepository
+type memoryAPIKeys struct {
+	db *memoryDB
+}
+
+func (r *memoryAPIKeys) Create(key models.APIKey) (*models.APIKey, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.apiKeys[key.ID] = key
+	return &key, nil
+}
+
+func (r *memoryAPIKeys) List() ([]models.APIKey, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	keys := values(r.db.apiKeys)
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+func (r *memoryAPIKeys) Get(id uuid.UUID) (*models.APIKey, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	key, ok := r.db.apiKeys[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &key, nil
+}
+
+func (r *memoryAPIKeys) GetByPrefix(prefix string) (*models.APIKey, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, key := range r.db.apiKeys {
+		if key.Prefix == prefix {
+			return &key, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryAPIKeys) Revoke(id uuid.UUID, revokedAt time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	key, ok := r.db.apiKeys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	key.RevokedAt = &revokedAt
+	r.db.apiKeys[id] = key
+	return nil
+}
+
+func (r *memoryAPIKeys) TouchLastUsed(id uuid.UUID, usedAt time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	key, ok := r.db.apiKeys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	key.LastUsedAt = &usedAt
+	r.db.apiKeys[id] = key
+	return nil
+}
+
+// memoryLogins implements LoginAttemptRepository
+type memoryLogins struct {
+	db *memoryDB
+}
+
+func (r *memoryLogins) Get(key string) (*models.LoginAttempt, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	attempt, ok := r.db.logins[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &attempt, nil
+}
+
+func (r *memoryLogins) RecordFailure(key string, at, windowStart time.Time) (*models.LoginAttempt, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	attempt, ok := r.db.logins[key]
+	if !ok || attempt.LastFailureAt.Before(windowStart) {
+		attempt = models.LoginAttempt{Key: key}
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = at
+	r.db.logins[key] = attempt
+	return &attempt, nil
+}
+
+func (r *memoryLogins) Lock(key string, until time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	attempt, ok := r.db.logins[key]
+	if !ok {
+		return ErrNotFound
+	}
+	attempt.LockedUntil = &until
+	r.db.logins[key] = attempt
+	return nil
+}
+
+func (r *memoryLogins) ListLocked(at time.Time) ([]models.LoginAttempt, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var locked []models.LoginAttempt
+	for _, attempt := range r.db.logins {
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(at) {
+			locked = append(locked, attempt)
+		}
+	}
+	slices.SortFunc(locked, func(a, b models.LoginAttempt) int {
+		return b.LockedUntil.Compare(*a.LockedUntil)
+	})
+	return locked, nil
+}
+
+func (r *memoryLogins) Delete(key string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.logins, key)
+	return nil
+}
+
+// values copies the rows of a table into a slice
+func values[K comparable, V any](table map[K]V) []V {
+	rows := make([]V, 0, len(table))
+	for _, row := range table {
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// nextID returns one past the highest ID in a table, like a sequence would
+func nextID[V any](table map[int64]V) int64 {
+	var highest int64
+	for id := range table {
+		highest = max(highest, id)
+	}
+	return highest + 1
+}
+
+// where keeps the rows that match
+func where[T any](rows []T, keep func(T) bool) []T {
+	var kept []T
+	for _, row := range rows {
+		if keep(row) {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
+
+// paginate returns one page of sorted rows and the total row count
+func paginate[T any](rows []T, page, perPage int) ([]T, int64, error) {
+	total := int64(len(rows))
+	from := (page - 1) * perPage
+	if from >= len(rows) {
+		return []T{}, total, nil
+	}
+	return rows[from:min(from+perPage, len(rows))], total, nil
+}
+
+// hasFoldPrefix reports whether s starts with prefix, ignoring case
+func hasFoldPrefix(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// timeOrZero returns the value of an optional time, or the zero time
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
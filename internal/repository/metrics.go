@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/okoye-dev/flux-server/internal/metrics"
+)
+
+// Storage backends as labelled in the query latency metric
+const (
+	metricsBackendSupabase = "supabase"
+	metricsBackendPostgres = "postgres"
+)
+
+// timedTransport records the latency of PostgREST requests, by the operation their
+// method stands for
+type timedTransport struct {
+	next http.RoundTripper
+}
+
+func (t timedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	metrics.DatabaseQueryDuration.ObserveSince(start, metricsBackendSupabase, postgrestOperation(req.Method))
+	return resp, err
+}
+
+// postgrestOperation names the SQL operation a PostgREST request method performs
+func postgrestOperation(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return "select"
+	case http.MethodPost:
+		return "insert"
+	case http.MethodPatch, http.MethodPut:
+		return "update"
+	case http.MethodDelete:
+		return "delete"
+	}
+	return "other"
+}
+
+// timedQuerier records the latency of queries on a connection pool or transaction, by
+// their SQL operation
+type timedQuerier struct {
+	q querier
+}
+
+func (t timedQuerier) Exec(query string, args ...any) (sql.Result, error) {
+	defer observeQuery(time.Now(), query)
+	return t.q.Exec(query, args...)
+}
+
+func (t timedQuerier) Query(query string, args ...any) (*sql.Rows, error) {
+	defer observeQuery(time.Now(), query)
+	return t.q.Query(query, args...)
+}
+
+func (t timedQuerier) QueryRow(query string, args ...any) *sql.Row {
+	defer observeQuery(time.Now(), query)
+	return t.q.QueryRow(query, args...)
+}
+
+func observeQuery(start time.Time, query string) {
+	metrics.DatabaseQueryDuration.ObserveSince(start, metricsBackendPostgres, sqlOperation(query))
+}
+
+// sqlOperation returns the lowercased first keyword of a statement, such as "select"
+func sqlOperation(query string) string {
+	keyword := strings.TrimLeftFunc(query, unicode.IsSpace)
+	if end := strings.IndexFunc(keyword, unicode.IsSpace); end >= 0 {
+		keyword = keyword[:end]
+	}
+	switch keyword = strings.ToLower(keyword); keyword {
+	case "select", "insert", "update", "delete", "with":
+		return keyword
+	}
+	return "other"
+}
@@ -0,0 +1,1051 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" database/sql driver
+	"github.com/okoye-dev/flux-server/internal/models"
+)
+
+// Columns selected for each table; nullable text columns mapped onto plain strings are coalesced
+const (
+	profileColumns    = "id, auth_user_id, role_id, display_name, email, phone, metadata, created_at, disabled_at"
+	roleColumns       = "id, name, description, created_at"
+	farmerColumns     = "id, auth_user_id, name, COALESCE(phone_number, ''), COALESCE(crop_type, ''), COALESCE(location_id, 0), COALESCE(language, ''), created_at"
+	officerColumns    = "id, auth_user_id, name, COALESCE(phone_number, ''), assigned_location_id"
+	cropColumns       = "c.id, c.name, c.scientific_name, c.created_at"
+	cropAliasColumns  = "id, crop_id, alias, created_at"
+	farmerCropColumns = "id, farmer_id, crop_id, created_at"
+	harvestColumns    = "h.id, h.user_profile_id, h.crop_id, h.quantity, h.harvested_at"
+	feedbackColumns   = "id, farmer_id, COALESCE(phone_number, ''), message, ai_response, created_at"
+	visitNoteColumns  = "id, farmer_id, officer_id, note, visited_at, created_at"
+	locationColumns   = "id, name, country, created_at"
+	cutoffColumns     = "auth_user_id, revoked_before"
+	otpCodeColumns    = "phone_number, code_hash, attempts, expires_at, created_at"
+	resetColumns      = "auth_user_id, token_hash, attempts, expires_at, created_at"
+	apiKeyColumns     = "id, name, owner_id, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_by, created_at"
+	loginColumns      = "key, failures, last_failure_at, locked_until"
+	auditColumns      = "id, actor_id, action, target_id, COALESCE(ip_address, ''), COALESCE(user_agent, ''), COALESCE(request_id, ''), details, created_at"
+)
+
+// NewPostgresStore creates a store that talks to Postgres directly through a DSN.
+// Unlike PostgREST it supports transactions, see Store.Transact.
+func NewPostgresStore(databaseURL string) (*Store, error) {
+	db, err := OpenPostgres(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	store := newPostgresStore(db)
+	store.transact = func(fn func(tx *Store) error) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		// Rollback is a no-op once the transaction has been committed
+		defer tx.Rollback()
+
+		if err := fn(newPostgresStore(tx)); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	return store, nil
+}
+
+// OpenPostgres opens and checks a connection pool for a Postgres DSN
+func OpenPostgres(databaseURL string) (*sql.DB, error) {
+	if databaseURL == "" {
+		return nil, ErrDatabaseURLMissing
+	}
+
+	db, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
+
+// newPostgresStore builds the repositories on top of a connection pool or a transaction,
+// timing their queries
+func newPostgresStore(pool querier) *Store {
+	q := timedQuerier{q: pool}
+	return &Store{
+		Profiles:    &postgresProfiles{q: q},
+		Roles:       &postgresRoles{q: q},
+		Farmers:     &postgresFarmers{q: q},
+		Officers:    &postgresOfficers{q: q},
+		Crops:       &postgresCrops{q: q},
+		FarmerCrops: &postgresFarmerCrops{q: q},
+		Harvests:    &postgresHarvests{q: q},
+		Feedback:    &postgresFeedback{q: q},
+		VisitNotes:  &postgresVisitNotes{q: q},
+		Locations:   &postgresLocations{q: q},
+		Sessions:    &postgresSessions{q: q},
+		OTPCodes:    &postgresOTPCodes{q: q},
+		Resets:      &postgresResets{q: q},
+		Audit:       &postgresAudit{q: q},
+		APIKeys:     &postgresAPIKeys{q: q},
+		Logins:      &postgresLogins{q: q},
+	}
+}
+
+// querier is the part of *sql.DB and *sql.Tx the repositories use
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// postgresProfiles implements ProfileRepository
+type postgresProfiles struct {
+	q querier
+}
+
+func (r *postgresProfiles) Create(profile models.UserProfile) (*models.UserProfile, error) {
+	metadata, err := json.Marshal(profile.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return queryRow(r.q, scanProfile,
+		"INSERT INTO user_profiles (id, auth_user_id, role_id, display_name, email, phone, metadata, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING "+profileColumns,
+		profile.ID, profile.AuthUserID, profile.RoleID, profile.DisplayName, profile.Email, profile.Phone, string(metadata), profile.CreatedAt)
+}
+
+func (r *postgresProfiles) GetByAuthUserID(authUserID string) (*models.UserProfile, error) {
+	id, err := uuid.Parse(authUserID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return queryRow(r.q, scanProfile, "SELECT "+profileColumns+" FROM user_profiles WHERE auth_user_id = $1 LIMIT 1", id)
+}
+
+func (r *postgresProfiles) FindByDisplayName(displayName string) (*models.UserProfile, error) {
+	return queryRow(r.q, scanProfile, "SELECT "+profileColumns+" FROM user_profiles WHERE display_name = $1 ORDER BY created_at LIMIT 1", displayName)
+}
+
+func (r *postgresProfiles) FindByEmail(email string) (*models.UserProfile, error) {
+	return queryRow(r.q, scanProfile, "SELECT "+profileColumns+" FROM user_profiles WHERE email = lower($1) LIMIT 1", email)
+}
+
+func (r *postgresProfiles) FindByPhone(phoneNumbers []string) (*models.UserProfile, error) {
+	if len(phoneNumbers) == 0 {
+		return nil, ErrNotFound
+	}
+	return queryRow(r.q, scanProfile,
+		"SELECT "+profileColumns+" FROM user_profiles WHERE phone = ANY($1) ORDER BY created_at LIMIT 1",
+		phoneNumbers)
+}
+
+func (r *postgresProfiles) SetEmail(id uuid.UUID, email string) error {
+	return execAffected(r.q, "UPDATE user_profiles SET email = $2 WHERE id = $1", id, email)
+}
+
+func (r *postgresProfiles) ListByIDs(ids []uuid.UUID) ([]models.UserProfile, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return queryRows(r.q, scanProfile, "SELECT "+profileColumns+" FROM user_profiles WHERE id = ANY($1)", ids)
+}
+
+// Delete relies on ON DELETE CASCADE for farm_harvests
+func (r *postgresProfiles) Search(filter ProfileFilter, page, perPage int) ([]models.UserProfile, int64, error) {
+	var conditions []string
+	var args []any
+	if filter.Query != "" {
+		args = append(args, likePrefix(filter.Query))
+		conditions = append(conditions, fmt.Sprintf("(display_name ILIKE $%d OR email ILIKE $%d OR phone ILIKE $%d)", len(args), len(args), len(args)))
+	}
+	if filter.RoleID != nil {
+		args = append(args, *filter.RoleID)
+		conditions = append(conditions, fmt.Sprintf("role_id = $%d", len(args)))
+	}
+	if filter.Disabled != nil {
+		if *filter.Disabled {
+			conditions = append(conditions, "disabled_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "disabled_at IS NULL")
+		}
+	}
+	clause := ""
+	if len(conditions) > 0 {
+		clause = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	total, err := queryCount(r.q, "SELECT COUNT(*) FROM user_profiles"+clause, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, perPage, (page-1)*perPage)
+	profiles, err := queryRows(r.q, scanProfile,
+		fmt.Sprintf("SELECT %s FROM user_profiles%s ORDER BY created_at DESC LIMIT $%d OFFSET $%d", profileColumns, clause, len(args)-1, len(args)),
+		args...)
+	return profiles, total, err
+}
+
+func (r *postgresProfiles) SetRole(id, roleID uuid.UUID) error {
+	return execAffected(r.q, "UPDATE user_profiles SET role_id = $2 WHERE id = $1", id, roleID)
+}
+
+func (r *postgresProfiles) SetDisabled(id uuid.UUID, disabledAt *time.Time) error {
+	return execAffected(r.q, "UPDATE user_profiles SET disabled_at = $2 WHERE id = $1", id, disabledAt)
+}
+
+func (r *postgresProfiles) Delete(id uuid.UUID) error {
+	return execAffected(r.q, "DELETE FROM user_profiles WHERE id = $1", id)
+}
+
+// postgresRoles implements RoleRepository
+type postgresRoles struct {
+	q querier
+}
+
+func (r *postgresRoles) Get(id uuid.UUID) (*models.Role, error) {
+	return queryRow(r.q, scanRole, "SELECT "+roleColumns+" FROM roles WHERE id = $1", id)
+}
+
+func (r *postgresRoles) GetByName(name string) (*models.Role, error) {
+	return queryRow(r.q, scanRole, "SELECT "+roleColumns+" FROM roles WHERE name = $1 LIMIT 1", name)
+}
+
+func (r *postgresRoles) List() ([]models.Role, error) {
+	return queryRows(r.q, scanRole, "SELECT "+roleColumns+" FROM roles ORDER BY name")
+}
+
+// postgresFarmers implements FarmerRepository
+type postgresFarmers struct {
+	q querier
+}
+
+func (r *postgresFarmers) List(page, perPage int) ([]models.Farmer, int64, error) {
+	total, err := queryCount(r.q, "SELECT COUNT(*) FROM farmers")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	farmers, err := queryRows(r.q, scanFarmer,
+		"SELECT "+farmerColumns+" FROM farmers ORDER BY created_at DESC LIMIT $1 OFFSET $2",
+		perPage, (page-1)*perPage)
+	return farmers, total, err
+}
+
+func (r *postgresFarmers) ListByLocation(locationID int64, page, perPage int) ([]models.Farmer, int64, error) {
+	total, err := queryCount(r.q, "SELECT COUNT(*) FROM farmers WHERE location_id = $1", locationID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	farmers, err := queryRows(r.q, scanFarmer,
+		"SELECT "+farmerColumns+" FROM farmers WHERE location_id = $1 ORDER BY name LIMIT $2 OFFSET $3",
+		locationID, perPage, (page-1)*perPage)
+	return farmers, total, err
+}
+
+func (r *postgresFarmers) ListByAuthUserIDs(authUserIDs []uuid.UUID) ([]models.Farmer, error) {
+	if len(authUserIDs) == 0 {
+		return nil, nil
+	}
+	return queryRows(r.q, scanFarmer, "SELECT "+farmerColumns+" FROM farmers WHERE auth_user_id = ANY($1)", authUserIDs)
+}
+
+func (r *postgresFarmers) Get(id int64) (*models.Farmer, error) {
+	return queryRow(r.q, scanFarmer, "SELECT "+farmerColumns+" FROM farmers WHERE id = $1", id)
+}
+
+func (r *postgresFarmers) FindByPhone(phoneNumbers []string) (*models.Farmer, error) {
+	if len(phoneNumbers) == 0 {
+		return nil, ErrNotFound
+	}
+	return queryRow(r.q, scanFarmer,
+		"SELECT "+farmerColumns+" FROM farmers WHERE phone_number = ANY($1) ORDER BY created_at LIMIT 1",
+		phoneNumbers)
+}
+
+func (r *postgresFarmers) ListByPhone(phoneNumbers []string) ([]models.Farmer, error) {
+	if len(phoneNumbers) == 0 {
+		return nil, nil
+	}
+	return queryRows(r.q, scanFarmer,
+		"SELECT "+farmerColumns+" FROM farmers WHERE phone_number = ANY($1) ORDER BY created_at",
+		phoneNumbers)
+}
+
+// Create takes a zero ID from the farmers id sequence (migration 004)
+func (r *postgresFarmers) Create(farmer models.Farmer) (*models.Farmer, error) {
+	return queryRow(r.q, scanFarmer,
+		"INSERT INTO farmers (id, auth_user_id, name, phone_number, crop_type, location_id, language, created_at) VALUES ("+nextIDOr("farmers", "$1")+", $2, $3, $4, $5, $6, $7, $8) RETURNING "+farmerColumns,
+		farmer.ID, farmer.AuthUserID, farmer.Name, farmer.PhoneNumber, farmer.CropType, farmer.LocationID, farmer.Language, farmer.CreatedAt)
+}
+
+// Update leaves a column untouched when its FarmerUpdate field is nil, which binds as NULL
+func (r *postgresFarmers) Update(id int64, update FarmerUpdate) (*models.Farmer, error) {
+	return queryRow(r.q, scanFarmer,
+		`UPDATE farmers SET
+			name = COALESCE($2, name),
+			phone_number = COALESCE($3, phone_number),
+			crop_type = COALESCE($4, crop_type),
+			location_id = COALESCE($5, location_id),
+			language = COALESCE($6, language),
+			auth_user_id = COALESCE($7, auth_user_id)
+		WHERE id = $1 RETURNING `+farmerColumns,
+		id, update.Name, update.PhoneNumber, update.CropType, update.LocationID, update.Language, update.AuthUserID)
+}
+
+// Delete relies on ON DELETE CASCADE for farmer_crops, farmer_feedback and farmer_visit_notes
+func (r *postgresFarmers) Delete(id int64) error {
+	return execAffected(r.q, "DELETE FROM farmers WHERE id = $1", id)
+}
+
+// postgresOfficers implements OfficerRepository
+type postgresOfficers struct {
+	q querier
+}
+
+// Create takes a zero ID from the extension_officers id sequence (migration 004)
+func (r *postgresOfficers) Create(officer models.ExtensionOfficer) (*models.ExtensionOfficer, error) {
+	return queryRow(r.q, scanOfficer,
+		"INSERT INTO extension_officers (id, auth_user_id, name, phone_number, assigned_location_id) VALUES ("+nextIDOr("extension_officers", "$1")+", $2, $3, $4, $5) RETURNING "+officerColumns,
+		officer.ID, officer.AuthUserID, officer.Name, officer.PhoneNumber, officer.AssignedLocationID)
+}
+
+func (r *postgresOfficers) GetByAuthUserID(authUserID string) (*models.ExtensionOfficer, error) {
+	id, err := uuid.Parse(authUserID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return queryRow(r.q, scanOfficer, "SELECT "+officerColumns+" FROM extension_officers WHERE auth_user_id = $1 LIMIT 1", id)
+}
+
+func (r *postgresOfficers) SetAssignedLocation(id int64, locationID *int64) (*models.ExtensionOfficer, error) {
+	return queryRow(r.q, scanOfficer,
+		"UPDATE extension_officers SET assigned_location_id = $2 WHERE id = $1 RETURNING "+officerColumns,
+		id, locationID)
+}
+
+// Delete relies on ON DELETE CASCADE for farmer_visit_notes
+func (r *postgresOfficers) Delete(id int64) error {
+	return execAffected(r.q, "DELETE FROM extension_officers WHERE id = $1", id)
+}
+
+// postgresCrops implements CropRepository
+type postgresCrops struct {
+	q querier
+}
+
+func (r *postgresCrops) List(prefix string, page, perPage int) ([]models.Crop, int64, error) {
+	pattern := likePrefix(prefix)
+	total, err := queryCount(r.q, "SELECT COUNT(*) FROM crops c WHERE c.name ILIKE $1 OR c.scientific_name ILIKE $1", pattern)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	crops, err := queryRows(r.q, scanCrop,
+		"SELECT "+cropColumns+" FROM crops c WHERE c.name ILIKE $1 OR c.scientific_name ILIKE $1 ORDER BY c.name LIMIT $2 OFFSET $3",
+		pattern, perPage, (page-1)*perPage)
+	return crops, total, err
+}
+
+func (r *postgresCrops) All() ([]models.Crop, error) {
+	return queryRows(r.q, scanCrop, "SELECT "+cropColumns+" FROM crops c")
+}
+
+func (r *postgresCrops) Get(id uuid.UUID) (*models.Crop, error) {
+	return queryRow(r.q, scanCrop, "SELECT "+cropColumns+" FROM crops c WHERE c.id = $1", id)
+}
+
+func (r *postgresCrops) FindByName(name string) (*models.Crop, error) {
+	return queryRow(r.q, scanCrop, "SELECT "+cropColumns+" FROM crops c WHERE LOWER(c.name) = LOWER($1) LIMIT 1", name)
+}
+
+func (r *postgresCrops) Create(crop models.Crop) (*models.Crop, error) {
+	return queryRow(r.q, scanCrop,
+		"INSERT INTO crops AS c (id, name, scientific_name, created_at) VALUES ($1, $2, $3, $4) RETURNING "+cropColumns,
+		crop.ID, crop.Name, crop.ScientificName, crop.CreatedAt)
+}
+
+func (r *postgresCrops) Delete(id uuid.UUID) error {
+	return execAffected(r.q, "DELETE FROM crops WHERE id = $1", id)
+}
+
+func (r *postgresCrops) AllAliases() ([]models.CropAlias, error) {
+	return queryRows(r.q, scanCropAlias, "SELECT "+cropAliasColumns+" FROM crop_aliases")
+}
+
+func (r *postgresCrops) FindAlias(alias string) (*models.CropAlias, error) {
+	return queryRow(r.q, scanCropAlias, "SELECT "+cropAliasColumns+" FROM crop_aliases WHERE LOWER(alias) = LOWER($1) LIMIT 1", alias)
+}
+
+func (r *postgresCrops) CreateAlias(alias models.CropAlias) (*models.CropAlias, error) {
+	return queryRow(r.q, scanCropAlias,
+		"INSERT INTO crop_aliases (id, crop_id, alias, created_at) VALUES ($1, $2, $3, $4) RETURNING "+cropAliasColumns,
+		alias.ID, alias.CropID, alias.Alias, alias.CreatedAt)
+}
+
+func (r *postgresCrops) MoveAliases(fromCropID, toCropID uuid.UUID) error {
+	_, err := r.q.Exec("UPDATE crop_aliases SET crop_id = $2 WHERE crop_id = $1", fromCropID, toCropID)
+	return err
+}
+
+// postgresFarmerCrops implements FarmerCropRepository
+type postgresFarmerCrops struct {
+	q querier
+}
+
+func (r *postgresFarmerCrops) CropsForFarmer(farmerID int64) ([]models.Crop, error) {
+	return queryRows(r.q, scanCrop,
+		"SELECT "+cropColumns+" FROM farmer_crops fc JOIN crops c ON c.id = fc.crop_id WHERE fc.farmer_id = $1 ORDER BY fc.created_at",
+		farmerID)
+}
+
+func (r *postgresFarmerCrops) ListByCrop(cropID uuid.UUID) ([]models.FarmerCrop, error) {
+	return queryRows(r.q, scanFarmerCrop, "SELECT "+farmerCropColumns+" FROM farmer_crops WHERE crop_id = $1", cropID)
+}
+
+func (r *postgresFarmerCrops) Create(link models.FarmerCrop) error {
+	_, err := r.q.Exec("INSERT INTO farmer_crops (id, farmer_id, crop_id, created_at) VALUES ($1, $2, $3, $4)",
+		link.ID, link.FarmerID, link.CropID, link.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *postgresFarmerCrops) SetCrop(linkID, cropID uuid.UUID) error {
+	return execAffected(r.q, "UPDATE farmer_crops SET crop_id = $2 WHERE id = $1", linkID, cropID)
+}
+
+func (r *postgresFarmerCrops) Delete(linkID uuid.UUID) error {
+	return execAffected(r.q, "DELETE FROM farmer_crops WHERE id = $1", linkID)
+}
+
+func (r *postgresFarmerCrops) DeleteByFarmer(farmerID int64) error {
+	_, err := r.q.Exec("DELETE FROM farmer_crops WHERE farmer_id = $1", farmerID)
+	return err
+}
+
+// postgresHarvests implements HarvestRepository
+type postgresHarvests struct {
+	q querier
+}
+
+func (r *postgresHarvests) Create(harvest models.FarmHarvest) (*models.FarmHarvest, error) {
+	return queryRow(r.q, scanHarvest,
+		"INSERT INTO farm_harvests AS h (id, user_profile_id, crop_id, quantity, harvested_at) VALUES ($1, $2, $3, $4, $5) RETURNING "+harvestColumns,
+		harvest.ID, harvest.UserProfileID, harvest.CropID, harvest.Quantity, harvest.HarvestedAt)
+}
+
+func (r *postgresHarvests) ListByProfile(profileID uuid.UUID, filter HarvestFilter, page, perPage int) ([]models.FarmHarvestWithDetails, int64, error) {
+	clause, args := harvestConditions([]string{"h.user_profile_id = $1"}, []any{profileID}, filter)
+
+	total, err := queryCount(r.q, "SELECT COUNT(*) FROM farm_harvests h"+clause, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, perPage, (page-1)*perPage)
+	harvests, err := queryRows(r.q, scanHarvestWithCrop,
+		fmt.Sprintf("%s%s ORDER BY h.harvested_at DESC LIMIT $%d OFFSET $%d", harvestWithCropQuery, clause, len(args)-1, len(args)),
+		args...)
+	return harvests, total, err
+}
+
+func (r *postgresHarvests) RecentByProfile(profileID uuid.UUID, limit int) ([]models.FarmHarvestWithDetails, error) {
+	return queryRows(r.q, scanHarvestWithCrop,
+		harvestWithCropQuery+" WHERE h.user_profile_id = $1 ORDER BY h.harvested_at DESC LIMIT $2",
+		profileID, limit)
+}
+
+func (r *postgresHarvests) List(filter HarvestFilter) ([]models.FarmHarvestWithDetails, error) {
+	clause, args := harvestConditions(nil, nil, filter)
+	return queryRows(r.q, scanHarvestWithCrop, harvestWithCropQuery+clause, args...)
+}
+
+func (r *postgresHarvests) ListByLocation(locationID int64, filter HarvestFilter) ([]models.FarmHarvestWithDetails, error) {
+	clause, args := harvestConditions([]string{
+		"h.user_profile_id IN (SELECT p.id FROM user_profiles p JOIN farmers f ON f.auth_user_id = p.auth_user_id WHERE f.location_id = $1)",
+	}, []any{locationID}, filter)
+	return queryRows(r.q, scanHarvestWithCrop, harvestWithCropQuery+clause, args...)
+}
+
+func (r *postgresHarvests) MoveCrop(fromCropID, toCropID uuid.UUID) (int, error) {
+	result, err := r.q.Exec("UPDATE farm_harvests SET crop_id = $2 WHERE crop_id = $1", fromCropID, toCropID)
+	if err != nil {
+		return 0, err
+	}
+
+	moved, err := result.RowsAffected()
+	return int(moved), err
+}
+
+// harvestWithCropQuery selects harvests with their crop embedded, like the "crop:crops(*)" select does
+const harvestWithCropQuery = "SELECT " + harvestColumns + ", " + cropColumns + " FROM farm_harvests h LEFT JOIN crops c ON c.id = h.crop_id"
+
+// harvestConditions adds the crop and date range filters to conditions and returns the WHERE clause
+func harvestConditions(conditions []string, args []any, filter HarvestFilter) (string, []any) {
+	if filter.CropID != nil {
+		args = append(args, *filter.CropID)
+		conditions = append(conditions, fmt.Sprintf("h.crop_id = $%d", len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("h.harvested_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("h.harvested_at <= $%d", len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// postgresFeedback implements FeedbackRepository
+type postgresFeedback struct {
+	q querier
+}
+
+func (r *postgresFeedback) Create(feedback models.FarmerFeedback) (*models.FarmerFeedback, error) {
+	return queryRow(r.q, scanFeedback,
+		"INSERT INTO farmer_feedback (id, farmer_id, phone_number, message, ai_response, created_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING "+feedbackColumns,
+		feedback.ID, feedback.FarmerID, feedback.PhoneNumber, feedback.Message, feedback.AIResponse, feedback.CreatedAt)
+}
+
+func (r *postgresFeedback) RecentByFarmer(farmerID int64, limit int) ([]models.FarmerFeedback, error) {
+	return queryRows(r.q, scanFeedback,
+		"SELECT "+feedbackColumns+" FROM farmer_feedback WHERE farmer_id = $1 ORDER BY created_at DESC LIMIT $2",
+		farmerID, limit)
+}
+
+func (r *postgresFeedback) ListByFarmerOrPhone(farmerIDs []int64, phoneNumbers []string) ([]models.FarmerFeedback, error) {
+	if len(farmerIDs) == 0 && len(phoneNumbers) == 0 {
+		return nil, nil
+	}
+	return queryRows(r.q, scanFeedback,
+		"SELECT "+feedbackColumns+" FROM farmer_feedback WHERE farmer_id = ANY($1) OR phone_number = ANY($2) ORDER BY created_at DESC",
+		farmerIDs, phoneNumbers)
+}
+
+func (r *postgresFeedback) DeleteByPhone(phoneNumbers []string) error {
+	if len(phoneNumbers) == 0 {
+		return nil
+	}
+	_, err := r.q.Exec("DELETE FROM farmer_feedback WHERE phone_number = ANY($1)", phoneNumbers)
+	return err
+}
+
+// postgresVisitNotes implements VisitNoteRepository
+type postgresVisitNotes struct {
+	q querier
+}
+
+func (r *postgresVisitNotes) Create(note models.FarmerVisitNote) (*models.FarmerVisitNote, error) {
+	return queryRow(r.q, scanVisitNote,
+		"INSERT INTO farmer_visit_notes (id, farmer_id, officer_id, note, visited_at, created_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING "+visitNoteColumns,
+		note.ID, note.FarmerID, note.OfficerID, note.Note, note.VisitedAt, note.CreatedAt)
+}
+
+func (r *postgresVisitNotes) ListByFarmer(farmerID int64) ([]models.FarmerVisitNote, error) {
+	return queryRows(r.q, scanVisitNote,
+		"SELECT "+visitNoteColumns+" FROM farmer_visit_notes WHERE farmer_id = $1 ORDER BY visited_at DESC",
+		farmerID)
+}
+
+// postgresLocations implements LocationRepository
+type postgresLocations struct {
+	q querier
+}
+
+func (r *postgresLocations) List(prefix string, page, perPage int) ([]models.Location, int64, error) {
+	pattern := likePrefix(prefix)
+	total, err := queryCount(r.q, "SELECT COUNT(*) FROM locations WHERE name ILIKE $1 OR country ILIKE $1", pattern)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	locations, err := queryRows(r.q, scanLocation,
+		"SELECT "+locationColumns+" FROM locations WHERE name ILIKE $1 OR country ILIKE $1 ORDER BY name LIMIT $2 OFFSET $3",
+		pattern, perPage, (page-1)*perPage)
+	return locations, total, err
+}
+
+func (r *postgresLocations) All() ([]models.Location, error) {
+	return queryRows(r.q, scanLocation, "SELECT "+locationColumns+" FROM locations")
+}
+
+func (r *postgresLocations) Get(id uuid.UUID) (*models.Location, error) {
+	return queryRow(r.q, scanLocation, "SELECT "+locationColumns+" FROM locations WHERE id = $1", id)
+}
+
+func (r *postgresLocations) Create(location models.Location) (*models.Location, error) {
+	return queryRow(r.q, scanLocation,
+		"INSERT INTO locations (id, name, country, created_at) VALUES ($1, $2, $3, $4) RETURNING "+locationColumns,
+		location.ID, location.Name, location.Country, location.CreatedAt)
+}
+
+// postgresSessions implements SessionRepository
+type postgresSessions struct {
+	q querier
+}
+
+func (r *postgresSessions) Revoke(session models.RevokedSession) error {
+	_, err := r.q.Exec(
+		"INSERT INTO revoked_sessions (session_id, auth_user_id, expires_at, revoked_at) VALUES ($1, $2, $3, $4) ON CONFLICT (session_id) DO NOTHING",
+		session.SessionID, session.AuthUserID, session.ExpiresAt, session.RevokedAt)
+	return err
+}
+
+func (r *postgresSessions) IsRevoked(sessionID uuid.UUID) (bool, error) {
+	var revoked bool
+	err := r.q.QueryRow("SELECT EXISTS (SELECT 1 FROM revoked_sessions WHERE session_id = $1)", sessionID).Scan(&revoked)
+	return revoked, err
+}
+
+// SetCutoff never moves an existing cutoff backwards
+func (r *postgresSessions) SetCutoff(cutoff models.SessionCutoff) error {
+	_, err := r.q.Exec(
+		`INSERT INTO session_cutoffs (auth_user_id, revoked_before) VALUES ($1, $2)
+		ON CONFLICT (auth_user_id) DO UPDATE SET revoked_before = GREATEST(session_cutoffs.revoked_before, EXCLUDED.revoked_before)`,
+		cutoff.AuthUserID, cutoff.RevokedBefore)
+	return err
+}
+
+func (r *postgresSessions) GetCutoff(authUserID uuid.UUID) (*models.SessionCutoff, error) {
+	return queryRow(r.q, scanCutoff, "SELECT "+cutoffColumns+" FROM session_cutoffs WHERE auth_user_id = $1", authUserID)
+}
+
+func (r *postgresSessions) DeleteExpired(now time.Time) error {
+	_, err := r.q.Exec("DELETE FROM revoked_sessions WHERE expires_at < $1", now)
+	return err
+}
+
+// postgresOTPCodes implements OTPRepository
+type postgresOTPCodes struct {
+	q querier
+}
+
+func (r *postgresOTPCodes) Save(code models.OTPCode) error {
+	_, err := r.q.Exec(
+		`INSERT INTO otp_codes (phone_number, code_hash, attempts, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (phone_number) DO UPDATE SET code_hash = EXCLUDED.code_hash, attempts = EXCLUDED.attempts,
+			expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at`,
+		code.PhoneNumber, code.CodeHash, code.Attempts, code.ExpiresAt, code.CreatedAt)
+	return err
+}
+
+func (r *postgresOTPCodes) Get(phoneNumber string) (*models.OTPCode, error) {
+	return queryRow(r.q, scanOTPCode, "SELECT "+otpCodeColumns+" FROM otp_codes WHERE phone_number = $1", phoneNumber)
+}
+
+func (r *postgresOTPCodes) RecordAttempt(phoneNumber string) (int, error) {
+	var attempts int
+	err := r.q.QueryRow("UPDATE otp_codes SET attempts = attempts + 1 WHERE phone_number = $1 RETURNING attempts", phoneNumber).Scan(&attempts)
+	if err == sql.ErrNoRows {
+		return 0, ErrNotFound
+	}
+	return attempts, err
+}
+
+func (r *postgresOTPCodes) Delete(phoneNumber string) error {
+	_, err := r.q.Exec("DELETE FROM otp_codes WHERE phone_number = $1", phoneNumber)
+	return err
+}
+
+// postgresResets implements PasswordResetRepository
+type postgresResets struct {
+	q querier
+}
+
+func (r *postgresResets) Save(token models.PasswordResetToken) error {
+	_, err := r.q.Exec(
+		`INSERT INTO password_reset_tokens (auth_user_id, token_hash, attempts, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (auth_user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, attempts = EXCLUDED.attempts,
+			expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at`,
+		token.AuthUserID, token.TokenHash, token.Attempts, token.ExpiresAt, token.CreatedAt)
+	return err
+}
+
+func (r *postgresResets) Get(authUserID uuid.UUID) (*models.PasswordResetToken, error) {
+	return queryRow(r.q, scanReset, "SELECT "+resetColumns+" FROM password_reset_tokens WHERE auth_user_id = $1", authUserID)
+}
+
+func (r *postgresResets) RecordAttempt(authUserID uuid.UUID) (int, error) {
+	var attempts int
+	err := r.q.QueryRow("UPDATE password_reset_tokens SET attempts = attempts + 1 WHERE auth_user_id = $1 RETURNING attempts", authUserID).Scan(&attempts)
+	if err == sql.ErrNoRows {
+		return 0, ErrNotFound
+	}
+	return attempts, err
+}
+
+func (r *postgresResets) Delete(authUserID uuid.UUID) error {
+	_, err := r.q.Exec("DELETE FROM password_reset_tokens WHERE auth_user_id = $1", authUserID)
+	return err
+}
+
+// postgresAudit implements AuditRepository
+type postgresAudit struct {
+	q querier
+}
+
+func (r *postgresAudit) Create(event models.AuditEvent) error {
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.q.Exec(
+		"INSERT INTO audit_log (id, actor_id, action, target_id, ip_address, user_agent, request_id, details, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		event.ID, event.ActorID, event.Action, event.TargetID, event.IPAddress, event.UserAgent, event.RequestID, string(details), event.CreatedAt)
+	return err
+}
+
+func (r *postgresAudit) List(filter AuditFilter, page, perPage int) ([]models.AuditEvent, int64, error) {
+	var conditions []string
+	var args []any
+	if filter.ActorID != nil {
+		args = append(args, *filter.ActorID)
+		conditions = append(conditions, fmt.Sprintf("actor_id = $%d", len(args)))
+	}
+	if filter.TargetID != nil {
+		args = append(args, *filter.TargetID)
+		conditions = append(conditions, fmt.Sprintf("target_id = $%d", len(args)))
+	}
+	if filter.Action != "" {
+		args = append(args, likePrefix(filter.Action))
+		conditions = append(conditions, fmt.Sprintf("action LIKE $%d", len(args)))
+	}
+	if filter.RequestID != "" {
+		args = append(args, filter.RequestID)
+		conditions = append(conditions, fmt.Sprintf("request_id = $%d", len(args)))
+	}
+	if filter.IPAddress != "" {
+		args = append(args, filter.IPAddress)
+		conditions = append(conditions, fmt.Sprintf("ip_address = $%d", len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at <= $%d", len(args)))
+	}
+	clause := ""
+	if len(conditions) > 0 {
+		clause = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	total, err := queryCount(r.q, "SELECT COUNT(*) FROM audit_log"+clause, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, perPage, (page-1)*perPage)
+	events, err := queryRows(r.q, scanAuditEvent,
+		fmt.Sprintf("SELECT %s FROM audit_log%s ORDER BY created_at DESC LIMIT $%d OFFSET $%d", auditColumns, clause, len(args)-1, len(args)),
+		args...)
+	return events, total, err
+}
+
+// postgresAPIKeys implements APIKeyRepository
+type postgresAPIKeys struct {
+	q querier
+}
+
+func (r *postgresAPIKeys) Create(key models.APIKey) (*models.APIKey, error) {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	return queryRow(r.q, scanAPIKey,
+		"INSERT INTO api_keys (id, name, owner_id, prefix, key_hash, scopes, expires_at, created_by, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING "+apiKeyColumns,
+		key.ID, key.Name, key.OwnerID, key.Prefix, key.KeyHash, string(scopes), key.ExpiresAt, key.CreatedBy, key.CreatedAt)
+}
+
+func (r *postgresAPIKeys) List() ([]models.APIKey, error) {
+	return queryRows(r.q, scanAPIKey, "SELECT "+apiKeyColumns+" FROM api_keys ORDER BY created_at DESC")
+}
+
+func (r *postgresAPIKeys) Get(id uuid.UUID) (*models.APIKey, error) {
+	return queryRow(r.q, scanAPIKey, "SELECT "+apiKeyColumns+" FROM api_keys WHERE id = $1", id)
+}
+
+func (r *postgresAPIKeys) GetByPrefix(prefix string) (*models.APIKey, error) {
+	return queryRow(r.q, scanAPIKey, "SELECT "+apiKeyColumns+" FROM api_keys WHERE prefix = $1", prefix)
+}
+
+func (r *postgresAPIKeys) Revoke(id uuid.UUID, revokedAt time.Time) error {
+	return execAffected(r.q, "UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $2) WHERE id = $1", id, revokedAt)
+}
+
+func (r *postgresAPIKeys) TouchLastUsed(id uuid.UUID, usedAt time.Time) error {
+	_, err := r.q.Exec("UPDATE api_keys SET last_used_at = $2 WHERE id = $1", id, usedAt)
+	return err
+}
+
+// postgresLogins implements LoginAttemptRepository
+type postgresLogins struct {
+	q querier
+}
+
+func (r *postgresLogins) Get(key string) (*models.LoginAttempt, error) {
+	return queryRow(r.q, scanLoginAttempt, "SELECT "+loginColumns+" FROM login_attempts WHERE key = $1", key)
+}
+
+// RecordFailure counts in a single upsert, so concurrent failures are all counted
+func (r *postgresLogins) RecordFailure(key string, at, windowStart time.Time) (*models.LoginAttempt, error) {
+	return queryRow(r.q, scanLoginAttempt,
+		`INSERT INTO login_attempts (key, failures, last_failure_at) VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < $3 THEN 1 ELSE login_attempts.failures + 1 END,
+			locked_until = CASE WHEN login_attempts.last_failure_at < $3 THEN NULL ELSE login_attempts.locked_until END,
+			last_failure_at = $2
+		RETURNING `+loginColumns,
+		key, at, windowStart)
+}
+
+func (r *postgresLogins) Lock(key string, until time.Time) error {
+	return execAffected(r.q, "UPDATE login_attempts SET locked_until = $2 WHERE key = $1", key, until)
+}
+
+func (r *postgresLogins) ListLocked(at time.Time) ([]models.LoginAttempt, error) {
+	return queryRows(r.q, scanLoginAttempt, "SELECT "+loginColumns+" FROM login_attempts WHERE locked_until > $1 ORDER BY locked_until DESC", at)
+}
+
+func (r *postgresLogins) Delete(key string) error {
+	_, err := r.q.Exec("DELETE FROM login_attempts WHERE key = $1", key)
+	return err
+}
+
+func scanProfile(row rowScanner) (models.UserProfile, error) {
+	var profile models.UserProfile
+	var metadata []byte
+	err := row.Scan(&profile.ID, &profile.AuthUserID, &profile.RoleID, &profile.DisplayName, &profile.Email, &profile.Phone, &metadata, &profile.CreatedAt, &profile.DisabledAt)
+	if err == nil && metadata != nil {
+		err = json.Unmarshal(metadata, &profile.Metadata)
+	}
+	return profile, err
+}
+
+func scanAuditEvent(row rowScanner) (models.AuditEvent, error) {
+	var event models.AuditEvent
+	var details []byte
+	err := row.Scan(&event.ID, &event.ActorID, &event.Action, &event.TargetID, &event.IPAddress, &event.UserAgent, &event.RequestID, &details, &event.CreatedAt)
+	if err == nil && details != nil {
+		err = json.Unmarshal(details, &event.Details)
+	}
+	return event, err
+}
+
+func scanRole(row rowScanner) (models.Role, error) {
+	var role models.Role
+	err := row.Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt)
+	return role, err
+}
+
+func scanFarmer(row rowScanner) (models.Farmer, error) {
+	var farmer models.Farmer
+	err := row.Scan(&farmer.ID, &farmer.AuthUserID, &farmer.Name, &farmer.PhoneNumber, &farmer.CropType, &farmer.LocationID, &farmer.Language, &farmer.CreatedAt)
+	return farmer, err
+}
+
+func scanOfficer(row rowScanner) (models.ExtensionOfficer, error) {
+	var officer models.ExtensionOfficer
+	err := row.Scan(&officer.ID, &officer.AuthUserID, &officer.Name, &officer.PhoneNumber, &officer.AssignedLocationID)
+	return officer, err
+}
+
+func scanCrop(row rowScanner) (models.Crop, error) {
+	var crop models.Crop
+	err := row.Scan(&crop.ID, &crop.Name, &crop.ScientificName, &crop.CreatedAt)
+	return crop, err
+}
+
+func scanCropAlias(row rowScanner) (models.CropAlias, error) {
+	var alias models.CropAlias
+	err := row.Scan(&alias.ID, &alias.CropID, &alias.Alias, &alias.CreatedAt)
+	return alias, err
+}
+
+func scanFarmerCrop(row rowScanner) (models.FarmerCrop, error) {
+	var link models.FarmerCrop
+	err := row.Scan(&link.ID, &link.FarmerID, &link.CropID, &link.CreatedAt)
+	return link, err
+}
+
+func scanHarvest(row rowScanner) (models.FarmHarvest, error) {
+	var harvest models.FarmHarvest
+	err := row.Scan(&harvest.ID, &harvest.UserProfileID, &harvest.CropID, &harvest.Quantity, &harvest.HarvestedAt)
+	return harvest, err
+}
+
+// scanHarvestWithCrop scans a harvestWithCropQuery row; the crop is nil when the join found none
+func scanHarvestWithCrop(row rowScanner) (models.FarmHarvestWithDetails, error) {
+	var harvest models.FarmHarvestWithDetails
+	var cropID *uuid.UUID
+	var cropName *string
+	var scientificName *string
+	var cropCreatedAt *time.Time
+	err := row.Scan(&harvest.ID, &harvest.UserProfileID, &harvest.CropID, &harvest.Quantity, &harvest.HarvestedAt,
+		&cropID, &cropName, &scientificName, &cropCreatedAt)
+	if err != nil {
+		return harvest, err
+	}
+
+	if cropID != nil {
+		harvest.Crop = &models.Crop{ID: *cropID, ScientificName: scientificName}
+		if cropName != nil {
+			harvest.Crop.Name = *cropName
+		}
+		if cropCreatedAt != nil {
+			harvest.Crop.CreatedAt = *cropCreatedAt
+		}
+	}
+	return harvest, nil
+}
+
+func scanFeedback(row rowScanner) (models.FarmerFeedback, error) {
+	var feedback models.FarmerFeedback
+	err := row.Scan(&feedback.ID, &feedback.FarmerID, &feedback.PhoneNumber, &feedback.Message, &feedback.AIResponse, &feedback.CreatedAt)
+	return feedback, err
+}
+
+func scanVisitNote(row rowScanner) (models.FarmerVisitNote, error) {
+	var note models.FarmerVisitNote
+	err := row.Scan(&note.ID, &note.FarmerID, &note.OfficerID, &note.Note, &note.VisitedAt, &note.CreatedAt)
+	return note, err
+}
+
+func scanLocation(row rowScanner) (models.Location, error) {
+	var location models.Location
+	err := row.Scan(&location.ID, &location.Name, &location.Country, &location.CreatedAt)
+	return location, err
+}
+
+func scanCutoff(row rowScanner) (models.SessionCutoff, error) {
+	var cutoff models.SessionCutoff
+	err := row.Scan(&cutoff.AuthUserID, &cutoff.RevokedBefore)
+	return cutoff, err
+}
+
+func scanOTPCode(row rowScanner) (models.OTPCode, error) {
+	var code models.OTPCode
+	err := row.Scan(&code.PhoneNumber, &code.CodeHash, &code.Attempts, &code.ExpiresAt, &code.CreatedAt)
+	return code, err
+}
+
+func scanReset(row rowScanner) (models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := row.Scan(&token.AuthUserID, &token.TokenHash, &token.Attempts, &token.ExpiresAt, &token.CreatedAt)
+	return token, err
+}
+
+func scanAPIKey(row rowScanner) (models.APIKey, error) {
+	var key models.APIKey
+	var scopes []byte
+	err := row.Scan(&key.ID, &key.Name, &key.OwnerID, &key.Prefix, &key.KeyHash, &scopes,
+		&key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedBy, &key.CreatedAt)
+	if err == nil && scopes != nil {
+		err = json.Unmarshal(scopes, &key.Scopes)
+	}
+	return key, err
+}
+
+func scanLoginAttempt(row rowScanner) (models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := row.Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailureAt, &attempt.LockedUntil)
+	return attempt, err
+}
+
+// queryRow runs a query that returns at most one row, or ErrNotFound when there is none
+func queryRow[T any](q querier, scan func(rowScanner) (T, error), query string, args ...any) (*T, error) {
+	row, err := scan(q.QueryRow(query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// queryRows runs a query and scans every row it returns
+func queryRows[T any](q querier, scan func(rowScanner) (T, error), query string, args ...any) ([]T, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []T{}
+	for rows.Next() {
+		row, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// queryCount runs a SELECT COUNT(*) query
+func queryCount(q querier, query string, args ...any) (int64, error) {
+	var total int64
+	err := q.QueryRow(query, args...).Scan(&total)
+	return total, err
+}
+
+// isUniqueViolation reports whether a statement failed on a unique constraint
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// execAffected runs an update or delete, returning ErrNotFound when it matched no row
+func execAffected(q querier, query string, args ...any) error {
+	result, err := q.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// nextIDOr returns an insert value for table's id: param, or the next value of the
+// table's id sequence when param is zero
+func nextIDOr(table, param string) string {
+	return fmt.Sprintf("COALESCE(NULLIF(%s::bigint, 0), nextval(pg_get_serial_sequence('%s', 'id')))", param, table)
+}
+
+// likePrefix builds an ILIKE pattern matching values that start with prefix
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+}
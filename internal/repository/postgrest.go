@@ -0,0 +1,1010 @@
+package repository
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/supabase-community/postgrest-go"
+)
+
+// Limits on the size of PostgREST requests
+const (
+	// postgrestPageSize is how many rows listAll asks for at a time
+	postgrestPageSize = 1000
+	// postgrestInBatchSize is how many IDs go in one In filter
+	postgrestInBatchSize = 100
+)
+
+// NewPostgrestStore creates a store backed by Supabase's PostgREST API
+func NewPostgrestStore(supabaseURL, supabaseAnonKey string) (*Store, error) {
+	if supabaseURL == "" || supabaseAnonKey == "" {
+		return nil, ErrSupabaseConfigMissing
+	}
+
+	// The PostgREST client is built directly, as the Supabase client hides its transport
+	client := postgrest.NewClient(strings.TrimSuffix(supabaseURL, "/")+"/rest/v1", "public", map[string]string{
+		"Authorization": "Bearer " + supabaseAnonKey,
+		"apikey":        supabaseAnonKey,
+	})
+	if client.ClientError != nil {
+		return nil, client.ClientError
+	}
+	client.Transport.Parent = timedTransport{next: http.DefaultTransport}
+
+	return &Store{
+		Profiles:    &postgrestProfiles{client: client},
+		Roles:       &postgrestRoles{client: client},
+		Farmers:     &postgrestFarmers{client: client},
+		Officers:    &postgrestOfficers{client: client},
+		Crops:       &postgrestCrops{client: client},
+		FarmerCrops: &postgrestFarmerCrops{client: client},
+		Harvests:    &postgrestHarvests{client: client},
+		Feedback:    &postgrestFeedback{client: client},
+		VisitNotes:  &postgrestVisitNotes{client: client},
+		Locations:   &postgrestLocations{client: client},
+		Sessions:    &postgrestSessions{client: client},
+		OTPCodes:    &postgrestOTPCodes{client: client},
+		Resets:      &postgrestResets{client: client},
+		Audit:       &postgrestAudit{client: client},
+		APIKeys:     &postgrestAPIKeys{client: client},
+		Logins:      &postgrestLogins{client: client},
+	}, nil
+}
+
+// postgrestProfiles implements ProfileRepository
+type postgrestProfiles struct {
+	client *postgrest.Client
+}
+
+func (r *postgrestProfiles) Create(profile models.UserProfile) (*models.UserProfile, error) {
+	return insertOne(r.client, "user_profiles", profile)
+}
+
+func (r *postgrestProfiles) GetByAuthUserID(authUserID string) (*models.UserProfile, error) {
+	var result []models.UserProfile
+	_, err := r.client.From("user_profiles").Select("*", "", false).Eq("auth_user_id", authUserID).ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestProfiles) FindByDisplayName(displayName string) (*models.UserProfile, error) {
+	var result []models.UserProfile
+	_, err := r.client.From("user_profiles").
+		Select("*", "", false).
+		Eq("display_name", displayName).
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+		ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestProfiles) FindByEmail(email string) (*models.UserProfile, error) {
+	var result []models.UserProfile
+	_, err := r.client.From("user_profiles").Select("*", "", false).Eq("email", strings.ToLower(email)).ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestProfiles) FindByPhone(phoneNumbers []string) (*models.UserProfile, error) {
+	if len(phoneNumbers) == 0 {
+		return nil, ErrNotFound
+	}
+
+	var result []models.UserProfile
+	_, err := r.client.From("user_profiles").
+		Select("*", "", false).
+		In("phone", phoneNumbers).
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+		ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestProfiles) SetEmail(id uuid.UUID, email string) error {
+	_, _, err := r.client.From("user_profiles").Update(map[string]any{"email": email}, "minimal", "").Eq("id", id.String()).Execute()
+	return err
+}
+
+func (r *postgrestProfiles) ListByIDs(ids []uuid.UUID) ([]models.UserProfile, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	return inBatches[models.UserProfile](ids, func(batch []string) *postgrest.FilterBuilder {
+		return r.client.From("user_profiles").Select("*", "", false).In("id", batch)
+	})
+}
+
+func (r *postgrestProfiles) Search(filter ProfileFilter, page, perPage int) ([]models.UserProfile, int64, error) {
+	builder := r.client.From("user_profiles").Select("*", "exact", false)
+	if filter.Query != "" {
+		builder = builder.Or(fmt.Sprintf("display_name.ilike.%s*,email.ilike.%s*,phone.ilike.%s*", filter.Query, filter.Query, filter.Query), "")
+	}
+	if filter.RoleID != nil {
+		builder = builder.Eq("role_id", filter.RoleID.String())
+	}
+	if filter.Disabled != nil {
+		if *filter.Disabled {
+			builder = builder.Not("disabled_at", "is", "null")
+		} else {
+			builder = builder.Is("disabled_at", "null")
+		}
+	}
+
+	from := (page - 1) * perPage
+	var profiles []models.UserProfile
+	total, err := builder.
+		Order("created_at", &postgrest.OrderOpts{Ascending: false}).
+		Range(from, from+perPage-1, "").
+		ExecuteTo(&profiles)
+	return profiles, total, err
+}
+
+func (r *postgrestProfiles) SetRole(id, roleID uuid.UUID) error {
+	return r.update(id, map[string]any{"role_id": roleID.String()})
+}
+
+func (r *postgrestProfiles) SetDisabled(id uuid.UUID, disabledAt *time.Time) error {
+	return r.update(id, map[string]any{"disabled_at": disabledAt})
+}
+
+// update changes a profile's columns, returning ErrNotFound when there is no such profile
+func (r *postgrestProfiles) update(id uuid.UUID, changes map[string]any) error {
+	var result []models.UserProfile
+	_, err := r.client.From("user_profiles").Update(changes, "", "").Eq("id", id.String()).ExecuteTo(&result)
+	_, err = first(result, err)
+	return err
+}
+
+// Delete relies on ON DELETE CASCADE for farm_harvests
+func (r *postgrestProfiles) Delete(id uuid.UUID) error {
+	var result []models.UserProfile
+	_, err := r.client.From("user_profiles").Delete("", "").Eq("id", id.String()).ExecuteTo(&result)
+	_, err = first(result, err)
+	return err
+}
+
+// postgrestRoles implements RoleRepository
+type postgrestRoles struct {
+	client *postgrest.Client
+}
+
+func (r *postgrestRoles) Get(id uuid.UUID) (*models.Role, error) {
+	var result []models.Role
+	_, err := r.client.From("roles").Select("*", "", false).Eq("id", id.String()).ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestRoles) GetByName(name string) (*models.Role, error) {
+	var result []models.Role
+	_, err := r.client.From("roles").Select("*", "", false).Eq("name", name).ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestRoles) List() ([]models.Role, error) {
+	var roles []models.Role
+	_, err := r.client.From("roles").Select("*", "", false).Order("name", &postgrest.OrderOpts{Ascending: true}).ExecuteTo(&roles)
+	return roles, err
+}
+
+// postgrestFarmers implements FarmerRepository
+type postgrestFarmers struct {
+	client *postgrest.Client
+}
+
+func (r *postgrestFarmers) List(page, perPage int) ([]models.Farmer, int64, error) {
+	from := (page - 1) * perPage
+	var farmers []models.Farmer
+	total, err := r.client.From("farmers").
+		Select("*", "exact", false).
+		Order("created_at", &postgrest.OrderOpts{Ascending: false}).
+		Range(from, from+perPage-1, "").
+		ExecuteTo(&farmers)
+	return farmers, total, err
+}
+
+func (r *postgrestFarmers) ListByLocation(locationID int64, page, perPage int) ([]models.Farmer, int64, error) {
+	from := (page - 1) * perPage
+	var farmers []models.Farmer
+	total, err := r.client.From("farmers").
+		Select("*", "exact", false).
+		Eq("location_id", fmt.Sprintf("%d", locationID)).
+		Order("name", &postgrest.OrderOpts{Ascending: true}).
+		Range(from, from+perPage-1, "").
+		ExecuteTo(&farmers)
+	return farmers, total, err
+}
+
+func (r *postgrestFarmers) ListByAuthUserIDs(authUserIDs []uuid.UUID) ([]models.Farmer, error) {
+	if len(authUserIDs) == 0 {
+		return nil, nil
+	}
+
+	return inBatches[models.Farmer](authUserIDs, func(batch []string) *postgrest.FilterBuilder {
+		return r.client.From("farmers").Select("*", "", false).In("auth_user_id", batch)
+	})
+}
+
+func (r *postgrestFarmers) Get(id int64) (*models.Farmer, error) {
+	var result []models.Farmer
+	_, err := r.client.From("farmers").Select("*", "", false).Eq("id", fmt.Sprintf("%d", id)).ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestFarmers) FindByPhone(phoneNumbers []string) (*models.Farmer, error) {
+	if len(phoneNumbers) == 0 {
+		return nil, ErrNotFound
+	}
+
+	var result []models.Farmer
+	_, err := r.client.From("farmers").
+		Select("*", "", false).
+		In("phone_number", phoneNumbers).
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+		ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestFarmers) ListByPhone(phoneNumbers []string) ([]models.Farmer, error) {
+	if len(phoneNumbers) == 0 {
+		return nil, nil
+	}
+
+	var farmers []models.Farmer
+	_, err := r.client.From("farmers").
+		Select("*", "", false).
+		In("phone_number", phoneNumbers).
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+		ExecuteTo(&farmers)
+	return farmers, err
+}
+
+func (r *postgrestFarmers) Create(farmer models.Farmer) (*models.Farmer, error) {
+	return insertOne(r.client, "farmers", farmer)
+}
+
+func (r *postgrestFarmers) Update(id int64, update FarmerUpdate) (*models.Farmer, error) {
+	changes := map[string]any{}
+	if update.Name != nil {
+		changes["name"] = *update.Name
+	}
+	if update.PhoneNumber != nil {
+		changes["phone_number"] = *update.PhoneNumber
+	}
+	if update.CropType != nil {
+		changes["crop_type"] = *update.CropType
+	}
+	if update.LocationID != nil {
+		changes["location_id"] = *update.LocationID
+	}
+	if update.Language != nil {
+		changes["language"] = *update.Language
+	}
+	if update.AuthUserID != nil {
+		changes["auth_user_id"] = *update.AuthUserID
+	}
+	if len(changes) == 0 {
+		return r.Get(id)
+	}
+
+	var result []models.Farmer
+	_, err := r.client.From("farmers").Update(changes, "", "").Eq("id", fmt.Sprintf("%d", id)).ExecuteTo(&result)
+	return first(result, err)
+}
+
+// Delete relies on ON DELETE CASCADE for farmer_crops, farmer_feedback and farmer_visit_notes
+func (r *postgrestFarmers) Delete(id int64) error {
+	var result []models.Farmer
+	_, err := r.client.From("farmers").Delete("", "").Eq("id", fmt.Sprintf("%d", id)).ExecuteTo(&result)
+	_, err = first(result, err)
+	return err
+}
+
+// postgrestOfficers implements OfficerRepository
+type postgrestOfficers struct {
+	client *postgrest.Client
+}
+
+func (r *postgrestOfficers) Create(officer models.ExtensionOfficer) (*models.ExtensionOfficer, error) {
+	return insertOne(r.client, "extension_officers", officer)
+}
+
+func (r *postgrestOfficers) GetByAuthUserID(authUserID string) (*models.ExtensionOfficer, error) {
+	var result []models.ExtensionOfficer
+	_, err := r.client.From("extension_officers").Select("*", "", false).Eq("auth_user_id", authUserID).ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestOfficers) SetAssignedLocation(id int64, locationID *int64) (*models.ExtensionOfficer, error) {
+	var result []models.ExtensionOfficer
+	_, err := r.client.From("extension_officers").
+		Update(map[string]any{"assigned_location_id": locationID}, "", "").
+		Eq("id", fmt.Sprintf("%d", id)).
+		ExecuteTo(&result)
+	return first(result, err)
+}
+
+// Delete relies on ON DELETE CASCADE for farmer_visit_notes
+func (r *postgrestOfficers) Delete(id int64) error {
+	var result []models.ExtensionOfficer
+	_, err := r.client.From("extension_officers").Delete("", "").Eq("id", fmt.Sprintf("%d", id)).ExecuteTo(&result)
+	_, err = first(result, err)
+	return err
+}
+
+// postgrestCrops implements CropRepository
+type postgrestCrops struct {
+	client *postgrest.Client
+}
+
+func (r *postgrestCrops) List(prefix string, page, perPage int) ([]models.Crop, int64, error) {
+	builder := r.client.From("crops").Select("*", "exact", false)
+	if prefix != "" {
+		builder = builder.Or(fmt.Sprintf("name.ilike.%s*,scientific_name.ilike.%s*", prefix, prefix), "")
+	}
+
+	from := (page - 1) * perPage
+	var crops []models.Crop
+	total, err := builder.
+		Order("name", &postgrest.OrderOpts{Ascending: true}).
+		Range(from, from+perPage-1, "").
+		ExecuteTo(&crops)
+	return crops, total, err
+}
+
+func (r *postgrestCrops) All() ([]models.Crop, error) {
+	var crops []models.Crop
+	_, err := r.client.From("crops").Select("*", "", false).ExecuteTo(&crops)
+	return crops, err
+}
+
+func (r *postgrestCrops) Get(id uuid.UUID) (*models.Crop, error) {
+	var result []models.Crop
+	_, err := r.client.From("crops").Select("*", "", false).Eq("id", id.String()).ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestCrops) FindByName(name string) (*models.Crop, error) {
+	var result []models.Crop
+	_, err := r.client.From("crops").Select("*", "", false).Ilike("name", name).ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestCrops) Create(crop models.Crop) (*models.Crop, error) {
+	return insertOne(r.client, "crops", crop)
+}
+
+func (r *postgrestCrops) Delete(id uuid.UUID) error {
+	var result []models.Crop
+	_, err := r.client.From("crops").Delete("", "").Eq("id", id.String()).ExecuteTo(&result)
+	_, err = first(result, err)
+	return err
+}
+
+func (r *postgrestCrops) AllAliases() ([]models.CropAlias, error) {
+	var aliases []models.CropAlias
+	_, err := r.client.From("crop_aliases").Select("*", "", false).ExecuteTo(&aliases)
+	return aliases, err
+}
+
+func (r *postgrestCrops) FindAlias(alias string) (*models.CropAlias, error) {
+	var result []models.CropAlias
+	_, err := r.client.From("crop_aliases").Select("*", "", false).Ilike("alias", alias).ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestCrops) CreateAlias(alias models.CropAlias) (*models.CropAlias, error) {
+	return insertOne(r.client, "crop_aliases", alias)
+}
+
+func (r *postgrestCrops) MoveAliases(fromCropID, toCropID uuid.UUID) error {
+	var result []models.CropAlias
+	_, err := r.client.From("crop_aliases").
+		Update(map[string]any{"crop_id": toCropID}, "", "").
+		Eq("crop_id", fromCropID.String()).
+		ExecuteTo(&result)
+	return err
+}
+
+// postgrestFarmerCrops implements FarmerCropRepository
+type postgrestFarmerCrops struct {
+	client *postgrest.Client
+}
+
+func (r *postgrestFarmerCrops) CropsForFarmer(farmerID int64) ([]models.Crop, error) {
+	var farmerCrops []models.FarmerCropWithDetails
+	_, err := r.client.From("farmer_crops").
+		Select("*, crop:crops(*)", "", false).
+		Eq("farmer_id", fmt.Sprintf("%d", farmerID)).
+		ExecuteTo(&farmerCrops)
+	if err != nil {
+		return nil, err
+	}
+
+	var crops []models.Crop
+	for _, fc := range farmerCrops {
+		if fc.Crop != nil {
+			crops = append(crops, *fc.Crop)
+		}
+	}
+	return crops, nil
+}
+
+func (r *postgrestFarmerCrops) ListByCrop(cropID uuid.UUID) ([]models.FarmerCrop, error) {
+	var links []models.FarmerCrop
+	_, err := r.client.From("farmer_crops").Select("*", "", false).Eq("crop_id", cropID.String()).ExecuteTo(&links)
+	return links, err
+}
+
+func (r *postgrestFarmerCrops) Create(link models.FarmerCrop) error {
+	_, err := insertOne(r.client, "farmer_crops", link)
+	if isPostgrestUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *postgrestFarmerCrops) SetCrop(linkID, cropID uuid.UUID) error {
+	var result []models.FarmerCrop
+	_, err := r.client.From("farmer_crops").
+		Update(map[string]any{"crop_id": cropID}, "", "").
+		Eq("id", linkID.String()).
+		ExecuteTo(&result)
+	_, err = first(result, err)
+	return err
+}
+
+func (r *postgrestFarmerCrops) Delete(linkID uuid.UUID) error {
+	var result []models.FarmerCrop
+	_, err := r.client.From("farmer_crops").Delete("", "").Eq("id", linkID.String()).ExecuteTo(&result)
+	_, err = first(result, err)
+	return err
+}
+
+func (r *postgrestFarmerCrops) DeleteByFarmer(farmerID int64) error {
+	var result []models.FarmerCrop
+	_, err := r.client.From("farmer_crops").Delete("", "").Eq("farmer_id", fmt.Sprintf("%d", farmerID)).ExecuteTo(&result)
+	return err
+}
+
+// postgrestHarvests implements HarvestRepository
+type postgrestHarvests struct {
+	client *postgrest.Client
+}
+
+func (r *postgrestHarvests) Create(harvest models.FarmHarvest) (*models.FarmHarvest, error) {
+	return insertOne(r.client, "farm_harvests", harvest)
+}
+
+func (r *postgrestHarvests) ListByProfile(profileID uuid.UUID, filter HarvestFilter, page, perPage int) ([]models.FarmHarvestWithDetails, int64, error) {
+	query := r.client.From("farm_harvests").
+		Select("*, crop:crops(*)", "exact", false).
+		Eq("user_profile_id", profileID.String())
+	query = applyHarvestFilter(query, filter)
+
+	from := (page - 1) * perPage
+	var harvests []models.FarmHarvestWithDetails
+	total, err := query.
+		Order("harvested_at", &postgrest.OrderOpts{Ascending: false}).
+		Range(from, from+perPage-1, "").
+		ExecuteTo(&harvests)
+	return harvests, total, err
+}
+
+func (r *postgrestHarvests) RecentByProfile(profileID uuid.UUID, limit int) ([]models.FarmHarvestWithDetails, error) {
+	var harvests []models.FarmHarvestWithDetails
+	_, err := r.client.From("farm_harvests").
+		Select("*, crop:crops(*)", "", false).
+		Eq("user_profile_id", profileID.String()).
+		Order("harvested_at", &postgrest.OrderOpts{Ascending: false}).
+		Limit(limit, "").
+		ExecuteTo(&harvests)
+	return harvests, err
+}
+
+func (r *postgrestHarvests) List(filter HarvestFilter) ([]models.FarmHarvestWithDetails, error) {
+	return listAll[models.FarmHarvestWithDetails](func() *postgrest.FilterBuilder {
+		query := r.client.From("farm_harvests").Select("*, crop:crops(*)", "", false)
+		return applyHarvestFilter(query, filter)
+	})
+}
+
+// ListByLocation resolves the location's farmers to their user profiles first, as PostgREST
+// can't join farmers to harvests through the auth user
+func (r *postgrestHarvests) ListByLocation(locationID int64, filter HarvestFilter) ([]models.FarmHarvestWithDetails, error) {
+	farmers, err := listAll[models.Farmer](func() *postgrest.FilterBuilder {
+		return r.client.From("farmers").Select("*", "", false).
+			Eq("location_id", fmt.Sprintf("%d", locationID)).
+			Not("auth_user_id", "is", "null")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var authUserIDs []uuid.UUID
+	for _, farmer := range farmers {
+		if farmer.AuthUserID != nil {
+			authUserIDs = append(authUserIDs, *farmer.AuthUserID)
+		}
+	}
+	if len(authUserIDs) == 0 {
+		return nil, nil
+	}
+
+	profiles, err := inBatches[models.UserProfile](authUserIDs, func(batch []string) *postgrest.FilterBuilder {
+		return r.client.From("user_profiles").Select("id", "", false).In("auth_user_id", batch)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	profileIDs := make([]uuid.UUID, 0, len(profiles))
+	for _, profile := range profiles {
+		profileIDs = append(profileIDs, profile.ID)
+	}
+	if len(profileIDs) == 0 {
+		return nil, nil
+	}
+
+	return inBatches[models.FarmHarvestWithDetails](profileIDs, func(batch []string) *postgrest.FilterBuilder {
+		query := r.client.From("farm_harvests").Select("*, crop:crops(*)", "", false).In("user_profile_id", batch)
+		return applyHarvestFilter(query, filter)
+	})
+}
+
+func (r *postgrestHarvests) MoveCrop(fromCropID, toCropID uuid.UUID) (int, error) {
+	var harvests []models.FarmHarvest
+	_, err := r.client.From("farm_harvests").
+		Update(map[string]any{"crop_id": toCropID}, "", "").
+		Eq("crop_id", fromCropID.String()).
+		ExecuteTo(&harvests)
+	return len(harvests), err
+}
+
+// applyHarvestFilter adds the crop and date range filters to a harvest query
+func applyHarvestFilter(query *postgrest.FilterBuilder, filter HarvestFilter) *postgrest.FilterBuilder {
+	if filter.CropID != nil {
+		query = query.Eq("crop_id", filter.CropID.String())
+	}
+	if filter.From != nil {
+		query = query.Gte("harvested_at", filter.From.Format(time.RFC3339))
+	}
+	if filter.To != nil {
+		query = query.Lte("harvested_at", filter.To.Format(time.RFC3339))
+	}
+	return query
+}
+
+// postgrestFeedback implements FeedbackRepository
+type postgrestFeedback struct {
+	client *postgrest.Client
+}
+
+func (r *postgrestFeedback) Create(feedback models.FarmerFeedback) (*models.FarmerFeedback, error) {
+	return insertOne(r.client, "farmer_feedback", feedback)
+}
+
+func (r *postgrestFeedback) RecentByFarmer(farmerID int64, limit int) ([]models.FarmerFeedback, error) {
+	var feedback []models.FarmerFeedback
+	_, err := r.client.From("farmer_feedback").
+		Select("*", "", false).
+		Eq("farmer_id", fmt.Sprintf("%d", farmerID)).
+		Order("created_at", &postgrest.OrderOpts{Ascending: false}).
+		Limit(limit, "").
+		ExecuteTo(&feedback)
+	return feedback, err
+}
+
+func (r *postgrestFeedback) ListByFarmerOrPhone(farmerIDs []int64, phoneNumbers []string) ([]models.FarmerFeedback, error) {
+	var conditions []string
+	if len(farmerIDs) > 0 {
+		ids := make([]string, len(farmerIDs))
+		for i, id := range farmerIDs {
+			ids[i] = fmt.Sprintf("%d", id)
+		}
+		conditions = append(conditions, "farmer_id.in.("+strings.Join(ids, ",")+")")
+	}
+	if len(phoneNumbers) > 0 {
+		conditions = append(conditions, "phone_number.in.("+strings.Join(phoneNumbers, ",")+")")
+	}
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+
+	var feedback []models.FarmerFeedback
+	_, err := r.client.From("farmer_feedback").
+		Select("*", "", false).
+		Or(strings.Join(conditions, ","), "").
+		Order("created_at", &postgrest.OrderOpts{Ascending: false}).
+		ExecuteTo(&feedback)
+	return feedback, err
+}
+
+func (r *postgrestFeedback) DeleteByPhone(phoneNumbers []string) error {
+	if len(phoneNumbers) == 0 {
+		return nil
+	}
+
+	var result []models.FarmerFeedback
+	_, err := r.client.From("farmer_feedback").Delete("", "").In("phone_number", phoneNumbers).ExecuteTo(&result)
+	return err
+}
+
+// postgrestVisitNotes implements VisitNoteRepository
+type postgrestVisitNotes struct {
+	client *postgrest.Client
+}
+
+func (r *postgrestVisitNotes) Create(note models.FarmerVisitNote) (*models.FarmerVisitNote, error) {
+	return insertOne(r.client, "farmer_visit_notes", note)
+}
+
+func (r *postgrestVisitNotes) ListByFarmer(farmerID int64) ([]models.FarmerVisitNote, error) {
+	var notes []models.FarmerVisitNote
+	_, err := r.client.From("farmer_visit_notes").
+		Select("*", "", false).
+		Eq("farmer_id", fmt.Sprintf("%d", farmerID)).
+		Order("visited_at", &postgrest.OrderOpts{Ascending: false}).
+		ExecuteTo(&notes)
+	return notes, err
+}
+
+// postgrestLocations implements LocationRepository
+type postgrestLocations struct {
+	client *postgrest.Client
+}
+
+func (r *postgrestLocations) List(prefix string, page, perPage int) ([]models.Location, int64, error) {
+	builder := r.client.From("locations").Select("*", "exact", false)
+	if prefix != "" {
+		builder = builder.Or(fmt.Sprintf("name.ilike.%s*,country.ilike.%s*", prefix, prefix), "")
+	}
+
+	from := (page - 1) * perPage
+	var locations []models.Location
+	total, err := builder.
+		Order("name", &postgrest.OrderOpts{Ascending: true}).
+		Range(from, from+perPage-1, "").
+		ExecuteTo(&locations)
+	return locations, total, err
+}
+
+func (r *postgrestLocations) All() ([]models.Location, error) {
+	var locations []models.Location
+	_, err := r.client.From("locations").Select("*", "", false).ExecuteTo(&locations)
+	return locations, err
+}
+
+func (r *postgrestLocations) Get(id uuid.UUID) (*models.Location, error) {
+	var result []models.Location
+	_, err := r.client.From("locations").Select("*", "", false).Eq("id", id.String()).ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestLocations) Create(location models.Location) (*models.Location, error) {
+	return insertOne(r.client, "locations", location)
+}
+
+// postgrestSessions implements SessionRepository
+type postgrestSessions struct {
+	client *postgrest.Client
+}
+
+func (r *postgrestSessions) Revoke(session models.RevokedSession) error {
+	_, _, err := r.client.From("revoked_sessions").Upsert(session, "session_id", "minimal", "").Execute()
+	return err
+}
+
+func (r *postgrestSessions) IsRevoked(sessionID uuid.UUID) (bool, error) {
+	var result []models.RevokedSession
+	_, err := r.client.From("revoked_sessions").Select("session_id", "", false).Eq("session_id", sessionID.String()).ExecuteTo(&result)
+	return len(result) > 0, err
+}
+
+func (r *postgrestSessions) SetCutoff(cutoff models.SessionCutoff) error {
+	_, _, err := r.client.From("session_cutoffs").Upsert(cutoff, "auth_user_id", "minimal", "").Execute()
+	return err
+}
+
+func (r *postgrestSessions) GetCutoff(authUserID uuid.UUID) (*models.SessionCutoff, error) {
+	var result []models.SessionCutoff
+	_, err := r.client.From("session_cutoffs").Select("*", "", false).Eq("auth_user_id", authUserID.String()).ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestSessions) DeleteExpired(now time.Time) error {
+	_, _, err := r.client.From("revoked_sessions").Delete("minimal", "").Lt("expires_at", now.Format(time.RFC3339)).Execute()
+	return err
+}
+
+// postgrestOTPCodes implements OTPRepository
+type postgrestOTPCodes struct {
+	client *postgrest.Client
+}
+
+func (r *postgrestOTPCodes) Save(code models.OTPCode) error {
+	_, _, err := r.client.From("otp_codes").Upsert(code, "phone_number", "minimal", "").Execute()
+	return err
+}
+
+func (r *postgrestOTPCodes) Get(phoneNumber string) (*models.OTPCode, error) {
+	var result []models.OTPCode
+	_, err := r.client.From("otp_codes").Select("*", "", false).Eq("phone_number", phoneNumber).ExecuteTo(&result)
+	return first(result, err)
+}
+
+// RecordAttempt reads and writes the count separately, PostgREST has no atomic increment,
+// so concurrent attempts may be undercounted by one each
+func (r *postgrestOTPCodes) RecordAttempt(phoneNumber string) (int, error) {
+	code, err := r.Get(phoneNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	attempts := code.Attempts + 1
+	var result []models.OTPCode
+	_, err = r.client.From("otp_codes").Update(map[string]any{"attempts": attempts}, "", "").Eq("phone_number", phoneNumber).ExecuteTo(&result)
+	if _, err := first(result, err); err != nil {
+		return 0, err
+	}
+	return attempts, nil
+}
+
+func (r *postgrestOTPCodes) Delete(phoneNumber string) error {
+	_, _, err := r.client.From("otp_codes").Delete("minimal", "").Eq("phone_number", phoneNumber).Execute()
+	return err
+}
+
+// postgrestResets implements PasswordResetRepository
+type postgrestResets struct {
+	client *postgrest.Client
+}
+
+func (r *postgrestResets) Save(token models.PasswordResetToken) error {
+	_, _, err := r.client.From("password_reset_tokens").Upsert(token, "auth_user_id", "minimal", "").Execute()
+	return err
+}
+
+func (r *postgrestResets) Get(authUserID uuid.UUID) (*models.PasswordResetToken, error) {
+	var result []models.PasswordResetToken
+	_, err := r.client.From("password_reset_tokens").Select("*", "", false).Eq("auth_user_id", authUserID.String()).ExecuteTo(&result)
+	return first(result, err)
+}
+
+// RecordAttempt reads and writes the count separately, like postgrestOTPCodes.RecordAttempt
+func (r *postgrestResets) RecordAttempt(authUserID uuid.UUID) (int, error) {
+	token, err := r.Get(authUserID)
+	if err != nil {
+		return 0, err
+	}
+
+	attempts := token.Attempts + 1
+	var result []models.PasswordResetToken
+	_, err = r.client.From("password_reset_tokens").Update(map[string]any{"attempts": attempts}, "", "").Eq("auth_user_id", authUserID.String()).ExecuteTo(&result)
+	if _, err := first(result, err); err != nil {
+		return 0, err
+	}
+	return attempts, nil
+}
+
+func (r *postgrestResets) Delete(authUserID uuid.UUID) error {
+	_, _, err := r.client.From("password_reset_tokens").Delete("minimal", "").Eq("auth_user_id", authUserID.String()).Execute()
+	return err
+}
+
+// postgrestAudit implements AuditRepository
+type postgrestAudit struct {
+	client *postgrest.Client
+}
+
+func (r *postgrestAudit) Create(event models.AuditEvent) error {
+	_, _, err := r.client.From("audit_log").Insert(event, false, "", "minimal", "").Execute()
+	return err
+}
+
+func (r *postgrestAudit) List(filter AuditFilter, page, perPage int) ([]models.AuditEvent, int64, error) {
+	builder := r.client.From("audit_log").Select("*", "exact", false)
+	if filter.ActorID != nil {
+		builder = builder.Eq("actor_id", filter.ActorID.String())
+	}
+	if filter.TargetID != nil {
+		builder = builder.Eq("target_id", filter.TargetID.String())
+	}
+	if filter.Action != "" {
+		builder = builder.Like("action", filter.Action+"*")
+	}
+	if filter.RequestID != "" {
+		builder = builder.Eq("request_id", filter.RequestID)
+	}
+	if filter.IPAddress != "" {
+		builder = builder.Eq("ip_address", filter.IPAddress)
+	}
+	if filter.From != nil {
+		builder = builder.Gte("created_at", filter.From.Format(time.RFC3339))
+	}
+	if filter.To != nil {
+		builder = builder.Lte("created_at", filter.To.Format(time.RFC3339))
+	}
+
+	from := (page - 1) * perPage
+	var events []models.AuditEvent
+	total, err := builder.
+		Order("created_at", &postgrest.OrderOpts{Ascending: false}).
+		Range(from, from+perPage-1, "").
+		ExecuteTo(&events)
+	return events, total, err
+}
+
+// postgrestAPIKeys implements APIKeyRepository
+type postgrestAPIKeys struct {
+	client *postgrest.Client
+}
+
+func (r *postgrestAPIKeys) Create(key models.APIKey) (*models.APIKey, error) {
+	return insertOne(r.client, "api_keys", key)
+}
+
+func (r *postgrestAPIKeys) List() ([]models.APIKey, error) {
+	var result []models.APIKey
+	_, err := r.client.From("api_keys").
+		Select("*", "", false).
+		Order("created_at", &postgrest.OrderOpts{Ascending: false}).
+		ExecuteTo(&result)
+	return result, err
+}
+
+func (r *postgrestAPIKeys) Get(id uuid.UUID) (*models.APIKey, error) {
+	var result []models.APIKey
+	_, err := r.client.From("api_keys").Select("*", "", false).Eq("id", id.String()).ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestAPIKeys) GetByPrefix(prefix string) (*models.APIKey, error) {
+	var result []models.APIKey
+	_, err := r.client.From("api_keys").Select("*", "", false).Eq("prefix", prefix).ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestAPIKeys) Revoke(id uuid.UUID, revokedAt time.Time) error {
+	var result []models.APIKey
+	_, err := r.client.From("api_keys").Update(map[string]any{"revoked_at": revokedAt}, "", "").Eq("id", id.String()).ExecuteTo(&result)
+	_, err = first(result, err)
+	return err
+}
+
+func (r *postgrestAPIKeys) TouchLastUsed(id uuid.UUID, usedAt time.Time) error {
+	_, _, err := r.client.From("api_keys").Update(map[string]any{"last_used_at": usedAt}, "minimal", "").Eq("id", id.String()).Execute()
+	return err
+}
+
+// postgrestLogins implements LoginAttemptRepository
+type postgrestLogins struct {
+	client *postgrest.Client
+}
+
+func (r *postgrestLogins) Get(key string) (*models.LoginAttempt, error) {
+	var result []models.LoginAttempt
+	_, err := r.client.From("login_attempts").Select("*", "", false).Eq("key", key).ExecuteTo(&result)
+	return first(result, err)
+}
+
+// RecordFailure reads and writes the count separately, like postgrestOTPCodes.RecordAttempt
+func (r *postgrestLogins) RecordFailure(key string, at, windowStart time.Time) (*models.LoginAttempt, error) {
+	attempt, err := r.Get(key)
+	if err == ErrNotFound || (err == nil && attempt.LastFailureAt.Before(windowStart)) {
+		attempt, err = &models.LoginAttempt{Key: key}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attempt.Failures++
+	attempt.LastFailureAt = at
+	var result []models.LoginAttempt
+	_, err = r.client.From("login_attempts").Insert(attempt, true, "key", "", "").ExecuteTo(&result)
+	return first(result, err)
+}
+
+func (r *postgrestLogins) Lock(key string, until time.Time) error {
+	var result []models.LoginAttempt
+	_, err := r.client.From("login_attempts").Update(map[string]any{"locked_until": until}, "", "").Eq("key", key).ExecuteTo(&result)
+	_, err = first(result, err)
+	return err
+}
+
+func (r *postgrestLogins) ListLocked(at time.Time) ([]models.LoginAttempt, error) {
+	var result []models.LoginAttempt
+	_, err := r.client.From("login_attempts").
+		Select("*", "", false).
+		Gt("locked_until", at.Format(time.RFC3339Nano)).
+		Order("locked_until", &postgrest.OrderOpts{Ascending: false}).
+		ExecuteTo(&result)
+	return result, err
+}
+
+func (r *postgrestLogins) Delete(key string) error {
+	_, _, err := r.client.From("login_attempts").Delete("minimal", "").Eq("key", key).Execute()
+	return err
+}
+
+// insertOne inserts a row and returns it as stored
+func insertOne[T any](client *postgrest.Client, table string, row T) (*T, error) {
+	var result []T
+	_, err := client.From(table).Insert(row, false, "", "", "").ExecuteTo(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, ErrInsertFailed
+	}
+
+	return &result[0], nil
+}
+
+// isPostgrestUniqueViolation reports whether PostgREST rejected a write on a unique constraint;
+// its errors read "(<SQLSTATE>) <message>"
+func isPostgrestUniqueViolation(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "(23505)")
+}
+
+// first returns the first row of a query result, or ErrNotFound when there is none
+func first[T any](rows []T, err error) (*T, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &rows[0], nil
+}
+
+// listAll pages through every row a query matches, in id order. PostgREST cuts responses
+// off at its max rows setting (1000 on Supabase), so a single request can miss rows.
+// Each page starts after the rows received so far, whatever the server's limit is.
+func listAll[T any](query func() *postgrest.FilterBuilder) ([]T, error) {
+	var rows []T
+	for {
+		var page []T
+		_, err := query().
+			Order("id", &postgrest.OrderOpts{Ascending: true}).
+			Range(len(rows), len(rows)+postgrestPageSize-1, "").
+			ExecuteTo(&page)
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			return rows, nil
+		}
+		rows = append(rows, page...)
+	}
+}
+
+// inBatches runs a query with an In filter once per batch of IDs, keeping request URLs
+// short, and returns every matching row
+func inBatches[T any](ids []uuid.UUID, query func(batch []string) *postgrest.FilterBuilder) ([]T, error) {
+	values := uuidStrings(ids)
+	var rows []T
+	for len(values) > 0 {
+		batch := values[:min(len(values), postgrestInBatchSize)]
+		values = values[len(batch):]
+
+		page, err := listAll[T](func() *postgrest.FilterBuilder { return query(batch) })
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, page...)
+	}
+	return rows, nil
+}
+
+// uuidStrings formats IDs for an In filter
+func uuidStrings(ids []uuid.UUID) []string {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, id.String())
+	}
+	return values
+}
@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/config"
+	"github.com/okoye-dev/flux-server/internal/models"
+)
+
+// Storage backends selectable through config.StorageConfig
+const (
+	BackendSupabase = "supabase"
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+var (
+	// ErrNotFound is returned when a lookup, update or delete matches no row
+	ErrNotFound = errors.New("record not found")
+	// ErrInsertFailed is returned when an insert reports success but returns no row
+	ErrInsertFailed = errors.New("insert returned no rows")
+	// ErrConflict is returned when an insert would break a unique constraint
+	ErrConflict = errors.New("record already exists")
+	// ErrSupabaseConfigMissing is returned when the Supabase URL or anon key is not configured
+	ErrSupabaseConfigMissing = errors.New("supabase configuration missing")
+	// ErrDatabaseURLMissing is returned when the postgres backend has no DSN configured
+	ErrDatabaseURLMissing = errors.New("database URL missing")
+)
+
+// Store groups the repositories the services read and write through
+type Store struct {
+	Profiles    ProfileRepository
+	Roles       RoleRepository
+	Farmers     FarmerRepository
+	Officers    OfficerRepository
+	Crops       CropRepository
+	FarmerCrops FarmerCropRepository
+	Harvests    HarvestRepository
+	Feedback    FeedbackRepository
+	VisitNotes  VisitNoteRepository
+	Locations   LocationRepository
+	Sessions    SessionRepository
+	OTPCodes    OTPRepository
+	Resets      PasswordResetRepository
+	Audit       AuditRepository
+	APIKeys     APIKeyRepository
+	Logins      LoginAttemptRepository
+
+	// transact runs fn in a backend transaction; nil when the backend has none
+	transact func(fn func(tx *Store) error) error
+}
+
+// Transact runs fn against a store whose writes are committed together when fn
+// returns nil and rolled back when it returns an error. Backends without
+// transactions (PostgREST) run fn directly against s.
+func (s *Store) Transact(fn func(tx *Store) error) error {
+	if s.transact == nil {
+		return fn(s)
+	}
+	return s.transact(fn)
+}
+
+// Open creates the store for the configured storage backend
+func Open(cfg *config.Config) (*Store, error) {
+	switch cfg.Storage.Backend {
+	case BackendSupabase, "":
+		return NewPostgrestStore(cfg.Supabase.URL, cfg.Supabase.AnonKey)
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	case BackendPostgres:
+		return NewPostgresStore(cfg.Storage.DatabaseURL)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Storage.Backend)
+	}
+}
+
+// HarvestFilter narrows down harvest listings and reports
+type HarvestFilter struct {
+	CropID *uuid.UUID
+	From   *time.Time
+	To     *time.Time
+}
+
+// ProfileFilter narrows down profile searches
+type ProfileFilter struct {
+	Query    string // Prefix of the username, email or phone number
+	RoleID   *uuid.UUID
+	Disabled *bool
+}
+
+// AuditFilter narrows down audit log queries
+type AuditFilter struct {
+	ActorID   *uuid.UUID
+	TargetID  *uuid.UUID
+	Action    string // Prefix of the action, e.g. "password." for every password event
+	RequestID string
+	IPAddress string
+	From      *time.Time
+	To        *time.Time
+}
+
+// FarmerUpdate holds the farmer columns to change; nil fields are left untouched
+type FarmerUpdate struct {
+	Name        *string
+	PhoneNumber *string
+	CropType    *string
+	LocationID  *int64
+	Language    *string
+	AuthUserID  *uuid.UUID
+}
+
+// ProfileRepository stores user_profiles
+type ProfileRepository interface {
+	Create(profile models.UserProfile) (*models.UserProfile, error)
+	GetByAuthUserID(authUserID string) (*models.UserProfile, error)
+	// FindByDisplayName returns the oldest profile with the display name (the username)
+	FindByDisplayName(displayName string) (*models.UserProfile, error)
+	// FindByEmail returns the profile of the account with the (lowercased) sign-in email
+	FindByEmail(email string) (*models.UserProfile, error)
+	// FindByPhone returns the oldest profile with any of the phone numbers
+	FindByPhone(phoneNumbers []string) (*models.UserProfile, error)
+	// SetEmail records the sign-in email of a profile's account
+	SetEmail(id uuid.UUID, email string) error
+	ListByIDs(ids []uuid.UUID) ([]models.UserProfile, error)
+	// Search returns a page of the profiles matching the filter, newest first, and the total count
+	Search(filter ProfileFilter, page, perPage int) ([]models.UserProfile, int64, error)
+	SetRole(id, roleID uuid.UUID) error
+	// SetDisabled records when a profile's account was disabled; nil enables it again
+	SetDisabled(id uuid.UUID, disabledAt *time.Time) error
+	// Delete removes a profile along with its harvests
+	Delete(id uuid.UUID) error
+}
+
+// RoleRepository reads roles
+type RoleRepository interface {
+	Get(id uuid.UUID) (*models.Role, error)
+	GetByName(name string) (*models.Role, error)
+	List() ([]models.Role, error)
+}
+
+// FarmerRepository stores farmers
+type FarmerRepository interface {
+	// List returns a page of farmers, newest first, and the total count
+	List(page, perPage int) ([]models.Farmer, int64, error)
+	// ListByLocation returns a page of the farmers in a location, by name, and their total count
+	ListByLocation(locationID int64, page, perPage int) ([]models.Farmer, int64, error)
+	ListByAuthUserIDs(authUserIDs []uuid.UUID) ([]models.Farmer, error)
+	Get(id int64) (*models.Farmer, error)
+	// FindByPhone returns the oldest farmer registered with any of the phone numbers
+	FindByPhone(phoneNumbers []string) (*models.Farmer, error)
+	// ListByPhone returns every farmer registered with any of the phone numbers
+	ListByPhone(phoneNumbers []string) ([]models.Farmer, error)
+	// Create inserts a farmer; a zero ID is assigned by the storage
+	Create(farmer models.Farmer) (*models.Farmer, error)
+	Update(id int64, update FarmerUpdate) (*models.Farmer, error)
+	// Delete removes a farmer along with their crop links, feedback and visit notes
+	Delete(id int64) error
+}
+
+// OfficerRepository stores extension_officers
+type OfficerRepository interface {
+	// Create inserts an extension officer; a zero ID is assigned by the storage
+	Create(officer models.ExtensionOfficer) (*models.ExtensionOfficer, error)
+	GetByAuthUserID(authUserID string) (*models.ExtensionOfficer, error)
+	// SetAssignedLocation assigns an extension officer to a location; nil unassigns them
+	SetAssignedLocation(id int64, locationID *int64) (*models.ExtensionOfficer, error)
+	// Delete removes an extension officer along with the visit notes they wrote
+	Delete(id int64) error
+}
+
+// CropRepository stores crops and crop_aliases
+type CropRepository interface {
+	// List returns a page of crops whose name or scientific name starts with prefix, by name
+	List(prefix string, page, perPage int) ([]models.Crop, int64, error)
+	All() ([]models.Crop, error)
+	Get(id uuid.UUID) (*models.Crop, error)
+	// FindByName matches a crop name case-insensitively
+	FindByName(name string) (*models.Crop, error)
+	Create(crop models.Crop) (*models.Crop, error)
+	Delete(id uuid.UUID) error
+	AllAliases() ([]models.CropAlias, error)
+	// FindAlias matches an alias case-insensitively
+	FindAlias(alias string) (*models.CropAlias, error)
+	CreateAlias(alias models.CropAlias) (*models.CropAlias, error)
+	// MoveAliases points every alias of one crop at another
+	MoveAliases(fromCropID, toCropID uuid.UUID) error
+}
+
+// FarmerCropRepository stores the farmer_crops links
+type FarmerCropRepository interface {
+	CropsForFarmer(farmerID int64) ([]models.Crop, error)
+	ListByCrop(cropID uuid.UUID) ([]models.FarmerCrop, error)
+	// Create returns ErrConflict when the farmer already has the crop
+	Create(link models.FarmerCrop) error
+	SetCrop(linkID, cropID uuid.UUID) error
+	Delete(linkID uuid.UUID) error
+	DeleteByFarmer(farmerID int64) error
+}
+
+// HarvestRepository stores farm_harvests; listings embed the harvested crop
+type HarvestRepository interface {
+	Create(harvest models.FarmHarvest) (*models.FarmHarvest, error)
+	// ListByProfile returns a page of a profile's harvests, newest first, and the total count
+	ListByProfile(profileID uuid.UUID, filter HarvestFilter, page, perPage int) ([]models.FarmHarvestWithDetails, int64, error)
+	RecentByProfile(profileID uuid.UUID, limit int) ([]models.FarmHarvestWithDetails, error)
+	List(filter HarvestFilter) ([]models.FarmHarvestWithDetails, error)
+	// ListByLocation returns the harvests of users whose farmer record is in a location
+	ListByLocation(locationID int64, filter HarvestFilter) ([]models.FarmHarvestWithDetails, error)
+	// MoveCrop re-points every harvest of one crop at another and returns how many moved
+	MoveCrop(fromCropID, toCropID uuid.UUID) (int, error)
+}
+
+// FeedbackRepository stores farmer_feedback
+type FeedbackRepository interface {
+	Create(feedback models.FarmerFeedback) (*models.FarmerFeedback, error)
+	// RecentByFarmer returns a farmer's latest feedback, newest first
+	RecentByFarmer(farmerID int64, limit int) ([]models.FarmerFeedback, error)
+	// ListByFarmerOrPhone returns the feedback of any of the farmers or sent from any of
+	// the phone numbers, newest first
+	ListByFarmerOrPhone(farmerIDs []int64, phoneNumbers []string) ([]models.FarmerFeedback, error)
+	// DeleteByPhone removes the feedback sent from any of the phone numbers
+	DeleteByPhone(phoneNumbers []string) error
+}
+
+// VisitNoteRepository stores farmer_visit_notes
+type VisitNoteRepository interface {
+	Create(note models.FarmerVisitNote) (*models.FarmerVisitNote, error)
+	// ListByFarmer returns a farmer's visit notes, most recent visit first
+	ListByFarmer(farmerID int64) ([]models.FarmerVisitNote, error)
+}
+
+// LocationRepository stores locations
+type LocationRepository interface {
+	// List returns a page of locations whose name or country starts with prefix, by name
+	List(prefix string, page, perPage int) ([]models.Location, int64, error)
+	All() ([]models.Location, error)
+	Get(id uuid.UUID) (*models.Location, error)
+	Create(location models.Location) (*models.Location, error)
+}
+
+// SessionRepository stores revoked_sessions and session_cutoffs
+type SessionRepository interface {
+	// Revoke records a signed-out session; revoking it again is a no-op
+	Revoke(session models.RevokedSession) error
+	IsRevoked(sessionID uuid.UUID) (bool, error)
+	// SetCutoff records that every token issued to the user up to cutoff.RevokedBefore is revoked
+	SetCutoff(cutoff models.SessionCutoff) error
+	// GetCutoff returns ErrNotFound when the user never signed out everywhere
+	GetCutoff(authUserID uuid.UUID) (*models.SessionCutoff, error)
+	// DeleteExpired forgets revoked sessions whose tokens have all expired by now
+	DeleteExpired(now time.Time) error
+}
+
+// OTPRepository stores otp_codes, at most one pending code per phone number
+type OTPRepository interface {
+	// Save stores a code, replacing any pending code for the phone number
+	Save(code models.OTPCode) error
+	Get(phoneNumber string) (*models.OTPCode, error)
+	// RecordAttempt counts a verification attempt and returns the attempts made so far
+	RecordAttempt(phoneNumber string) (int, error)
+	Delete(phoneNumber string) error
+}
+
+// PasswordResetRepository stores password_reset_tokens, at most one pending token per user
+type PasswordResetRepository interface {
+	// Save stores a token, replacing any pending token for the user
+	Save(token models.PasswordResetToken) error
+	Get(authUserID uuid.UUID) (*models.PasswordResetToken, error)
+	// RecordAttempt counts a reset attempt and returns the attempts made so far
+	RecordAttempt(authUserID uuid.UUID) (int, error)
+	Delete(authUserID uuid.UUID) error
+}
+
+// AuditRepository appends to audit_log. Events are never updated or deleted.
+type AuditRepository interface {
+	Create(event models.AuditEvent) error
+	// List returns a page of the events matching the filter, newest first
+	List(filter AuditFilter, page, perPage int) ([]models.AuditEvent, int64, error)
+}
+
+// APIKeyRepository stores api_keys
+type APIKeyRepository interface {
+	Create(key models.APIKey) (*models.APIKey, error)
+	// List returns every key, newest first
+	List() ([]models.APIKey, error)
+	Get(id uuid.UUID) (*models.APIKey, error)
+	GetByPrefix(prefix string) (*models.APIKey, error)
+	Revoke(id uuid.UUID, revokedAt time.Time) error
+	TouchLastUsed(id uuid.UUID, usedAt time.Time) error
+}
+
+// LoginAttemptRepository counts failed sign-ins per account and per IP address. Backed by
+// the database, the counters are shared by every server instance.
+type LoginAttemptRepository interface {
+	Get(key string) (*models.LoginAttempt, error)
+	// RecordFailure counts a failed sign-in for a key, starting the count over when the
+	// previous failure was before windowStart, and returns the updated counter
+	RecordFailure(key string, at, windowStart time.Time) (*models.LoginAttempt, error)
+	// Lock refuses sign-ins for a counted key until the given time
+	Lock(key string, until time.Time) error
+	// ListLocked returns the keys locked beyond the given time, latest lock first
+	ListLocked(at time.Time) ([]models.LoginAttempt, error)
+	Delete(key string) error
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// API key settings
+const (
+	// apiKeyTag starts every key, so leaked keys are easy to recognise and scan for
+	apiKeyTag        = "flux"
+	apiKeyDefaultTTL = 90 * 24 * time.Hour
+	// apiKeyTouchInterval limits last_used_at writes to one per key per interval
+	apiKeyTouchInterval = time.Minute
+)
+
+// Audited API key actions
+const (
+	AuditAPIKeyCreated = "api_key.created"
+	AuditAPIKeyRevoked = "api_key.revoked"
+)
+
+// APIKeyService creates, revokes and verifies API keys. A key is "flux_<prefix>_<secret>":
+// the prefix finds the stored key and a SHA-256 hash of the whole key verifies it. Keys
+// are random, so unlike one-time codes they need no secret mixed into the hash.
+type APIKeyService struct {
+	store  *repository.Store
+	scopes []string
+	audit  *AuditService
+}
+
+// NewAPIKeyService creates a new API key service; scopes are the ones keys may be granted
+func NewAPIKeyService(store *repository.Store, scopes []string) *APIKeyService {
+	return &APIKeyService{
+		store:  store,
+		scopes: scopes,
+		audit:  NewAuditService(store),
+	}
+}
+
+// CreatedAPIKey is a new key along with its secret, which is only ever returned here
+type CreatedAPIKey struct {
+	models.APIKey
+	Key string
+}
+
+// CreateKey creates a key acting as req.OwnerID with the requested scopes
+func (s *APIKeyService) CreateKey(req models.CreateAPIKeyRequest, actx AuditContext) (*CreatedAPIKey, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrAPIKeyNameRequired
+	}
+	if len(req.Scopes) == 0 {
+		return nil, ErrAPIKeyScopesInvalid
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(s.scopes, scope) {
+			return nil, ErrAPIKeyScopesInvalid
+		}
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(apiKeyDefaultTTL)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) {
+			return nil, ErrAPIKeyExpiryInvalid
+		}
+		expiresAt = *req.ExpiresAt
+	}
+
+	if _, err := s.store.Profiles.GetByAuthUserID(req.OwnerID.String()); err != nil {
+		if err == repository.ErrNotFound {
+			return nil, ErrAPIKeyOwnerNotFound
+		}
+		return nil, err
+	}
+
+	prefix, err := randomToken(8, hex.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomToken(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+	key := apiKeyTag + "_" + prefix + "_" + secret
+
+	stored, err := s.store.APIKeys.Create(models.APIKey{
+		ID:        uuid.New(),
+		Name:      name,
+		OwnerID:   req.OwnerID,
+		Prefix:    prefix,
+		KeyHash:   hashAPIKey(key),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(req.Scopes))),
+		ExpiresAt: expiresAt,
+		CreatedBy: actx.ActorID,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actx, AuditAPIKeyCreated, &stored.OwnerID, map[string]any{
+		"key_id": stored.ID,
+		"name":   stored.Name,
+		"scopes": stored.Scopes,
+	})
+	return &CreatedAPIKey{APIKey: *stored, Key: key}, nil
+}
+
+// ListKeys returns every key, newest first, including revoked and expired ones
+func (s *APIKeyService) ListKeys() ([]models.APIKey, error) {
+	return s.store.APIKeys.List()
+}
+
+// RevokeKey stops a key from being accepted; revoking a revoked key is a no-op
+func (s *APIKeyService) RevokeKey(id uuid.UUID, actx AuditContext) (*models.APIKey, error) {
+	key, err := s.store.APIKeys.Get(id)
+	if err == repository.ErrNotFound {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return key, nil
+	}
+
+	now := time.Now()
+	if err := s.store.APIKeys.Revoke(id, now); err != nil {
+		return nil, err
+	}
+	key.RevokedAt = &now
+
+	s.audit.Record(actx, AuditAPIKeyRevoked, &key.OwnerID, map[string]any{"key_id": key.ID, "name": key.Name})
+	return key, nil
+}
+
+// Authenticate returns the key a request presented, or ErrAPIKeyInvalid when it is
+// unknown, revoked or expired
+func (s *APIKeyService) Authenticate(rawKey string) (*models.APIKey, error) {
+	tag, rest, ok := strings.Cut(rawKey, "_")
+	if !ok || tag != apiKeyTag {
+		return nil, ErrAPIKeyInvalid
+	}
+	prefix, _, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	key, err := s.store.APIKeys.GetByPrefix(prefix)
+	if err == repository.ErrNotFound {
+		return nil, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(rawKey)), []byte(key.KeyHash)) != 1 {
+		return nil, ErrAPIKeyInvalid
+	}
+	if key.RevokedAt != nil || !now.Before(key.ExpiresAt) {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.store.APIKeys.TouchLastUsed(key.ID, now); err != nil {
+			slog.Warn("Failed to record use of API key", "api_key_id", key.ID, "error", err)
+		}
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
+
+// revokeOwnedAPIKeys revokes every active key acting as a user and returns how many it revoked
+func revokeOwnedAPIKeys(store *repository.Store, ownerID uuid.UUID) (int, error) {
+	keys, err := store.APIKeys.List()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	revoked := 0
+	for _, key := range keys {
+		if key.OwnerID != ownerID || key.RevokedAt != nil {
+			continue
+		}
+		if err := store.APIKeys.Revoke(key.ID, now); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// hashAPIKey returns the stored form of a key
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken encodes n random bytes
+func randomToken(n int, encode func([]byte) string) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return encode(buf), nil
+}
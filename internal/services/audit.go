@@ -0,0 +1,77 @@
+package services
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// Audited actions
+const (
+	AuditPasswordChanged        = "password.changed"
+	AuditPasswordChangeFailed   = "password.change_failed"
+	AuditPasswordResetRequested = "password.reset_requested"
+	AuditPasswordResetFailed    = "password.reset_failed"
+	AuditPasswordResetLocked    = "password.reset_locked"
+	AuditPasswordResetCompleted = "password.reset_completed"
+	AuditSignedUp               = "account.signed_up"
+	AuditSignInSucceeded        = "signin.succeeded"
+	AuditSignInFailed           = "signin.failed"
+	AuditSignedOut              = "session.signed_out"
+	AuditSignedOutEverywhere    = "session.signed_out_everywhere"
+)
+
+// AuditFilter narrows down audit log queries
+type AuditFilter = repository.AuditFilter
+
+// AuditContext describes who performed an audited action and from where
+type AuditContext struct {
+	ActorID   *uuid.UUID // Nil when the caller isn't signed in
+	IPAddress string
+	UserAgent string
+	RequestID string
+}
+
+// AuditService appends security-relevant actions to the audit log
+type AuditService struct {
+	store *repository.Store
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(store *repository.Store) *AuditService {
+	return &AuditService{store: store}
+}
+
+// Record appends an event for an action on targetID, which may be nil. A failed write is
+// logged rather than returned, so auditing never fails the action being audited.
+func (s *AuditService) Record(actx AuditContext, action string, targetID *uuid.UUID, details map[string]any) {
+	event := models.AuditEvent{
+		ID:        uuid.New(),
+		ActorID:   actx.ActorID,
+		Action:    action,
+		TargetID:  targetID,
+		IPAddress: actx.IPAddress,
+		UserAgent: actx.UserAgent,
+		RequestID: actx.RequestID,
+		Details:   details,
+		CreatedAt: time.Now(),
+	}
+	if event.Details == nil {
+		event.Details = map[string]any{}
+	}
+
+	if err := s.store.Audit.Create(event); err != nil {
+		slog.Error("Failed to write audit event", "action", action, "request_id", actx.RequestID, "error", err)
+	}
+}
+
+// List returns a page of the audit events matching the filter, newest first
+func (s *AuditService) List(filter AuditFilter, page, perPage int) ([]models.AuditEvent, int64, error) {
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return nil, 0, ErrAuditRangeInvalid
+	}
+	return s.store.Audit.List(filter, page, perPage)
+}
@@ -0,0 +1,518 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// Catalogue search modes
+const (
+	SearchModePrefix = "prefix"
+	SearchModeFuzzy  = "fuzzy"
+)
+
+// Audited catalogue actions
+const (
+	AuditCropCreated     = "crop.created"
+	AuditCropsMerged     = "crop.merged"
+	AuditLocationCreated = "location.created"
+)
+
+// CatalogueSearch describes a catalogue search; an empty Query lists everything
+type CatalogueSearch struct {
+	Query string
+	Mode  string
+}
+
+// CatalogueService handles the location and crop catalogues
+type CatalogueService struct {
+	store *repository.Store
+	audit *AuditService
+}
+
+// NewCatalogueService creates a new catalogue service
+func NewCatalogueService(store *repository.Store) *CatalogueService {
+	return &CatalogueService{store: store, audit: NewAuditService(store)}
+}
+
+// ListCrops returns a page of crops matching the search on name, scientific name and (fuzzy only) aliases
+func (s *CatalogueService) ListCrops(search CatalogueSearch, page, perPage int) ([]models.Crop, int64, error) {
+	query, mode, err := normalizeSearch(search)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if query != "" && mode == SearchModeFuzzy {
+		crops, err := s.allCrops()
+		if err != nil {
+			return nil, 0, err
+		}
+		aliases, err := s.allCropAliases()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		aliasesByCrop := map[uuid.UUID][]string{}
+		for _, alias := range aliases {
+			aliasesByCrop[alias.CropID] = append(aliasesByCrop[alias.CropID], alias.Alias)
+		}
+
+		matches := rankFuzzy(query, crops, func(crop models.Crop) []string {
+			return append([]string{crop.Name, derefString(crop.ScientificName)}, aliasesByCrop[crop.ID]...)
+		})
+		return paginateSlice(matches, page, perPage), int64(len(matches)), nil
+	}
+
+	return s.store.Crops.List(query, page, perPage)
+}
+
+// GetCrop retrieves a crop by ID
+func (s *CatalogueService) GetCrop(cropID uuid.UUID) (*models.Crop, error) {
+	crop, err := s.store.Crops.Get(cropID)
+	if err == repository.ErrNotFound {
+		return nil, ErrCropNotFound
+	}
+	return crop, err
+}
+
+// CreateCrop adds a crop to the catalogue, refusing names that already resolve to a crop
+func (s *CatalogueService) CreateCrop(req models.CreateCropRequest, actx AuditContext) (*models.Crop, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrCropNameRequired
+	}
+
+	if _, err := s.lookupCrop(name, false); err == nil {
+		return nil, ErrCropAlreadyExists
+	} else if err != ErrCropNotFound {
+		return nil, err
+	}
+
+	crop := models.Crop{
+		ID:             uuid.New(),
+		Name:           name,
+		ScientificName: req.ScientificName,
+		CreatedAt:      time.Now(),
+	}
+
+	created, err := s.store.Crops.Create(crop)
+	if err == repository.ErrInsertFailed {
+		return nil, fmt.Errorf("failed to create crop: %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actx, AuditCropCreated, nil, map[string]any{"crop_id": created.ID, "name": created.Name})
+	return created, nil
+}
+
+// ResolveCrop maps a free-text crop name onto a catalogue crop.
+// It matches names case-insensitively, then aliases, then tolerates small typos.
+func (s *CatalogueService) ResolveCrop(name string) (*models.Crop, error) {
+	return s.lookupCrop(name, true)
+}
+
+// MergeCrops folds duplicate crops into a canonical crop.
+// farmer_crops and farm_harvests references are rewritten before the duplicates are deleted,
+// all in one transaction, so a merge that fails part-way changes nothing.
+func (s *CatalogueService) MergeCrops(req models.MergeCropsRequest, actx AuditContext) (*models.CropMergeResult, error) {
+	var result *models.CropMergeResult
+	err := s.store.Transact(func(tx *repository.Store) error {
+		var err error
+		result, err = NewCatalogueService(tx).mergeCrops(req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actx, AuditCropsMerged, nil, map[string]any{
+		"crop_id":         result.Crop.ID,
+		"merged_crop_ids": result.MergedCropIDs,
+		"harvests_moved":  result.HarvestsMoved,
+	})
+	return result, nil
+}
+
+// mergeCrops does the work of MergeCrops on the service's store
+func (s *CatalogueService) mergeCrops(req models.MergeCropsRequest) (*models.CropMergeResult, error) {
+	canonical, err := s.GetCrop(req.CanonicalID)
+	if err != nil {
+		return nil, err
+	}
+
+	var duplicates []models.Crop
+	for _, duplicateID := range req.DuplicateIDs {
+		if duplicateID == canonical.ID {
+			continue
+		}
+		duplicate, err := s.GetCrop(duplicateID)
+		if err != nil {
+			return nil, err
+		}
+		duplicates = append(duplicates, *duplicate)
+	}
+
+	if len(duplicates) == 0 {
+		return nil, ErrCropMergeInvalid
+	}
+
+	result := &models.CropMergeResult{Crop: *canonical, MergedCropIDs: []string{}, AliasesAdded: []string{}}
+
+	// Farmers who already grow the canonical crop must not get a second link
+	canonicalLinks, err := s.store.FarmerCrops.ListByCrop(canonical.ID)
+	if err != nil {
+		return nil, err
+	}
+	linkedFarmers := map[int64]bool{}
+	for _, link := range canonicalLinks {
+		linkedFarmers[link.FarmerID] = true
+	}
+
+	for _, duplicate := range duplicates {
+		links, err := s.store.FarmerCrops.ListByCrop(duplicate.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, link := range links {
+			if linkedFarmers[link.FarmerID] {
+				err = s.store.FarmerCrops.Delete(link.ID)
+				result.FarmerCropsRemoved++
+			} else {
+				err = s.store.FarmerCrops.SetCrop(link.ID, canonical.ID)
+				linkedFarmers[link.FarmerID] = true
+				result.FarmerCropsMoved++
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		moved, err := s.store.Harvests.MoveCrop(duplicate.ID, canonical.ID)
+		if err != nil {
+			return nil, err
+		}
+		result.HarvestsMoved += moved
+
+		// Keep the duplicate's own aliases pointing somewhere useful
+		if err := s.store.Crops.MoveAliases(duplicate.ID, canonical.ID); err != nil {
+			return nil, err
+		}
+
+		if err := s.store.Crops.Delete(duplicate.ID); err != nil {
+			return nil, err
+		}
+		result.MergedCropIDs = append(result.MergedCropIDs, duplicate.ID.String())
+	}
+
+	// Old names keep resolving to the canonical crop
+	names := append([]string{}, req.Aliases...)
+	for _, duplicate := range duplicates {
+		names = append(names, duplicate.Name)
+	}
+	for _, name := range names {
+		added, err := s.addCropAlias(*canonical, name)
+		if err != nil {
+			return nil, err
+		}
+		if added {
+			result.AliasesAdded = append(result.AliasesAdded, strings.TrimSpace(name))
+		}
+	}
+
+	return result, nil
+}
+
+// ListLocations returns a page of locations matching the search on name and country
+func (s *CatalogueService) ListLocations(search CatalogueSearch, page, perPage int) ([]models.Location, int64, error) {
+	query, mode, err := normalizeSearch(search)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if query != "" && mode == SearchModeFuzzy {
+		locations, err := s.store.Locations.All()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		matches := rankFuzzy(query, locations, func(location models.Location) []string {
+			return []string{location.Name, derefString(location.Country)}
+		})
+		return paginateSlice(matches, page, perPage), int64(len(matches)), nil
+	}
+
+	return s.store.Locations.List(query, page, perPage)
+}
+
+// GetLocation retrieves a location by ID
+func (s *CatalogueService) GetLocation(locationID uuid.UUID) (*models.Location, error) {
+	location, err := s.store.Locations.Get(locationID)
+	if err == repository.ErrNotFound {
+		return nil, ErrLocationNotFound
+	}
+	return location, err
+}
+
+// CreateLocation adds a location to the catalogue
+func (s *CatalogueService) CreateLocation(req models.CreateLocationRequest, actx AuditContext) (*models.Location, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrLocationNameRequired
+	}
+
+	location := models.Location{
+		ID:        uuid.New(),
+		Name:      name,
+		Country:   req.Country,
+		CreatedAt: time.Now(),
+	}
+
+	created, err := s.store.Locations.Create(location)
+	if err == repository.ErrInsertFailed {
+		return nil, fmt.Errorf("failed to create location: %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actx, AuditLocationCreated, nil, map[string]any{"location_id": created.ID, "name": created.Name})
+	return created, nil
+}
+
+// lookupCrop resolves a crop by case-insensitive name, then alias, then (optionally) a close typo
+func (s *CatalogueService) lookupCrop(name string, allowTypos bool) (*models.Crop, error) {
+	name = sanitizeSearchTerm(name)
+	if name == "" {
+		return nil, ErrCropNotFound
+	}
+
+	crop, err := s.store.Crops.FindByName(name)
+	if err == nil {
+		return crop, nil
+	}
+	if err != repository.ErrNotFound {
+		return nil, err
+	}
+
+	alias, err := s.store.Crops.FindAlias(name)
+	if err == nil {
+		return s.GetCrop(alias.CropID)
+	}
+	if err != repository.ErrNotFound {
+		return nil, err
+	}
+
+	if !allowTypos {
+		return nil, ErrCropNotFound
+	}
+
+	all, err := s.allCrops()
+	if err != nil {
+		return nil, err
+	}
+	allAliases, err := s.allCropAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	best, bestDistance := -1, typoTolerance(name)+1
+	for i, crop := range all {
+		candidates := []string{crop.Name}
+		for _, alias := range allAliases {
+			if alias.CropID == crop.ID {
+				candidates = append(candidates, alias.Alias)
+			}
+		}
+		for _, candidate := range candidates {
+			if distance := levenshtein(strings.ToLower(name), strings.ToLower(candidate)); distance < bestDistance {
+				best, bestDistance = i, distance
+			}
+		}
+	}
+
+	if best < 0 {
+		return nil, ErrCropNotFound
+	}
+
+	return &all[best], nil
+}
+
+// addCropAlias records an alias for a crop unless the name already resolves somewhere
+func (s *CatalogueService) addCropAlias(crop models.Crop, alias string) (bool, error) {
+	alias = strings.TrimSpace(alias)
+	if alias == "" || strings.EqualFold(alias, crop.Name) {
+		return false, nil
+	}
+
+	if _, err := s.lookupCrop(alias, false); err == nil {
+		return false, nil
+	} else if err != ErrCropNotFound {
+		return false, err
+	}
+
+	record := models.CropAlias{
+		ID:        uuid.New(),
+		CropID:    crop.ID,
+		Alias:     alias,
+		CreatedAt: time.Now(),
+	}
+
+	if _, err := s.store.Crops.CreateAlias(record); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// allCrops loads the whole crop catalogue; it is small enough to rank in memory
+func (s *CatalogueService) allCrops() ([]models.Crop, error) {
+	return s.store.Crops.All()
+}
+
+// allCropAliases loads every crop alias
+func (s *CatalogueService) allCropAliases() ([]models.CropAlias, error) {
+	return s.store.Crops.AllAliases()
+}
+
+// normalizeSearch validates the search mode and strips characters PostgREST treats as syntax
+func normalizeSearch(search CatalogueSearch) (string, string, error) {
+	mode := search.Mode
+	if mode == "" {
+		mode = SearchModePrefix
+	}
+	if mode != SearchModePrefix && mode != SearchModeFuzzy {
+		return "", "", ErrSearchModeInvalid
+	}
+
+	return sanitizeSearchTerm(search.Query), mode, nil
+}
+
+// sanitizeSearchTerm removes wildcard and filter-syntax characters from user input
+func sanitizeSearchTerm(term string) string {
+	term = strings.Map(func(r rune) rune {
+		switch r {
+		case '*', '%', '_', ',', '(', ')', '"', '\\':
+			return -1
+		}
+		return r
+	}, term)
+	return strings.TrimSpace(term)
+}
+
+// rankFuzzy returns the items whose fields resemble the query, best matches first.
+// Prefixes rank above substrings, which rank above near-misses within the typo tolerance.
+func rankFuzzy[T any](query string, items []T, fields func(T) []string) []T {
+	query = strings.ToLower(query)
+	tolerance := typoTolerance(query)
+
+	type scored struct {
+		item  T
+		score int
+	}
+	var matches []scored
+	for _, item := range items {
+		best := -1
+		for _, field := range fields(item) {
+			field = strings.ToLower(field)
+			if field == "" {
+				continue
+			}
+
+			score := -1
+			switch {
+			case strings.HasPrefix(field, query):
+				score = 0
+			case strings.Contains(field, query):
+				score = 1
+			default:
+				distance := levenshtein(query, field)
+				// Also compare against the same-length prefix so "tomat" finds "tomatoes"
+				if fieldRunes, n := []rune(field), len([]rune(query)); len(fieldRunes) > n {
+					distance = min(distance, levenshtein(query, string(fieldRunes[:n])))
+				}
+				if distance <= tolerance {
+					score = 1 + distance
+				}
+			}
+
+			if score >= 0 && (best < 0 || score < best) {
+				best = score
+			}
+		}
+
+		if best >= 0 {
+			matches = append(matches, scored{item: item, score: best})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score < matches[j].score })
+
+	result := make([]T, 0, len(matches))
+	for _, match := range matches {
+		result = append(result, match.item)
+	}
+	return result
+}
+
+// typoTolerance is the number of edits allowed for a search term of this length
+func typoTolerance(term string) int {
+	switch n := len([]rune(term)); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// levenshtein computes the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+// paginateSlice returns one page of an in-memory result set
+func paginateSlice[T any](items []T, page, perPage int) []T {
+	from := (page - 1) * perPage
+	if from >= len(items) {
+		return []T{}
+	}
+	to := min(from+perPage, len(items))
+	return items[from:to]
+}
+
+// derefString returns the value of an optional string, or an empty string
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// Audited farmer record actions
+const (
+	AuditFarmerCreated = "farmer.created"
+	AuditFarmerUpdated = "farmer.updated"
+	AuditFarmerDeleted = "farmer.deleted"
+)
+
+// FarmerService handles farmer record operations
+type FarmerService struct {
+	store    *repository.Store
+	profiles *ProfileService
+	audit    *AuditService
+}
+
+// NewFarmerService creates a new farmer service
+func NewFarmerService(store *repository.Store) *FarmerService {
+	return &FarmerService{store: store, profiles: NewProfileService(store), audit: NewAuditService(store)}
+}
+
+// ListFarmers returns a page of farmers with their crops and the total farmer count
+func (s *FarmerService) ListFarmers(page, perPage int) ([]models.FarmerWithCrops, int64, error) {
+	farmers, total, err := s.store.Farmers.List(page, perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]models.FarmerWithCrops, 0, len(farmers))
+	for _, farmer := range farmers {
+		withCrops, err := s.withCrops(farmer)
+		if err != nil {
+			return nil, 0, err
+		}
+		result = append(result, *withCrops)
+	}
+
+	return result, total, nil
+}
+
+// GetFarmer retrieves a farmer and their crops by farmer ID
+func (s *FarmerService) GetFarmer(farmerID int64) (*models.FarmerWithCrops, error) {
+	farmer, err := s.getFarmer(farmerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withCrops(*farmer)
+}
+
+// CreateFarmer creates a farmer record and links the requested crops. The record has no
+// account; one is only linked at signup or when the farmer first signs in with a WhatsApp code.
+func (s *FarmerService) CreateFarmer(req models.CreateFarmerRequest, actx AuditContext) (*models.FarmerWithCrops, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, ErrFarmerNameRequired
+	}
+
+	language := req.Language
+	if language == "" {
+		language = "en"
+	}
+
+	// The ID is left zero for the database to assign
+	farmer := models.Farmer{
+		Name:        req.Name,
+		PhoneNumber: req.PhoneNumber,
+		CropType:    req.CropType,
+		LocationID:  req.LocationID,
+		Language:    language,
+		CreatedAt:   time.Now(),
+	}
+
+	// The farmer and their crop links are created together
+	var created *models.Farmer
+	err := s.store.Transact(func(tx *repository.Store) error {
+		var err error
+		created, err = tx.Farmers.Create(farmer)
+		if err == repository.ErrInsertFailed {
+			return ErrFarmerCreationFailed
+		}
+		if err != nil {
+			return err
+		}
+
+		return NewProfileService(tx).AddCropsToFarmer(created.ID, farmerCropNames(req.CropType, req.Crops))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actx, AuditFarmerCreated, created.AuthUserID, map[string]any{"farmer_id": created.ID})
+	return s.withCrops(*created)
+}
+
+// ReplaceFarmer overwrites every editable field of a farmer, including their crops
+func (s *FarmerService) ReplaceFarmer(farmerID int64, req models.CreateFarmerRequest, actx AuditContext) (*models.FarmerWithCrops, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, ErrFarmerNameRequired
+	}
+
+	language := req.Language
+	if language == "" {
+		language = "en"
+	}
+
+	crops := farmerCropNames(req.CropType, req.Crops)
+	update := models.UpdateFarmerRequest{
+		Name:        &req.Name,
+		PhoneNumber: &req.PhoneNumber,
+		CropType:    &req.CropType,
+		LocationID:  &req.LocationID,
+		Language:    &language,
+		Crops:       &crops,
+	}
+
+	return s.UpdateFarmer(farmerID, update, actx)
+}
+
+// UpdateFarmer applies a partial update to a farmer. The audit log records which fields
+// were changed, but not their values.
+func (s *FarmerService) UpdateFarmer(farmerID int64, req models.UpdateFarmerRequest, actx AuditContext) (*models.FarmerWithCrops, error) {
+	// Check, update and replace the crops in one transaction, so a failed crop add doesn't
+	// leave the farmer half updated or with no crops
+	var farmer *models.Farmer
+	err := s.store.Transact(func(tx *repository.Store) error {
+		var err error
+		farmer, err = NewFarmerService(tx).updateFarmer(farmerID, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actx, AuditFarmerUpdated, farmer.AuthUserID, map[string]any{
+		"farmer_id": farmerID,
+		"fields":    updatedFarmerFields(req),
+	})
+	return s.withCrops(*farmer)
+}
+
+// updateFarmer does the work of UpdateFarmer on the service's store
+func (s *FarmerService) updateFarmer(farmerID int64, req models.UpdateFarmerRequest) (*models.Farmer, error) {
+	// Make sure the farmer exists before touching farmer_crops
+	farmer, err := s.getFarmer(farmerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		return nil, ErrFarmerNameRequired
+	}
+	// The number signs the farmer into their account with a WhatsApp code, so it's
+	// fixed once they have one
+	if req.PhoneNumber != nil && farmer.AuthUserID != nil && !samePhoneNumber(*req.PhoneNumber, farmer.PhoneNumber) {
+		return nil, ErrFarmerPhoneLinked
+	}
+
+	update := repository.FarmerUpdate{
+		Name:        req.Name,
+		PhoneNumber: req.PhoneNumber,
+		CropType:    req.CropType,
+		LocationID:  req.LocationID,
+		Language:    req.Language,
+	}
+	if update != (repository.FarmerUpdate{}) {
+		farmer, err = s.store.Farmers.Update(farmerID, update)
+		if err == repository.ErrNotFound {
+			return nil, ErrFarmerNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Crops != nil {
+		if err := s.profiles.RemoveFarmerCrops(farmerID); err != nil {
+			return nil, err
+		}
+		if err := s.profiles.AddCropsToFarmer(farmerID, *req.Crops); err != nil {
+			return nil, err
+		}
+	}
+
+	return farmer, nil
+}
+
+// DeleteFarmer deletes a farmer; farmer_crops rows are removed by the ON DELETE CASCADE
+func (s *FarmerService) DeleteFarmer(farmerID int64, actx AuditContext) error {
+	farmer, err := s.getFarmer(farmerID)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.Farmers.Delete(farmerID)
+	if err == repository.ErrNotFound {
+		return ErrFarmerNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	s.audit.Record(actx, AuditFarmerDeleted, farmer.AuthUserID, map[string]any{"farmer_id": farmerID})
+	return nil
+}
+
+// updatedFarmerFields lists the fields a farmer update sets
+func updatedFarmerFields(req models.UpdateFarmerRequest) []string {
+	fields := []string{}
+	for _, field := range []struct {
+		name string
+		set  bool
+	}{
+		{"name", req.Name != nil},
+		{"phone_number", req.PhoneNumber != nil},
+		{"crop_type", req.CropType != nil},
+		{"location_id", req.LocationID != nil},
+		{"language", req.Language != nil},
+		{"crops", req.Crops != nil},
+	} {
+		if field.set {
+			fields = append(fields, field.name)
+		}
+	}
+	return fields
+}
+
+// botLanguageCodes maps the language names farmers type into the bot to language codes
+var botLanguageCodes = map[string]string{
+	"english": "en",
+	"swahili": "sw",
+	"french":  "fr",
+	"hausa":   "ha",
+	"yoruba":  "yo",
+	"igbo":    "ig",
+}
+
+// RegisterFarmer saves a farmer who registered through the WhatsApp bot. A farmer already
+// registered with the phone number is updated in place so the bot and the web app share one record.
+// The audit log records these without an actor.
+func (s *FarmerService) RegisterFarmer(phoneNumber, name string, crops []string, language string) error {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if code, ok := botLanguageCodes[language]; ok {
+		language = code
+	}
+	var cropType string
+	if len(crops) > 0 {
+		cropType = crops[0]
+	}
+
+	farmer, err := findFarmerByPhone(s.store, phoneNumber)
+	switch {
+	case err == ErrFarmerNotFound:
+		_, err = s.CreateFarmer(models.CreateFarmerRequest{
+			Name:        name,
+			PhoneNumber: phoneNumber,
+			CropType:    cropType,
+			Language:    language,
+			Crops:       crops,
+		}, AuditContext{})
+		return err
+	case err != nil:
+		return err
+	}
+
+	_, err = s.UpdateFarmer(farmer.ID, models.UpdateFarmerRequest{
+		Name:     &name,
+		CropType: &cropType,
+		Language: &language,
+		Crops:    &crops,
+	}, AuditContext{})
+	return err
+}
+
+// getFarmer retrieves a bare farmer row by ID
+func (s *FarmerService) getFarmer(farmerID int64) (*models.Farmer, error) {
+	farmer, err := s.store.Farmers.Get(farmerID)
+	if err == repository.ErrNotFound {
+		return nil, ErrFarmerNotFound
+	}
+	return farmer, err
+}
+
+// GetFarmerByPhone retrieves the farmer registered with a phone number
+func (s *FarmerService) GetFarmerByPhone(phoneNumber string) (*models.Farmer, error) {
+	return findFarmerByPhone(s.store, phoneNumber)
+}
+
+// samePhoneNumber reports whether two phone numbers have the same digits
+func samePhoneNumber(a, b string) bool {
+	variantsA, variantsB := phoneNumberVariants(a), phoneNumberVariants(b)
+	return variantsA != nil && variantsB != nil && variantsA[1] == variantsB[1]
+}
+
+// findFarmerByPhone looks a farmer up by phone number, with or without the leading "+"
+func findFarmerByPhone(store *repository.Store, phoneNumber string) (*models.Farmer, error) {
+	farmer, err := store.Farmers.FindByPhone(phoneNumberVariants(phoneNumber))
+	if err == repository.ErrNotFound {
+		return nil, ErrFarmerNotFound
+	}
+	return farmer, err
+}
+
+// withCrops attaches the farmer's crops from farmer_crops
+func (s *FarmerService) withCrops(farmer models.Farmer) (*models.FarmerWithCrops, error) {
+	crops, err := s.profiles.GetFarmerCrops(farmer.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.FarmerWithCrops{Farmer: farmer, Crops: crops}, nil
+}
+
+// farmerCropNames merges the legacy single crop type with the crops list, skipping blanks and
+// repeated names; AddCropsToFarmer also skips names resolving to the same crop
+func farmerCropNames(cropType string, crops []string) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, name := range append([]string{cropType}, crops...) {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, name)
+	}
+	return names
+}
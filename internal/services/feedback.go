@@ -0,0 +1,64 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// FeedbackService handles feedback farmers send through the WhatsApp bot
+type FeedbackService struct {
+	store *repository.Store
+}
+
+// NewFeedbackService creates a new feedback service
+func NewFeedbackService(store *repository.Store) *FeedbackService {
+	return &FeedbackService{store: store}
+}
+
+// SaveFeedback stores bot feedback, linking it to the farmer registered with the same phone number
+func (s *FeedbackService) SaveFeedback(phoneNumber, message, aiResponse string) error {
+	feedback := models.FarmerFeedback{
+		ID:          uuid.New(),
+		PhoneNumber: phoneNumber,
+		Message:     message,
+		CreatedAt:   time.Now(),
+	}
+	if aiResponse != "" {
+		feedback.AIResponse = &aiResponse
+	}
+
+	farmer, err := findFarmerByPhone(s.store, phoneNumber)
+	switch {
+	case err == nil:
+		feedback.FarmerID = &farmer.ID
+	case err != ErrFarmerNotFound:
+		return err
+	}
+
+	_, err = s.store.Feedback.Create(feedback)
+	return err
+}
+
+// RecentFarmerFeedback returns the latest feedback a farmer sent, newest first
+func (s *FeedbackService) RecentFarmerFeedback(farmerID int64, limit int) ([]models.FarmerFeedback, error) {
+	return s.store.Feedback.RecentByFarmer(farmerID, limit)
+}
+
+// phoneNumberVariants returns the stored forms a phone number may take ("+234..." and "234...")
+func phoneNumberVariants(phoneNumber string) []string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phoneNumber)
+
+	if digits == "" {
+		return nil
+	}
+	return []string{"+" + digits, digits}
+}
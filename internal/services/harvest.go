@@ -0,0 +1,255 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// HarvestFilter narrows down harvest listings and reports
+type HarvestFilter = repository.HarvestFilter
+
+// Harvest report groupings
+const (
+	HarvestGroupBySeason   = "season"
+	HarvestGroupByLocation = "location"
+)
+
+// HarvestService handles farm harvest recording and reporting
+type HarvestService struct {
+	store    *repository.Store
+	profiles *ProfileService
+}
+
+// NewHarvestService creates a new harvest service
+func NewHarvestService(store *repository.Store) *HarvestService {
+	return &HarvestService{store: store, profiles: NewProfileService(store)}
+}
+
+// RecordHarvest records a harvest against the authenticated user's profile
+func (s *HarvestService) RecordHarvest(authUserID string, req models.CreateFarmHarvestRequest) (*models.FarmHarvest, error) {
+	if req.CropID == nil {
+		return nil, ErrHarvestCropRequired
+	}
+	if req.Quantity == nil || *req.Quantity <= 0 {
+		return nil, ErrHarvestQuantityInvalid
+	}
+
+	profile, err := s.profiles.GetUserProfile(authUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	harvestedAt := time.Now()
+	if req.HarvestedAt != nil {
+		harvestedAt = *req.HarvestedAt
+	}
+
+	harvest := models.FarmHarvest{
+		ID:            uuid.New(),
+		UserProfileID: &profile.ID, // Always the caller's own profile
+		CropID:        req.CropID,
+		Quantity:      req.Quantity,
+		HarvestedAt:   &harvestedAt,
+	}
+
+	created, err := s.store.Harvests.Create(harvest)
+	if err == repository.ErrInsertFailed {
+		return nil, ErrHarvestCreationFailed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// ListUserHarvests returns a page of the authenticated user's harvests and the total count
+func (s *HarvestService) ListUserHarvests(authUserID string, filter HarvestFilter, page, perPage int) ([]models.FarmHarvestWithDetails, int64, error) {
+	profile, err := s.profiles.GetUserProfile(authUserID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return s.store.Harvests.ListByProfile(profile.ID, filter, page, perPage)
+}
+
+// RecentFarmerHarvests returns the latest harvests recorded by the farmer's linked user profile
+func (s *HarvestService) RecentFarmerHarvests(farmer models.Farmer, limit int) ([]models.FarmHarvestWithDetails, error) {
+	if farmer.AuthUserID == nil {
+		return []models.FarmHarvestWithDetails{}, nil
+	}
+
+	profile, err := s.profiles.GetUserProfile(farmer.AuthUserID.String())
+	if err == ErrProfileNotFound {
+		return []models.FarmHarvestWithDetails{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.store.Harvests.RecentByProfile(profile.ID, limit)
+}
+
+// HarvestReport aggregates harvest quantities per crop, grouped by season or by farmer location.
+// A non-nil locationID limits it to the harvests of farmers in that location.
+func (s *HarvestService) HarvestReport(groupBy string, locationID *int64, filter HarvestFilter) ([]models.HarvestReportEntry, error) {
+	if groupBy != HarvestGroupBySeason && groupBy != HarvestGroupByLocation {
+		return nil, ErrHarvestGroupByInvalid
+	}
+
+	var harvests []models.FarmHarvestWithDetails
+	var err error
+	if locationID != nil {
+		harvests, err = s.store.Harvests.ListByLocation(*locationID, filter)
+	} else {
+		harvests, err = s.store.Harvests.List(filter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var locations map[uuid.UUID]int64
+	if groupBy == HarvestGroupByLocation {
+		locations, err = s.profileLocations(harvests)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	entries := map[string]*models.HarvestReportEntry{}
+	for _, harvest := range harvests {
+		if harvest.CropID == nil || harvest.Quantity == nil {
+			continue
+		}
+
+		entry := models.HarvestReportEntry{CropID: *harvest.CropID}
+		if harvest.Crop != nil {
+			entry.CropName = harvest.Crop.Name
+		}
+
+		var key string
+		switch groupBy {
+		case HarvestGroupBySeason:
+			if harvest.HarvestedAt == nil {
+				continue
+			}
+			entry.Season = HarvestSeason(*harvest.HarvestedAt)
+			key = entry.CropID.String() + "|" + entry.Season
+		case HarvestGroupByLocation:
+			if harvest.UserProfileID == nil {
+				continue
+			}
+			locationID, ok := locations[*harvest.UserProfileID]
+			if !ok {
+				continue
+			}
+			entry.LocationID = &locationID
+			key = fmt.Sprintf("%s|%d", entry.CropID, locationID)
+		}
+
+		existing, ok := entries[key]
+		if !ok {
+			existing = &entry
+			entries[key] = existing
+		}
+		existing.TotalQuantity += *harvest.Quantity
+		existing.HarvestCount++
+	}
+
+	report := make([]models.HarvestReportEntry, 0, len(entries))
+	for _, entry := range entries {
+		report = append(report, *entry)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].CropName != report[j].CropName {
+			return report[i].CropName < report[j].CropName
+		}
+		if report[i].Season != report[j].Season {
+			return report[i].Season < report[j].Season
+		}
+		return derefInt64(report[i].LocationID) < derefInt64(report[j].LocationID)
+	})
+
+	return report, nil
+}
+
+// profileLocations maps user profile IDs to the location of the farmer linked to the same auth user
+func (s *HarvestService) profileLocations(harvests []models.FarmHarvestWithDetails) (map[uuid.UUID]int64, error) {
+	seen := map[uuid.UUID]bool{}
+	var profileIDs []uuid.UUID
+	for _, harvest := range harvests {
+		if harvest.UserProfileID == nil || seen[*harvest.UserProfileID] {
+			continue
+		}
+		seen[*harvest.UserProfileID] = true
+		profileIDs = append(profileIDs, *harvest.UserProfileID)
+	}
+
+	locations := map[uuid.UUID]int64{}
+	if len(profileIDs) == 0 {
+		return locations, nil
+	}
+
+	profiles, err := s.store.Profiles.ListByIDs(profileIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	profileByAuthUser := map[uuid.UUID]uuid.UUID{}
+	var authUserIDs []uuid.UUID
+	for _, profile := range profiles {
+		if profile.AuthUserID == nil {
+			continue
+		}
+		profileByAuthUser[*profile.AuthUserID] = profile.ID
+		authUserIDs = append(authUserIDs, *profile.AuthUserID)
+	}
+
+	if len(authUserIDs) == 0 {
+		return locations, nil
+	}
+
+	farmers, err := s.store.Farmers.ListByAuthUserIDs(authUserIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, farmer := range farmers {
+		if farmer.AuthUserID == nil {
+			continue
+		}
+		if profileID, ok := profileByAuthUser[*farmer.AuthUserID]; ok {
+			locations[profileID] = farmer.LocationID
+		}
+	}
+
+	return locations, nil
+}
+
+// HarvestSeason labels a harvest date with its growing season.
+// The rainy season runs April to October; the dry season runs November to March
+// and is labelled with both years it spans.
+func HarvestSeason(t time.Time) string {
+	year := t.Year()
+	switch month := t.Month(); {
+	case month >= time.April && month <= time.October:
+		return fmt.Sprintf("%d rainy", year)
+	case month >= time.November:
+		return fmt.Sprintf("%d/%d dry", year, year+1)
+	default:
+		return fmt.Sprintf("%d/%d dry", year-1, year)
+	}
+}
+
+// derefInt64 returns the value of an optional int64, or zero
+func derefInt64(value *int64) int64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/okoye-dev/flux-server/internal/identity"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// SignInProvider signs users in with their account email and password
+type SignInProvider interface {
+	SignIn(email, password string) (*AuthSession, error)
+}
+
+// IdentityService resolves the username, email address or phone number a user signs in
+// with to their account. Profiles record each account's sign-in email; profiles from before
+// that was recorded fall back to the synthetic emails of the current and legacy domains,
+// and get their email recorded at the next successful sign-in.
+type IdentityService struct {
+	store  *repository.Store
+	auth   SignInProvider
+	domain *identity.Domain
+}
+
+// NewIdentityService creates a new identity service. auth is only used to sign in and may be nil otherwise.
+func NewIdentityService(store *repository.Store, auth SignInProvider, domain *identity.Domain) *IdentityService {
+	return &IdentityService{store: store, auth: auth, domain: domain}
+}
+
+// SignupEmail returns the email to create an account with: the user's own address when
+// they gave one, otherwise a synthetic one for their username. Usernames must be unused,
+// and can't be mistaken for an email address or phone number.
+func (s *IdentityService) SignupEmail(username, email string) (string, error) {
+	if identity.ValidUsername(username) != nil {
+		return "", ErrUsernameInvalid
+	}
+	if _, err := s.store.Profiles.FindByDisplayName(username); err == nil {
+		return "", ErrUsernameTaken
+	} else if err != repository.ErrNotFound {
+		return "", err
+	}
+
+	if email == "" {
+		return s.domain.Email(username), nil
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	// Real addresses can't use the synthetic domain, or they could take another user's username
+	if _, synthetic := s.domain.Username(email); synthetic || !identity.ValidEmail(email) {
+		return "", ErrEmailInvalid
+	}
+	return email, nil
+}
+
+// FindProfile returns the profile of the user a login identifier belongs to
+func (s *IdentityService) FindProfile(identifier string) (*models.UserProfile, error) {
+	id, err := identity.Parse(identifier)
+	if err != nil {
+		return nil, ErrIdentifierInvalid
+	}
+
+	profile, err := s.findProfile(id)
+	if err == repository.ErrNotFound {
+		return nil, ErrProfileNotFound
+	}
+	return profile, err
+}
+
+// findProfile looks a profile up by the kind of identifier
+func (s *IdentityService) findProfile(id identity.Identifier) (*models.UserProfile, error) {
+	switch id.Kind {
+	case identity.KindEmail:
+		profile, err := s.store.Profiles.FindByEmail(id.Value)
+		// Profiles without a recorded email are still found by their synthetic one
+		if username, synthetic := s.domain.Username(id.Value); err == repository.ErrNotFound && synthetic {
+			return s.store.Profiles.FindByDisplayName(username)
+		}
+		return profile, err
+	case identity.KindPhone:
+		return s.store.Profiles.FindByPhone(phoneNumberVariants(id.Value))
+	default:
+		return s.store.Profiles.FindByDisplayName(id.Value)
+	}
+}
+
+// SignIn signs a user in with their username, email address or phone number and password.
+// Failures are reported as ErrSignInFailed, whether or not the account exists.
+func (s *IdentityService) SignIn(identifier, password string) (*AuthSession, error) {
+	id, err := identity.Parse(identifier)
+	if err != nil {
+		return nil, ErrIdentifierInvalid
+	}
+
+	profile, err := s.findProfile(id)
+	if err != nil && err != repository.ErrNotFound {
+		return nil, err
+	}
+	if err == repository.ErrNotFound {
+		profile = nil
+	}
+
+	switch {
+	case profile != nil:
+		return s.signInProfile(profile, password)
+	case id.Kind == identity.KindEmail:
+		return s.signIn(nil, []string{id.Value}, password)
+	case id.Kind == identity.KindUsername:
+		// Accounts whose profile is still missing can sign in by username too
+		return s.signIn(nil, s.syntheticEmails(id.Value), password)
+	default:
+		return nil, fmt.Errorf("%w: no account with this phone number", ErrSignInFailed)
+	}
+}
+
+// signInProfile signs in to a profile's account with its recorded email, or with its
+// synthetic emails when none is recorded
+func (s *IdentityService) signInProfile(profile *models.UserProfile, password string) (*AuthSession, error) {
+	if profile.Email != nil && *profile.Email != "" {
+		return s.signIn(profile, []string{*profile.Email}, password)
+	}
+	if profile.DisplayName == nil || *profile.DisplayName == "" {
+		return nil, fmt.Errorf("%w: the account has no sign-in email", ErrSignInFailed)
+	}
+	return s.signIn(profile, s.syntheticEmails(*profile.DisplayName), password)
+}
+
+// signIn tries each email in turn and records the one that worked on a profile without one
+func (s *IdentityService) signIn(profile *models.UserProfile, emails []string, password string) (*AuthSession, error) {
+	var lastErr error
+	for _, email := range emails {
+		session, err := s.auth.SignIn(email, password)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if profile != nil && profile.Email == nil && profile.AuthUserID != nil && *profile.AuthUserID == session.User.ID {
+			if err := s.store.Profiles.SetEmail(profile.ID, email); err != nil {
+				slog.Warn("Failed to record sign-in email", "profile_id", profile.ID, "error", err)
+			}
+		}
+		return session, nil
+	}
+	return nil, fmt.Errorf("%w: %v", ErrSignInFailed, lastErr)
+}
+
+// syntheticEmails lists the emails a username's account may have been created with, current domain first
+func (s *IdentityService) syntheticEmails(username string) []string {
+	return append([]string{s.domain.Email(username)}, s.domain.LegacyEmails(username)...)
+}
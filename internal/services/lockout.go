@@ -0,0 +1,187 @@
+package services
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/identity"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// Sign-in lockout limits
+const (
+	// lockoutAccountThreshold is how many failed sign-ins lock an account
+	lockoutAccountThreshold = 5
+	// lockoutIPThreshold is higher, since several users may share an address behind NAT
+	lockoutIPThreshold = 20
+	// lockoutBaseDuration is the first lock; each further failure doubles it up to lockoutMaxDuration
+	lockoutBaseDuration = time.Minute
+	lockoutMaxDuration  = time.Hour
+	// lockoutWindow is how long failures are remembered after the last one
+	lockoutWindow = 24 * time.Hour
+)
+
+// Audited sign-in lockout actions
+const (
+	AuditSignInLocked   = "signin.locked"
+	AuditSignInUnlocked = "signin.unlocked"
+)
+
+// Prefixes of login attempt keys
+const (
+	lockoutAccountKey = "account:" // Followed by the auth user ID
+	lockoutLoginKey   = "login:"   // Followed by the identifier, for logins that match no profile
+	lockoutIPKey      = "ip:"
+)
+
+// LockoutService slows down password guessing. Failed sign-ins are counted per account and
+// per IP address; past a threshold the account or address is locked out, for twice as long
+// with every further failure. Lockouts and unlocks are audited.
+type LockoutService struct {
+	store      *repository.Store
+	identities *IdentityService
+	audit      *AuditService
+}
+
+// NewLockoutService creates a new lockout service; identities resolve logins to accounts,
+// so a username, email address and phone number of one user share a counter
+func NewLockoutService(store *repository.Store, identities *IdentityService) *LockoutService {
+	return &LockoutService{
+		store:      store,
+		identities: identities,
+		audit:      NewAuditService(store),
+	}
+}
+
+// Check returns ErrSignInLocked, and how long until the lock ends, while the account of the
+// identifier or the IP address is locked out
+func (s *LockoutService) Check(identifier, ip string) (time.Duration, error) {
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range s.keys(identifier, ip) {
+		attempt, err := s.store.Logins.Get(key.key)
+		if err == repository.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			wait = max(wait, attempt.LockedUntil.Sub(now))
+		}
+	}
+	if wait > 0 {
+		return wait, ErrSignInLocked
+	}
+	return 0, nil
+}
+
+// RecordFailure counts a failed sign-in against the account and the IP address, and locks
+// out whichever reached its threshold. Failures to count are logged, not returned.
+func (s *LockoutService) RecordFailure(identifier string, actx AuditContext) {
+	now := time.Now()
+	for _, key := range s.keys(identifier, actx.IPAddress) {
+		attempt, err := s.store.Logins.RecordFailure(key.key, now, now.Add(-lockoutWindow))
+		if err != nil {
+			slog.Warn("Failed to count failed sign-in", "key", key.key, "error", err)
+			continue
+		}
+		if attempt.Failures < key.threshold {
+			continue
+		}
+
+		until := now.Add(lockoutDuration(attempt.Failures - key.threshold))
+		if err := s.store.Logins.Lock(key.key, until); err != nil {
+			slog.Error("Failed to lock out", "key", key.key, "error", err)
+			continue
+		}
+		s.audit.Record(actx, AuditSignInLocked, key.userID, map[string]any{
+			"key":          key.auditKey,
+			"identifier":   identity.Mask(identifier),
+			"failures":     attempt.Failures,
+			"locked_until": until,
+		})
+	}
+}
+
+// RecordSuccess forgets the account's failed sign-ins. The IP address's are left to
+// expire, so signing in to one account doesn't reset guessing at others.
+func (s *LockoutService) RecordSuccess(identifier string) {
+	for _, key := range s.keys(identifier, "") {
+		if err := s.store.Logins.Delete(key.key); err != nil {
+			slog.Warn("Failed to clear failed sign-ins", "key", key.key, "error", err)
+		}
+	}
+}
+
+// Unlock clears the failed sign-ins and any lock of the account of the identifier and of
+// the IP address; either may be empty, but not both
+func (s *LockoutService) Unlock(identifier, ip string, actx AuditContext) error {
+	keys := s.keys(identifier, ip)
+	if len(keys) == 0 {
+		return ErrUnlockTargetRequired
+	}
+
+	for _, key := range keys {
+		if err := s.store.Logins.Delete(key.key); err != nil {
+			return err
+		}
+		s.audit.Record(actx, AuditSignInUnlocked, key.userID, map[string]any{"key": key.auditKey})
+	}
+	return nil
+}
+
+// ListLocked returns the accounts, unknown logins and IP addresses locked out now, latest lock first
+func (s *LockoutService) ListLocked() ([]models.LoginAttempt, error) {
+	return s.store.Logins.ListLocked(time.Now())
+}
+
+// lockoutKey is a counter a sign-in is checked against
+type lockoutKey struct {
+	key       string
+	threshold int
+	userID    *uuid.UUID // The account's auth user, for the audit log
+	auditKey  string     // The key with any login masked, for the audit log
+}
+
+// keys returns the counters of the identifier's account and of the IP address, skipping empty ones
+func (s *LockoutService) keys(identifier, ip string) []lockoutKey {
+	var keys []lockoutKey
+	if identifier = strings.TrimSpace(identifier); identifier != "" {
+		keys = append(keys, s.accountKey(identifier))
+	}
+	if ip != "" {
+		keys = append(keys, lockoutKey{key: lockoutIPKey + ip, threshold: lockoutIPThreshold, auditKey: lockoutIPKey + ip})
+	}
+	return keys
+}
+
+// accountKey keys a login by its account when it matches a profile, and otherwise by the
+// normalised identifier, so guessing at accounts without a profile is limited too
+func (s *LockoutService) accountKey(identifier string) lockoutKey {
+	profile, err := s.identities.FindProfile(identifier)
+	if err == nil && profile.AuthUserID != nil {
+		key := lockoutAccountKey + profile.AuthUserID.String()
+		return lockoutKey{key: key, threshold: lockoutAccountThreshold, userID: profile.AuthUserID, auditKey: key}
+	}
+	if err != nil && err != ErrProfileNotFound && err != ErrIdentifierInvalid {
+		slog.Warn("Failed to look up the account of a sign-in", "error", err)
+	}
+
+	login := strings.ToLower(identifier)
+	if id, err := identity.Parse(identifier); err == nil && id.Kind == identity.KindPhone {
+		login = phoneNumberVariants(id.Value)[0]
+	}
+	return lockoutKey{key: lockoutLoginKey + login, threshold: lockoutAccountThreshold, auditKey: lockoutLoginKey + identity.Mask(login)}
+}
+
+// lockoutDuration doubles lockoutBaseDuration for each failure past the threshold
+func lockoutDuration(pastThreshold int) time.Duration {
+	if pastThreshold >= 16 {
+		return lockoutMaxDuration
+	}
+	return min(lockoutBaseDuration<<pastThreshold, lockoutMaxDuration)
+}
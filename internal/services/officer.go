@@ -0,0 +1,173 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// recentActivityLimit is how many harvests and feedback items an officer sees per farmer
+const recentActivityLimit = 5
+
+// OfficerService handles the extension officer workspace.
+// Every farmer lookup is scoped to the officer's assigned location.
+type OfficerService struct {
+	store    *repository.Store
+	farmers  *FarmerService
+	harvests *HarvestService
+	feedback *FeedbackService
+}
+
+// NewOfficerService creates a new officer service
+func NewOfficerService(store *repository.Store) *OfficerService {
+	return &OfficerService{
+		store:    store,
+		farmers:  NewFarmerService(store),
+		harvests: NewHarvestService(store),
+		feedback: NewFeedbackService(store),
+	}
+}
+
+// GetOfficerByAuthUserID retrieves the extension officer linked to an auth user
+func (s *OfficerService) GetOfficerByAuthUserID(authUserID string) (*models.ExtensionOfficer, error) {
+	officer, err := s.store.Officers.GetByAuthUserID(authUserID)
+	if err == repository.ErrNotFound {
+		return nil, ErrOfficerNotFound
+	}
+	return officer, err
+}
+
+// ListAssignedFarmers returns a page of farmers in the officer's location with their recent activity
+func (s *OfficerService) ListAssignedFarmers(officer *models.ExtensionOfficer, page, perPage int) ([]models.OfficerFarmerSummary, int64, error) {
+	if officer.AssignedLocationID == nil {
+		return nil, 0, ErrOfficerLocationUnassigned
+	}
+
+	farmers, total, err := s.store.Farmers.ListByLocation(*officer.AssignedLocationID, page, perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	summaries := make([]models.OfficerFarmerSummary, 0, len(farmers))
+	for _, farmer := range farmers {
+		summary, err := s.summarize(farmer)
+		if err != nil {
+			return nil, 0, err
+		}
+		summaries = append(summaries, *summary)
+	}
+
+	return summaries, total, nil
+}
+
+// GetAssignedFarmer returns one farmer in the officer's location, including visit notes
+func (s *OfficerService) GetAssignedFarmer(officer *models.ExtensionOfficer, farmerID int64) (*models.OfficerFarmerSummary, error) {
+	farmer, err := s.assignedFarmer(officer, farmerID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := s.summarize(*farmer)
+	if err != nil {
+		return nil, err
+	}
+
+	summary.VisitNotes, err = s.visitNotes(farmerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// ListVisitNotes returns the visit notes recorded for a farmer in the officer's location
+func (s *OfficerService) ListVisitNotes(officer *models.ExtensionOfficer, farmerID int64) ([]models.FarmerVisitNote, error) {
+	if _, err := s.assignedFarmer(officer, farmerID); err != nil {
+		return nil, err
+	}
+
+	return s.visitNotes(farmerID)
+}
+
+// AddVisitNote annotates a farmer in the officer's location with a visit note
+func (s *OfficerService) AddVisitNote(officer *models.ExtensionOfficer, farmerID int64, req models.CreateVisitNoteRequest) (*models.FarmerVisitNote, error) {
+	if strings.TrimSpace(req.Note) == "" {
+		return nil, ErrVisitNoteRequired
+	}
+
+	if _, err := s.assignedFarmer(officer, farmerID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	visitedAt := now
+	if req.VisitedAt != nil {
+		visitedAt = *req.VisitedAt
+	}
+
+	note := models.FarmerVisitNote{
+		ID:        uuid.New(),
+		FarmerID:  farmerID,
+		OfficerID: officer.ID,
+		Note:      strings.TrimSpace(req.Note),
+		VisitedAt: visitedAt,
+		CreatedAt: now,
+	}
+
+	created, err := s.store.VisitNotes.Create(note)
+	if err == repository.ErrInsertFailed {
+		return nil, fmt.Errorf("failed to save visit note for farmer %d", farmerID)
+	}
+	return created, err
+}
+
+// assignedFarmer loads a farmer and checks it belongs to the officer's location
+func (s *OfficerService) assignedFarmer(officer *models.ExtensionOfficer, farmerID int64) (*models.Farmer, error) {
+	if officer.AssignedLocationID == nil {
+		return nil, ErrOfficerLocationUnassigned
+	}
+
+	farmer, err := s.farmers.getFarmer(farmerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if farmer.LocationID != *officer.AssignedLocationID {
+		return nil, ErrFarmerOutsideLocation
+	}
+
+	return farmer, nil
+}
+
+// summarize attaches crops, recent harvests and recent feedback to a farmer
+func (s *OfficerService) summarize(farmer models.Farmer) (*models.OfficerFarmerSummary, error) {
+	withCrops, err := s.farmers.withCrops(farmer)
+	if err != nil {
+		return nil, err
+	}
+
+	harvests, err := s.harvests.RecentFarmerHarvests(farmer, recentActivityLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	feedback, err := s.feedback.RecentFarmerFeedback(farmer.ID, recentActivityLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OfficerFarmerSummary{
+		FarmerWithCrops: *withCrops,
+		RecentHarvests:  harvests,
+		RecentFeedback:  feedback,
+	}, nil
+}
+
+// visitNotes loads a farmer's visit notes, newest first
+func (s *OfficerService) visitNotes(farmerID int64) ([]models.FarmerVisitNote, error) {
+	return s.store.VisitNotes.ListByFarmer(farmerID)
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/logging"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// One-time code limits
+const (
+	otpDigits         = 6
+	otpTTL            = 5 * time.Minute
+	otpMaxAttempts    = 5
+	otpResendInterval = time.Minute
+	// otpSessionTTL is how long an access token issued for a code stays valid; there is
+	// no refresh token, so the farmer requests a new code once it expires
+	otpSessionTTL = time.Hour
+)
+
+// PhoneAuthProvider creates, reads and removes accounts for farmers who sign in with their phone number
+type PhoneAuthProvider interface {
+	CreatePhoneUser(phone string, metadata map[string]any) (*models.AuthUser, error)
+	GetUser(userID uuid.UUID) (*models.AuthUser, error)
+	DeleteUser(userID uuid.UUID) error
+}
+
+// OTPService signs farmers in with a one-time code sent to the WhatsApp number they
+// registered with through the bot. The session is issued for the auth user linked to
+// that farmer record, so the web app and the bot share one identity.
+type OTPService struct {
+	store     *repository.Store
+	auth      PhoneAuthProvider
+	sender    MessageSender
+	jwtSecret []byte
+	issuer    string
+	audience  string
+}
+
+// NewOTPService creates a new OTP service. Codes are hashed with, and access tokens
+// signed with, the JWT secret; issuer and audience must match what the auth middleware expects.
+func NewOTPService(store *repository.Store, auth PhoneAuthProvider, sender MessageSender, jwtSecret, issuer, audience string) *OTPService {
+	return &OTPService{
+		store:     store,
+		auth:      auth,
+		sender:    sender,
+		jwtSecret: []byte(jwtSecret),
+		issuer:    issuer,
+		audience:  audience,
+	}
+}
+
+// RequestCode sends a new code to a registered farmer's WhatsApp number, replacing any pending code.
+// Like a password reset request, it succeeds without sending anything when no farmer is
+// registered with the number or a code was sent less than otpResendInterval ago, so it
+// doesn't reveal which numbers are registered.
+func (s *OTPService) RequestCode(phoneNumber string) error {
+	phone, err := normalizeOTPPhone(phoneNumber)
+	if err != nil {
+		return err
+	}
+
+	if _, err := findFarmerByPhone(s.store, phone); err != nil {
+		if err == ErrFarmerNotFound {
+			slog.Info("Sign-in code not sent", logging.PhoneKey, phone, "reason", "unregistered")
+			return nil
+		}
+		return err
+	}
+
+	now := time.Now()
+	pending, err := s.store.OTPCodes.Get(phone)
+	if err != nil && err != repository.ErrNotFound {
+		return err
+	}
+	if pending != nil && now.Sub(pending.CreatedAt) < otpResendInterval {
+		slog.Info("Sign-in code not sent", logging.PhoneKey, phone, "reason", "too_soon")
+		return nil
+	}
+
+	code, err := generateCode(otpDigits)
+	if err != nil {
+		return err
+	}
+	err = s.store.OTPCodes.Save(models.OTPCode{
+		PhoneNumber: phone,
+		CodeHash:    s.hashCode(phone, code),
+		ExpiresAt:   now.Add(otpTTL),
+		CreatedAt:   now,
+	})
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("🔐 Your Flux sign-in code is %s\n\nIt expires in %d minutes. Don't share it with anyone.", code, int(otpTTL.Minutes()))
+	if err := s.sender.SendText(phone, message); err != nil {
+		// The farmer never saw this code, so don't make them wait out the resend interval
+		if err := s.store.OTPCodes.Delete(phone); err != nil {
+			slog.Warn("Failed to delete undelivered code", logging.PhoneKey, phone, "error", err)
+		}
+		return fmt.Errorf("%w: %v", ErrOTPDeliveryFailed, err)
+	}
+	return nil
+}
+
+// VerifyCode checks a code and, when it matches, signs the farmer in. Codes are single use
+// and are discarded after otpMaxAttempts wrong guesses. A farmer without an account yet
+// gets one, created for their phone number and linked to their farmer record.
+func (s *OTPService) VerifyCode(phoneNumber, code string, actx AuditContext) (*AuthSession, error) {
+	phone, err := normalizeOTPPhone(phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.store.OTPCodes.Get(phone)
+	if err == repository.ErrNotFound {
+		return nil, ErrOTPInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(pending.ExpiresAt) {
+		s.discardCode(phone)
+		return nil, ErrOTPInvalid
+	}
+	if pending.Attempts >= otpMaxAttempts {
+		s.discardCode(phone)
+		return nil, ErrOTPAttemptsExceeded
+	}
+
+	// Count the attempt before comparing, so concurrent guesses can't exceed the limit
+	attempts, err := s.store.OTPCodes.RecordAttempt(phone)
+	if err == repository.ErrNotFound {
+		return nil, ErrOTPInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(s.hashCode(phone, code)), []byte(pending.CodeHash)) {
+		if attempts >= otpMaxAttempts {
+			s.discardCode(phone)
+			return nil, ErrOTPAttemptsExceeded
+		}
+		return nil, ErrOTPInvalid
+	}
+	s.discardCode(phone)
+
+	farmer, err := findFarmerByPhone(s.store, phone)
+	if err == ErrFarmerNotFound {
+		return nil, ErrOTPPhoneNotRegistered
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Supabase refuses banned accounts, but these sessions are issued here
+	if farmer.AuthUserID != nil {
+		profile, err := s.store.Profiles.GetByAuthUserID(farmer.AuthUserID.String())
+		if err != nil && err != repository.ErrNotFound {
+			return nil, err
+		}
+		if profile != nil && profile.DisabledAt != nil {
+			return nil, ErrAccountDisabled
+		}
+	}
+
+	user, err := s.farmerAccount(farmer, phone, actx)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueSession(*user, farmer.Name, phone)
+}
+
+// farmerAccount returns the auth user linked to a farmer, creating and linking one
+// for farmers who only registered through the bot. A linked account only gets a session
+// when its confirmed phone is the number the code went to, so a farmer record whose
+// number was changed can't sign anyone into the account. The account is deleted again
+// when linking fails; should that fail too, the reconciler links it using its metadata.
+func (s *OTPService) farmerAccount(farmer *models.Farmer, phone string, actx AuditContext) (*models.AuthUser, error) {
+	if farmer.AuthUserID != nil {
+		user, err := s.auth.GetUser(*farmer.AuthUserID)
+		if err != nil {
+			if err == ErrServiceRoleKeyMissing {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", ErrOTPAccountFailed, err)
+		}
+		if !confirmedPhoneMatches(user, phone) {
+			slog.Warn("Farmer's phone number is not the confirmed phone of their account", "farmer_id", farmer.ID, "user_id", user.ID)
+			return nil, ErrOTPAccountMismatch
+		}
+		return user, nil
+	}
+
+	user, err := s.auth.CreatePhoneUser(phone, otpAccountMetadata(farmer))
+	if err != nil {
+		if err == ErrServiceRoleKeyMissing {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrOTPAccountFailed, err)
+	}
+
+	if _, err := NewProfileService(s.store).LinkFarmerAccount(farmer.ID, user.ID, farmer.Name, phone, actx); err != nil {
+		if deleteErr := s.auth.DeleteUser(user.ID); deleteErr != nil {
+			slog.Error("Failed to delete auth user after linking farmer failed", "user_id", user.ID, "farmer_id", farmer.ID, "error", deleteErr)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrOTPAccountFailed, err)
+	}
+	return user, nil
+}
+
+// issueSession signs an access token the auth middleware accepts like one issued by
+// Supabase Auth, with a new session ID so it can be signed out like any other session
+func (s *OTPService) issueSession(user models.AuthUser, username, phone string) (*AuthSession, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":        user.ID.String(),
+		"aud":        s.audience,
+		"role":       "authenticated",
+		"iss":        s.issuer,
+		"iat":        now.Unix(),
+		"exp":        now.Add(otpSessionTTL).Unix(),
+		"phone":      phone,
+		"session_id": uuid.New().String(),
+	})
+	accessToken, err := token.SignedString(s.jwtSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.UserMetadata == nil {
+		user.UserMetadata = map[string]any{"username": username}
+	}
+	return &AuthSession{
+		User:        user,
+		AccessToken: accessToken,
+		TokenType:   "bearer",
+		ExpiresIn:   int(otpSessionTTL.Seconds()),
+	}, nil
+}
+
+// hashCode keys the stored hash by phone number as well, so equal codes don't share a hash
+func (s *OTPService) hashCode(phone, code string) string {
+	mac := hmac.New(sha256.New, s.jwtSecret)
+	mac.Write([]byte(phone + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// discardCode deletes a used or exhausted code; a failure only delays its expiry
+func (s *OTPService) discardCode(phone string) {
+	if err := s.store.OTPCodes.Delete(phone); err != nil {
+		slog.Warn("Failed to delete one-time code", logging.PhoneKey, phone, "error", err)
+	}
+}
+
+// confirmedPhoneMatches reports whether an account has confirmed the given phone number
+func confirmedPhoneMatches(user *models.AuthUser, phone string) bool {
+	return user.Phone != nil && user.PhoneConfirmedAt != nil && samePhoneNumber(*user.Phone, phone)
+}
+
+// normalizeOTPPhone returns the "+digits" form codes are stored under
+func normalizeOTPPhone(phoneNumber string) (string, error) {
+	variants := phoneNumberVariants(phoneNumber)
+	if variants == nil || len(variants[1]) < 7 || len(variants[1]) > 15 {
+		return "", ErrOTPPhoneInvalid
+	}
+	return variants[0], nil
+}
+
+// generateCode returns a random numeric code of the given number of digits
+func generateCode(digits int) (string, error) {
+	limit := big.NewInt(1)
+	for range digits {
+		limit.Mul(limit, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
+
+// otpAccountMetadata is stored on accounts created for bot-registered farmers; the
+// reconciler reads farmer_id back to link an account whose linking failed
+func otpAccountMetadata(farmer *models.Farmer) map[string]any {
+	return map[string]any{
+		"username":     farmer.Name,
+		"role":         "farmer",
+		"phone_number": farmer.PhoneNumber,
+		"farmer_id":    farmer.ID,
+	}
+}
+
+// farmerIDFromMetadata reads back the farmer_id otpAccountMetadata stored
+func farmerIDFromMetadata(metadata map[string]any) (int64, bool) {
+	switch id := metadata["farmer_id"].(type) {
+	case float64:
+		return int64(id), true
+	case int64:
+		return id, true
+	default:
+		return 0, false
+	}
+}
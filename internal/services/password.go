@@ -0,0 +1,249 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/identity"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// minPasswordLength matches the minimum Supabase Auth enforces by default
+const minPasswordLength = 6
+
+// Password reset limits
+const (
+	resetDigits         = 8
+	resetTTL            = 15 * time.Minute
+	resetMaxAttempts    = 5
+	resetResendInterval = time.Minute
+)
+
+// PasswordAuthProvider checks and sets passwords in the auth provider
+type PasswordAuthProvider interface {
+	SignIn(email, password string) (*AuthSession, error)
+	UpdatePassword(accessToken, newPassword string) error
+	SetPassword(userID uuid.UUID, newPassword string) error
+	SignOut(accessToken string, everywhere bool) error
+}
+
+// PasswordService changes passwords and resets forgotten ones. Most account emails are
+// synthetic, so reset codes are sent over WhatsApp to the phone on the user's profile
+// instead of by email. Every change, reset and failed attempt is audited.
+type PasswordService struct {
+	store      *repository.Store
+	auth       PasswordAuthProvider
+	identities *IdentityService
+	sender     MessageSender
+	sessions   *SessionService
+	audit      *AuditService
+	secret     []byte
+}
+
+// NewPasswordService creates a new password service. The identities, which find the user
+// a reset is for, the sender and the secret, which reset codes are hashed with, are only
+// used by resets and may be empty otherwise.
+func NewPasswordService(store *repository.Store, auth PasswordAuthProvider, identities *IdentityService, sender MessageSender, sessions *SessionService, secret string) *PasswordService {
+	return &PasswordService{
+		store:      store,
+		auth:       auth,
+		identities: identities,
+		sender:     sender,
+		sessions:   sessions,
+		audit:      NewAuditService(store),
+		secret:     []byte(secret),
+	}
+}
+
+// ChangePassword sets a new password for a signed-in user after checking their current one.
+// Their other sessions stay signed in; /auth/signout-all ends them.
+func (s *PasswordService) ChangePassword(authUserID uuid.UUID, email, currentPassword, newPassword string, actx AuditContext) error {
+	if len(newPassword) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+	if email == "" {
+		return ErrPasswordNotSet
+	}
+
+	// Signing in is the only way to check a password; the session is only used to set the new one
+	session, err := s.auth.SignIn(email, currentPassword)
+	if err != nil || session.User.ID != authUserID {
+		s.audit.Record(actx, AuditPasswordChangeFailed, &authUserID, map[string]any{"reason": "incorrect_password"})
+		return ErrPasswordIncorrect
+	}
+
+	err = s.auth.UpdatePassword(session.AccessToken, newPassword)
+	if signOutErr := s.auth.SignOut(session.AccessToken, false); signOutErr != nil {
+		slog.Warn("Failed to sign out password check session", "user_id", authUserID, "error", signOutErr)
+	}
+	if err != nil {
+		s.audit.Record(actx, AuditPasswordChangeFailed, &authUserID, map[string]any{"reason": "update_failed"})
+		return fmt.Errorf("%w: %v", ErrPasswordUpdateFailed, err)
+	}
+
+	s.audit.Record(actx, AuditPasswordChanged, &authUserID, nil)
+	return nil
+}
+
+// RequestReset sends a reset code to the WhatsApp number on the profile of the user with
+// the username, email address or phone number, replacing any pending code. To avoid
+// revealing which users exist, unknown users, users without a phone number and requests
+// within resetResendInterval of the last code succeed silently.
+func (s *PasswordService) RequestReset(identifier string, actx AuditContext) error {
+	profile, err := s.identities.FindProfile(identifier)
+	if err == ErrProfileNotFound {
+		s.audit.Record(actx, AuditPasswordResetRequested, nil, map[string]any{"identifier": identity.Mask(identifier), "sent": false, "reason": "unknown_user"})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if profile.AuthUserID == nil || profile.Phone == nil || *profile.Phone == "" {
+		s.audit.Record(actx, AuditPasswordResetRequested, profile.AuthUserID, map[string]any{"identifier": identity.Mask(identifier), "sent": false, "reason": "no_phone"})
+		return nil
+	}
+	authUserID := *profile.AuthUserID
+	phone := *profile.Phone
+
+	now := time.Now()
+	pending, err := s.store.Resets.Get(authUserID)
+	if err != nil && err != repository.ErrNotFound {
+		return err
+	}
+	if pending != nil && now.Sub(pending.CreatedAt) < resetResendInterval {
+		s.audit.Record(actx, AuditPasswordResetRequested, &authUserID, map[string]any{"identifier": identity.Mask(identifier), "sent": false, "reason": "too_soon"})
+		return nil
+	}
+
+	code, err := generateCode(resetDigits)
+	if err != nil {
+		return err
+	}
+	err = s.store.Resets.Save(models.PasswordResetToken{
+		AuthUserID: authUserID,
+		TokenHash:  s.hashToken(authUserID, code),
+		ExpiresAt:  now.Add(resetTTL),
+		CreatedAt:  now,
+	})
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("🔑 Your Flux password reset code is %s\n\nIt expires in %d minutes. If you didn't ask to reset your password, ignore this message.", code, int(resetTTL.Minutes()))
+	if err := s.sender.SendText(phone, message); err != nil {
+		s.discardToken(authUserID)
+		s.audit.Record(actx, AuditPasswordResetRequested, &authUserID, map[string]any{"identifier": identity.Mask(identifier), "sent": false, "reason": "delivery_failed"})
+		return fmt.Errorf("%w: %v", ErrPasswordResetUndelivered, err)
+	}
+
+	s.audit.Record(actx, AuditPasswordResetRequested, &authUserID, map[string]any{"identifier": identity.Mask(identifier), "sent": true})
+	return nil
+}
+
+// ConfirmReset sets a new password with a reset code for the user with the username,
+// email address or phone number. Codes are single use and are discarded after
+// resetMaxAttempts wrong guesses. Every session of the user is signed out, since
+// whoever forgot the password may not be the only one who knew it.
+func (s *PasswordService) ConfirmReset(identifier, code, newPassword string, actx AuditContext) error {
+	if len(newPassword) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	profile, err := s.identities.FindProfile(identifier)
+	if err == ErrProfileNotFound {
+		return ErrPasswordResetInvalid
+	}
+	if err != nil {
+		return err
+	}
+	if profile.AuthUserID == nil {
+		return ErrPasswordResetInvalid
+	}
+	authUserID := *profile.AuthUserID
+
+	pending, err := s.store.Resets.Get(authUserID)
+	if err == repository.ErrNotFound {
+		return ErrPasswordResetInvalid
+	}
+	if err != nil {
+		return err
+	}
+	if time.Now().After(pending.ExpiresAt) {
+		s.discardToken(authUserID)
+		return ErrPasswordResetInvalid
+	}
+	if pending.Attempts >= resetMaxAttempts {
+		s.discardToken(authUserID)
+		return ErrPasswordResetLocked
+	}
+
+	// Count the attempt before comparing, so concurrent guesses can't exceed the limit
+	attempts, err := s.store.Resets.RecordAttempt(authUserID)
+	if err == repository.ErrNotFound {
+		return ErrPasswordResetInvalid
+	}
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(s.hashToken(authUserID, code)), []byte(pending.TokenHash)) {
+		s.audit.Record(actx, AuditPasswordResetFailed, &authUserID, map[string]any{"attempts": attempts})
+		if attempts >= resetMaxAttempts {
+			s.discardToken(authUserID)
+			s.audit.Record(actx, AuditPasswordResetLocked, &authUserID, map[string]any{"attempts": attempts})
+			return ErrPasswordResetLocked
+		}
+		return ErrPasswordResetInvalid
+	}
+	s.discardToken(authUserID)
+
+	if err := s.auth.SetPassword(authUserID, newPassword); err != nil {
+		s.audit.Record(actx, AuditPasswordResetFailed, &authUserID, map[string]any{"reason": "update_failed"})
+		if err == ErrServiceRoleKeyMissing {
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrPasswordUpdateFailed, err)
+	}
+
+	s.signOutEverywhere(profile, newPassword)
+	s.audit.Record(actx, AuditPasswordResetCompleted, &authUserID, nil)
+	return nil
+}
+
+// signOutEverywhere rejects the user's existing access tokens here and revokes their
+// Supabase refresh tokens, which takes a session of the user's own to do. Failures
+// are logged; the password has been changed either way.
+func (s *PasswordService) signOutEverywhere(profile *models.UserProfile, password string) {
+	authUserID := *profile.AuthUserID
+	if err := s.sessions.RevokeAll(authUserID.String()); err != nil {
+		slog.Error("Failed to revoke sessions after a password reset", "user_id", authUserID, "error", err)
+	}
+
+	session, err := s.identities.signInProfile(profile, password)
+	if err != nil {
+		slog.Warn("Failed to sign in to revoke refresh tokens after a password reset", "user_id", authUserID, "error", err)
+		return
+	}
+	if err := s.auth.SignOut(session.AccessToken, true); err != nil {
+		slog.Warn("Failed to revoke refresh tokens after a password reset", "user_id", authUserID, "error", err)
+	}
+}
+
+// hashToken keys the stored hash by user as well, so equal codes don't share a hash
+func (s *PasswordService) hashToken(authUserID uuid.UUID, code string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(authUserID.String() + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// discardToken deletes a used or exhausted reset code; a failure only delays its expiry
+func (s *PasswordService) discardToken(authUserID uuid.UUID) {
+	if err := s.store.Resets.Delete(authUserID); err != nil {
+		slog.Warn("Failed to delete password reset code", "user_id", authUserID, "error", err)
+	}
+}
@@ -0,0 +1,352 @@
+package services
+
+import (
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// Audited data subject requests
+const (
+	AuditDataExported   = "data.exported"
+	AuditAccountDeleted = "account.deleted"
+)
+
+// exportHarvestPage is how many harvests an export reads at a time
+const exportHarvestPage = 100
+
+// ConversationStore holds the WhatsApp bot's conversation state, per phone number
+type ConversationStore interface {
+	// Conversation returns a copy of the state of the chat with a phone number, or nil
+	Conversation(phoneNumber string) map[string]any
+	// ForgetConversation drops the state of the chat with a phone number
+	ForgetConversation(phoneNumber string)
+}
+
+// AccountManager reads and removes auth accounts
+type AccountManager interface {
+	GetUser(userID uuid.UUID) (*models.AuthUser, error)
+	DeleteUser(userID uuid.UUID) error
+}
+
+// PrivacyService exports and erases what is stored about a user, for data subject
+// requests. Users are found by their account, from the web app, or by their WhatsApp
+// number, from the bot. Data kept against a phone number alone, such as bot registrations
+// and the bot's conversation, only belongs to an account through a number the account
+// confirmed by signing in over WhatsApp, since signup phone numbers aren't verified.
+type PrivacyService struct {
+	store         *repository.Store
+	auth          AccountManager
+	conversations ConversationStore // Nil when the bot isn't running
+	sessions      *SessionService
+	audit         *AuditService
+}
+
+// NewPrivacyService creates a new privacy service; conversations may be nil
+func NewPrivacyService(store *repository.Store, auth AccountManager, conversations ConversationStore, sessions *SessionService) *PrivacyService {
+	return &PrivacyService{
+		store:         store,
+		auth:          auth,
+		conversations: conversations,
+		sessions:      sessions,
+		audit:         NewAuditService(store),
+	}
+}
+
+// dataSubject is what is stored about one person
+type dataSubject struct {
+	authUserID   *uuid.UUID
+	profile      *models.UserProfile
+	officer      *models.ExtensionOfficer
+	farmers      []models.Farmer
+	phoneNumber  string   // The confirmed number, if any
+	phoneNumbers []string // Its stored forms
+}
+
+// empty reports whether nothing at all is stored about the person
+func (d *dataSubject) empty() bool {
+	return d.authUserID == nil && d.profile == nil && d.officer == nil && len(d.farmers) == 0
+}
+
+// ExportAccount returns everything stored about a signed-in user. phoneNumber is the
+// number the account confirmed over WhatsApp, or empty.
+func (s *PrivacyService) ExportAccount(authUserID, phoneNumber string, actx AuditContext) (*models.PersonalDataExport, error) {
+	userID, err := uuid.Parse(authUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := s.subject(&userID, phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	export, err := s.export(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actx, AuditDataExported, &userID, map[string]any{"channel": "web"})
+	return export, nil
+}
+
+// ExportPhoneData returns everything stored about the farmer behind a WhatsApp number, as
+// JSON, or nil when nothing is stored about the number
+func (s *PrivacyService) ExportPhoneData(phoneNumber string) ([]byte, error) {
+	subject, err := s.subject(nil, phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	export, err := s.export(subject)
+	if err != nil {
+		return nil, err
+	}
+	if subject.empty() && len(export.Feedback) == 0 && export.BotConversation == nil {
+		return nil, nil
+	}
+
+	s.audit.Record(AuditContext{ActorID: subject.authUserID}, AuditDataExported, subject.authUserID, map[string]any{"channel": "whatsapp"})
+	return json.MarshalIndent(export, "", "  ")
+}
+
+// DeleteAccount erases a signed-in user: their account, profile, harvests, farmer records
+// with their crops, feedback and visit notes, and extension officer record. phoneNumber
+// is the number the account confirmed over WhatsApp, or empty.
+func (s *PrivacyService) DeleteAccount(authUserID, phoneNumber string, actx AuditContext) error {
+	userID, err := uuid.Parse(authUserID)
+	if err != nil {
+		return err
+	}
+
+	subject, err := s.subject(&userID, phoneNumber)
+	if err != nil {
+		return err
+	}
+	return s.erase(subject, actx, "web")
+}
+
+// DeletePhoneData erases the farmer behind a WhatsApp number, along with the account the
+// number signs in to. It reports false when nothing was stored about the number.
+func (s *PrivacyService) DeletePhoneData(phoneNumber string) (bool, error) {
+	subject, err := s.subject(nil, phoneNumber)
+	if err != nil {
+		return false, err
+	}
+	if subject.empty() {
+		feedback, err := s.store.Feedback.ListByFarmerOrPhone(nil, subject.phoneNumbers)
+		if err != nil {
+			return false, err
+		}
+		if len(feedback) == 0 && s.conversation(subject) == nil {
+			return false, nil
+		}
+	}
+	return true, s.erase(subject, AuditContext{ActorID: subject.authUserID}, "whatsapp")
+}
+
+// subject gathers the records of an account and/or a confirmed phone number. Without an
+// account, the account linked to the number's farmer record is used, as WhatsApp sign-in
+// does, but only when that account confirmed the number; signup phone numbers aren't
+// verified. Farmer records linked to any other account are left out.
+func (s *PrivacyService) subject(authUserID *uuid.UUID, phoneNumber string) (*dataSubject, error) {
+	subject := &dataSubject{authUserID: authUserID, phoneNumber: phoneNumber}
+	if phoneNumber != "" {
+		subject.phoneNumbers = phoneNumberVariants(phoneNumber)
+		farmers, err := s.store.Farmers.ListByPhone(subject.phoneNumbers)
+		if err != nil {
+			return nil, err
+		}
+		if subject.authUserID == nil {
+			if subject.authUserID, err = s.confirmedAccount(farmers, phoneNumber); err != nil {
+				return nil, err
+			}
+		}
+		for _, farmer := range farmers {
+			if farmer.AuthUserID == nil || (subject.authUserID != nil && *farmer.AuthUserID == *subject.authUserID) {
+				subject.farmers = append(subject.farmers, farmer)
+			}
+		}
+	}
+	if subject.authUserID == nil {
+		return subject, nil
+	}
+
+	profile, err := s.store.Profiles.GetByAuthUserID(subject.authUserID.String())
+	if err != nil && err != repository.ErrNotFound {
+		return nil, err
+	}
+	subject.profile = profile
+
+	officer, err := s.store.Officers.GetByAuthUserID(subject.authUserID.String())
+	if err != nil && err != repository.ErrNotFound {
+		return nil, err
+	}
+	subject.officer = officer
+
+	linked, err := s.store.Farmers.ListByAuthUserIDs([]uuid.UUID{*subject.authUserID})
+	if err != nil {
+		return nil, err
+	}
+	for _, farmer := range linked {
+		if !slices.ContainsFunc(subject.farmers, func(f models.Farmer) bool { return f.ID == farmer.ID }) {
+			subject.farmers = append(subject.farmers, farmer)
+		}
+	}
+	return subject, nil
+}
+
+// confirmedAccount returns the account linked to one of the farmers that confirmed the
+// phone number, or nil when none did
+func (s *PrivacyService) confirmedAccount(farmers []models.Farmer, phoneNumber string) (*uuid.UUID, error) {
+	for _, farmer := range farmers {
+		if farmer.AuthUserID == nil {
+			continue
+		}
+		user, err := s.auth.GetUser(*farmer.AuthUserID)
+		if err != nil {
+			return nil, err
+		}
+		if confirmedPhoneMatches(user, phoneNumber) {
+			return farmer.AuthUserID, nil
+		}
+	}
+	return nil, nil
+}
+
+// export reads everything stored about a subject
+func (s *PrivacyService) export(subject *dataSubject) (*models.PersonalDataExport, error) {
+	export := &models.PersonalDataExport{
+		ExportedAt:      time.Now(),
+		Profile:         subject.profile,
+		Officer:         subject.officer,
+		Farmers:         []models.FarmerDataExport{},
+		Harvests:        []models.FarmHarvestWithDetails{},
+		BotConversation: s.conversation(subject),
+	}
+
+	farmerIDs := make([]int64, 0, len(subject.farmers))
+	for _, farmer := range subject.farmers {
+		crops, err := s.store.FarmerCrops.CropsForFarmer(farmer.ID)
+		if err != nil {
+			return nil, err
+		}
+		notes, err := s.store.VisitNotes.ListByFarmer(farmer.ID)
+		if err != nil {
+			return nil, err
+		}
+		export.Farmers = append(export.Farmers, models.FarmerDataExport{Farmer: farmer, Crops: crops, VisitNotes: notes})
+		farmerIDs = append(farmerIDs, farmer.ID)
+	}
+
+	if subject.profile != nil {
+		for page := 1; ; page++ {
+			harvests, total, err := s.store.Harvests.ListByProfile(subject.profile.ID, repository.HarvestFilter{}, page, exportHarvestPage)
+			if err != nil {
+				return nil, err
+			}
+			export.Harvests = append(export.Harvests, harvests...)
+			if len(harvests) == 0 || int64(len(export.Harvests)) >= total {
+				break
+			}
+		}
+	}
+
+	feedback, err := s.store.Feedback.ListByFarmerOrPhone(farmerIDs, subject.phoneNumbers)
+	if err != nil {
+		return nil, err
+	}
+	export.Feedback = feedback
+	if export.Feedback == nil {
+		export.Feedback = []models.FarmerFeedback{}
+	}
+	return export, nil
+}
+
+// erase deletes a subject. The account goes first and its sessions are revoked, so a
+// failure part way can't leave a signed-in user without a profile, which the reconciler
+// would recreate from the account; the records are then deleted together.
+func (s *PrivacyService) erase(subject *dataSubject, actx AuditContext, channel string) error {
+	if subject.authUserID != nil {
+		if err := s.sessions.RevokeAll(subject.authUserID.String()); err != nil {
+			return err
+		}
+		if err := s.auth.DeleteUser(*subject.authUserID); err != nil {
+			return err
+		}
+	}
+
+	err := s.store.Transact(func(tx *repository.Store) error {
+		for _, farmer := range subject.farmers {
+			// Crop links, feedback and visit notes go with the farmer
+			if err := tx.Farmers.Delete(farmer.ID); err != nil && err != repository.ErrNotFound {
+				return err
+			}
+		}
+		if err := tx.Feedback.DeleteByPhone(subject.phoneNumbers); err != nil {
+			return err
+		}
+		if subject.officer != nil {
+			if err := tx.Officers.Delete(subject.officer.ID); err != nil && err != repository.ErrNotFound {
+				return err
+			}
+		}
+		if subject.profile != nil {
+			// Harvests go with the profile
+			if err := tx.Profiles.Delete(subject.profile.ID); err != nil && err != repository.ErrNotFound {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.forgetCredentials(subject)
+	if s.conversations != nil && subject.phoneNumber != "" {
+		s.conversations.ForgetConversation(subject.phoneNumber)
+	}
+
+	// The audit log keeps the deleted account's ID, but nothing else about the user
+	s.audit.Record(actx, AuditAccountDeleted, subject.authUserID, map[string]any{
+		"channel": channel,
+		"farmers": len(subject.farmers),
+	})
+	return nil
+}
+
+// forgetCredentials drops the subject's pending codes, sign-in counters and API keys.
+// They can't be used once the account is gone, so failures are only logged.
+func (s *PrivacyService) forgetCredentials(subject *dataSubject) {
+	if len(subject.phoneNumbers) > 0 {
+		if err := s.store.OTPCodes.Delete(subject.phoneNumbers[0]); err != nil && err != repository.ErrNotFound {
+			slog.Warn("Failed to delete one-time code of a deleted user", "error", err)
+		}
+	}
+	if subject.authUserID == nil {
+		return
+	}
+
+	if err := s.store.Resets.Delete(*subject.authUserID); err != nil && err != repository.ErrNotFound {
+		slog.Warn("Failed to delete password reset of deleted user", "user_id", subject.authUserID, "error", err)
+	}
+	if err := s.store.Logins.Delete(lockoutAccountKey + subject.authUserID.String()); err != nil && err != repository.ErrNotFound {
+		slog.Warn("Failed to delete sign-in counter of deleted user", "user_id", subject.authUserID, "error", err)
+	}
+
+	if _, err := revokeOwnedAPIKeys(s.store, *subject.authUserID); err != nil {
+		slog.Error("Failed to revoke API keys of deleted user", "user_id", subject.authUserID, "error", err)
+	}
+}
+
+// conversation returns the bot's state for the subject's confirmed number, if the bot runs
+func (s *PrivacyService) conversation(subject *dataSubject) map[string]any {
+	if s.conversations == nil || subject.phoneNumber == "" {
+		return nil
+	}
+	return s.conversations.Conversation(subject.phoneNumber)
+}
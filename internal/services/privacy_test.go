@@ -0,0 +1,141 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// fakeAccounts is an in-memory AccountManager
+type fakeAccounts struct {
+	users   map[uuid.UUID]*models.AuthUser
+	deleted []uuid.UUID
+}
+
+func (f *fakeAccounts) GetUser(userID uuid.UUID) (*models.AuthUser, error) {
+	if user, ok := f.users[userID]; ok {
+		return user, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f *fakeAccounts) DeleteUser(userID uuid.UUID) error {
+	f.deleted = append(f.deleted, userID)
+	delete(f.users, userID)
+	return nil
+}
+
+func TestPrivacyPhoneRequestsNeedConfirmedNumber(t *testing.T) {
+	const phone = "+2348012345678"
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		// account is the auth account of the farmer who signed up with phone
+		account    func(id uuid.UUID) *models.AuthUser
+		wantAccess bool
+	}{
+		{
+			name:       "signup phone never confirmed",
+			account:    func(id uuid.UUID) *models.AuthUser { return &models.AuthUser{ID: id} },
+			wantAccess: false,
+		},
+		{
+			name: "account confirmed another number",
+			account: func(id uuid.UUID) *models.AuthUser {
+				other := "2348099999999"
+				return &models.AuthUser{ID: id, Phone: &other, PhoneConfirmedAt: &now}
+			},
+			wantAccess: false,
+		},
+		{
+			name: "account set the number without confirming it",
+			account: func(id uuid.UUID) *models.AuthUser {
+				number := "2348012345678"
+				return &models.AuthUser{ID: id, Phone: &number}
+			},
+			wantAccess: false,
+		},
+		{
+			name: "account confirmed the number",
+			account: func(id uuid.UUID) *models.AuthUser {
+				number := "2348012345678"
+				return &models.AuthUser{ID: id, Phone: &number, PhoneConfirmedAt: &now}
+			},
+			wantAccess: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := repository.NewMemoryStore()
+			userID := uuid.New()
+			accounts := &fakeAccounts{users: map[uuid.UUID]*models.AuthUser{userID: tt.account(userID)}}
+			privacy := NewPrivacyService(store, accounts, nil, NewSessionService(store))
+
+			_, err := NewProfileService(store).CreateUserProfile(userID.String(), "ada", "farmer",
+				&SignupData{PhoneNumber: phone, CropType: "Maize"}, AuditContext{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			export, err := privacy.ExportPhoneData(phone)
+			if err != nil {
+				t.Fatalf("ExportPhoneData() error = %v", err)
+			}
+			if gotAccess := export != nil; gotAccess != tt.wantAccess {
+				t.Fatalf("ExportPhoneData() returned data = %v, want %v:\n%s", gotAccess, tt.wantAccess, export)
+			}
+
+			deleted, err := privacy.DeletePhoneData(phone)
+			if err != nil {
+				t.Fatalf("DeletePhoneData() error = %v", err)
+			}
+			if deleted != tt.wantAccess {
+				t.Fatalf("DeletePhoneData() = %v, want %v", deleted, tt.wantAccess)
+			}
+
+			_, profileErr := store.Profiles.GetByAuthUserID(userID.String())
+			farmers, err := store.Farmers.ListByAuthUserIDs([]uuid.UUID{userID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantAccess {
+				if len(accounts.deleted) != 1 || profileErr != repository.ErrNotFound || len(farmers) != 0 {
+					t.Fatalf("account not erased: deleted %v, profile error %v, farmers %d", accounts.deleted, profileErr, len(farmers))
+				}
+				return
+			}
+			if len(accounts.deleted) != 0 || profileErr != nil || len(farmers) != 1 {
+				t.Fatalf("account touched: deleted %v, profile error %v, farmers %d", accounts.deleted, profileErr, len(farmers))
+			}
+		})
+	}
+}
+
+func TestPrivacyPhoneRequestsKeepBotRegistrations(t *testing.T) {
+	const phone = "+2348012345678"
+	store := repository.NewMemoryStore()
+	accounts := &fakeAccounts{users: map[uuid.UUID]*models.AuthUser{}}
+	privacy := NewPrivacyService(store, accounts, nil, NewSessionService(store))
+
+	// A farmer registered through the bot, with no account
+	if err := NewFarmerService(store).RegisterFarmer(phone, "Ada", []string{"Maize"}, "en"); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := privacy.DeletePhoneData(phone)
+	if err != nil || !deleted {
+		t.Fatalf("DeletePhoneData() = %v, %v; want true", deleted, err)
+	}
+	farmers, err := store.Farmers.ListByPhone(phoneNumberVariants(phone))
+	if err != nil || len(farmers) != 0 {
+		t.Fatalf("bot registration left behind: %d farmers, error %v", len(farmers), err)
+	}
+	if len(accounts.deleted) != 0 {
+		t.Fatalf("deleted accounts %v, want none", accounts.deleted)
+	}
+}
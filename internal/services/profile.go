@@ -2,16 +2,24 @@ package services
 
 import (
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/okoye-dev/flux-server/internal/models"
-	"github.com/supabase-community/supabase-go"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// Audited profile actions
+const (
+	AuditProfileCreated = "profile.created"
+	AuditProfileLinked  = "profile.linked"
 )
 
 // SignupData contains additional signup information
 type SignupData struct {
+	// Email is the account's sign-in email, recorded on the profile
+	Email              string
 	PhoneNumber        string
 	CropType           string
 	LocationID         int64
@@ -21,28 +29,17 @@ type SignupData struct {
 
 // ProfileService handles user profile operations
 type ProfileService struct {
-	client *supabase.Client
+	store *repository.Store
+	audit *AuditService
 }
 
 // NewProfileService creates a new profile service
-func NewProfileService() (*ProfileService, error) {
-	supabaseURL := os.Getenv("SUPABASE_URL")
-	supabaseAnonKey := os.Getenv("SUPABASE_ANON_KEY")
-
-	if supabaseURL == "" || supabaseAnonKey == "" {
-		return nil, ErrSupabaseConfigMissing
-	}
-
-	client, err := supabase.NewClient(supabaseURL, supabaseAnonKey, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	return &ProfileService{client: client}, nil
+func NewProfileService(store *repository.Store) *ProfileService {
+	return &ProfileService{store: store, audit: NewAuditService(store)}
 }
 
 // CreateUserProfile creates a user profile after successful signup
-func (s *ProfileService) CreateUserProfile(authUserID, username, roleName string, signupData *SignupData) (*models.UserProfile, error) {
+func (s *ProfileService) CreateUserProfile(authUserID, username, roleName string, signupData *SignupData, actx AuditContext) (*models.UserProfile, error) {
 	// Parse auth user ID
 	authUUID, err := uuid.Parse(authUserID)
 	if err != nil {
@@ -73,57 +70,134 @@ func (s *ProfileService) CreateUserProfile(authUserID, username, roleName string
 		Metadata:    map[string]any{}, // Empty metadata - role and username are in dedicated fields
 		CreatedAt:   now,
 	}
+	if signupData != nil && signupData.Email != "" {
+		email := strings.ToLower(signupData.Email)
+		profile.Email = &email
+	}
+	// The profile phone receives password reset codes over WhatsApp
+	if signupData != nil && signupData.PhoneNumber != "" {
+		profile.Phone = &signupData.PhoneNumber
+	}
 
-	// Insert into user_profiles table
-	var result []models.UserProfile
-	_, err = s.client.From("user_profiles").Insert(profile, false, "", "", "").ExecuteTo(&result)
+	// Insert the profile, the role-specific record and its crops in one transaction,
+	// so a failure part way through doesn't leave a half-created user behind
+	var created *models.UserProfile
+	err = s.store.Transact(func(tx *repository.Store) error {
+		var err error
+		created, err = tx.Profiles.Create(profile)
+		if err == repository.ErrInsertFailed {
+			return ErrProfileCreationFailed
+		}
+		if err != nil {
+			return err
+		}
+
+		return NewProfileService(tx).createRoleSpecificRecord(authUserID, roleName, username, signupData)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if len(result) == 0 {
-		return nil, ErrProfileCreationFailed
-	}
+	s.audit.Record(actx, AuditProfileCreated, &authUUID, map[string]any{"profile_id": created.ID, "role": roleName})
+	return created, nil
+}
 
-	// Create role-specific record
-	err = s.createRoleSpecificRecord(authUserID, roleName, username, signupData)
+// LinkFarmerAccount gives an auth user a farmer profile for an existing farmer record,
+// e.g. one registered through the WhatsApp bot, and links the record to the user. A
+// farmer already linked to an account is never relinked.
+func (s *ProfileService) LinkFarmerAccount(farmerID int64, authUserID uuid.UUID, username, phone string, actx AuditContext) (*models.UserProfile, error) {
+	roleID, err := s.GetRoleIDByName("farmer")
 	if err != nil {
-		// Log error but don't fail - user profile is created
-		// TODO: Add proper logging
-		_ = err
+		return nil, err
 	}
 
-	return &result[0], nil
-}
+	profile := models.UserProfile{
+		ID:          uuid.New(),
+		AuthUserID:  &authUserID,
+		RoleID:      roleID,
+		DisplayName: &username,
+		Phone:       &phone,
+		Metadata:    map[string]any{},
+		CreatedAt:   time.Now(),
+	}
 
-// GetUserProfile retrieves a user profile by auth user ID
-func (s *ProfileService) GetUserProfile(authUserID string) (*models.UserProfile, error) {
-	var result []models.UserProfile
-	_, err := s.client.From("user_profiles").Select("*", "", false).Eq("auth_user_id", authUserID).ExecuteTo(&result)
+	var created *models.UserProfile
+	err = s.store.Transact(func(tx *repository.Store) error {
+		farmer, err := tx.Farmers.Get(farmerID)
+		if err == repository.ErrNotFound {
+			return ErrFarmerNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if farmer.AuthUserID != nil {
+			return ErrFarmerAlreadyLinked
+		}
+
+		created, err = tx.Profiles.Create(profile)
+		if err == repository.ErrInsertFailed {
+			return ErrProfileCreationFailed
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Farmers.Update(farmerID, repository.FarmerUpdate{AuthUserID: &authUserID})
+		if err == repository.ErrNotFound {
+			return ErrFarmerNotFound
+		}
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if len(result) > 0 {
-		return &result[0], nil
-	}
+	s.audit.Record(actx, AuditProfileLinked, &authUserID, map[string]any{"profile_id": created.ID, "farmer_id": farmerID})
+	return created, nil
+}
 
-	return nil, ErrProfileNotFound
+// GetUserProfile retrieves a user profile by auth user ID
+func (s *ProfileService) GetUserProfile(authUserID string) (*models.UserProfile, error) {
+	profile, err := s.store.Profiles.GetByAuthUserID(authUserID)
+	if err == repository.ErrNotFound {
+		return nil, ErrProfileNotFound
+	}
+	return profile, err
 }
 
 // GetRoleIDByName gets role ID by role name
 func (s *ProfileService) GetRoleIDByName(roleName string) (*uuid.UUID, error) {
-	var result []models.Role
-	_, err := s.client.From("roles").Select("id", "", false).Eq("name", roleName).ExecuteTo(&result)
+	role, err := s.store.Roles.GetByName(roleName)
+	if err == repository.ErrNotFound {
+		return nil, ErrRoleNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	if len(result) > 0 {
-		return &result[0].ID, nil
+	return &role.ID, nil
+}
+
+// GetUserRoleName resolves the role name of a user from user_profiles and roles
+func (s *ProfileService) GetUserRoleName(authUserID string) (string, error) {
+	profile, err := s.GetUserProfile(authUserID)
+	if err != nil {
+		return "", err
+	}
+
+	if profile.RoleID == nil {
+		return "", ErrRoleNotFound
 	}
 
-	return nil, ErrRoleNotFound
+	role, err := s.store.Roles.Get(*profile.RoleID)
+	if err == repository.ErrNotFound {
+		return "", ErrRoleNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return role.Name, nil
 }
 
 // createRoleSpecificRecord creates farmer or extension officer record based on role
@@ -146,9 +220,6 @@ func (s *ProfileService) createRoleSpecificRecord(authUserID, roleName, username
 
 // createFarmerRecord creates a farmer record
 func (s *ProfileService) createFarmerRecord(authUserID uuid.UUID, username string, signupData *SignupData) error {
-	// Generate a new ID for the farmer record
-	farmerID := generateFarmerID()
-
 	// Set defaults if not provided
 	phoneNumber := signupData.PhoneNumber
 	cropType := signupData.CropType
@@ -158,8 +229,8 @@ func (s *ProfileService) createFarmerRecord(authUserID uuid.UUID, username strin
 		language = "en"
 	}
 
+	// The ID is left zero for the database to assign
 	farmer := models.Farmer{
-		ID:          farmerID,
 		AuthUserID:  &authUserID, // Link to auth user
 		Name:        username,
 		PhoneNumber: phoneNumber,
@@ -169,111 +240,105 @@ func (s *ProfileService) createFarmerRecord(authUserID uuid.UUID, username strin
 		CreatedAt:   time.Now(),
 	}
 
-	var result []models.Farmer
-	_, err := s.client.From("farmers").Insert(farmer, false, "", "", "").ExecuteTo(&result)
+	created, err := s.store.Farmers.Create(farmer)
 	if err != nil {
 		return err
 	}
 
 	// If we have crop information, add it to the farmer_crops table
 	if cropType != "" {
-		return s.addCropToFarmer(farmerID, cropType)
+		return s.AddCropsToFarmer(created.ID, []string{cropType})
 	}
 
 	return nil
 }
 
-// addCropToFarmer adds a crop to a farmer's profile
-func (s *ProfileService) addCropToFarmer(farmerID int64, cropName string) error {
-	// First, find or create the crop
-	cropID, err := s.findOrCreateCrop(cropName)
-	if err != nil {
-		return err
-	}
-
-	// Create farmer-crop relationship
+// linkCropToFarmer adds a catalogue crop to a farmer's profile
+func (s *ProfileService) linkCropToFarmer(farmerID int64, cropID uuid.UUID) error {
 	farmerCrop := models.FarmerCrop{
 		ID:        uuid.New(),
 		FarmerID:  farmerID,
-		CropID:    *cropID,
+		CropID:    cropID,
 		CreatedAt: time.Now(),
 	}
 
-	var result []models.FarmerCrop
-	_, err = s.client.From("farmer_crops").Insert(farmerCrop, false, "", "", "").ExecuteTo(&result)
-	return err
+	return s.store.FarmerCrops.Create(farmerCrop)
 }
 
-// findOrCreateCrop finds an existing crop or creates a new one
+// findOrCreateCrop finds an existing crop (matching case, aliases and small typos) or creates a new one
 func (s *ProfileService) findOrCreateCrop(cropName string) (*uuid.UUID, error) {
-	// First, try to find existing crop
-	var existingCrops []models.Crop
-	_, err := s.client.From("crops").Select("id", "", false).Eq("name", cropName).ExecuteTo(&existingCrops)
-	if err != nil {
-		return nil, err
-	}
+	catalogue := NewCatalogueService(s.store)
 
-	if len(existingCrops) > 0 {
-		return &existingCrops[0].ID, nil
+	// First, try to resolve the name against the catalogue
+	existing, err := catalogue.ResolveCrop(cropName)
+	if err == nil {
+		return &existing.ID, nil
+	}
+	if err != ErrCropNotFound {
+		return nil, err
 	}
 
 	// Create new crop if not found
 	newCrop := models.Crop{
 		ID:        uuid.New(),
-		Name:      cropName,
+		Name:      strings.TrimSpace(cropName),
 		CreatedAt: time.Now(),
 	}
 
-	var result []models.Crop
-	_, err = s.client.From("crops").Insert(newCrop, false, "", "", "").ExecuteTo(&result)
+	created, err := s.store.Crops.Create(newCrop)
+	if err == repository.ErrInsertFailed {
+		return nil, fmt.Errorf("failed to create crop: %s", cropName)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	if len(result) > 0 {
-		return &result[0].ID, nil
-	}
-
-	return nil, fmt.Errorf("failed to create crop: %s", cropName)
+	return &created.ID, nil
 }
 
-// AddCropsToFarmer adds multiple crops to a farmer
+// AddCropsToFarmer adds multiple crops to a farmer. Names resolving to a crop the farmer
+// already grows, such as "Tomatoes" after "tomato" or "corn" after "maize", are skipped.
 func (s *ProfileService) AddCropsToFarmer(farmerID int64, cropNames []string) error {
+	current, err := s.store.FarmerCrops.CropsForFarmer(farmerID)
+	if err != nil {
+		return err
+	}
+	linked := make(map[uuid.UUID]bool, len(current))
+	for _, crop := range current {
+		linked[crop.ID] = true
+	}
+
 	for _, cropName := range cropNames {
-		if err := s.addCropToFarmer(farmerID, cropName); err != nil {
+		if strings.TrimSpace(cropName) == "" {
+			continue
+		}
+		cropID, err := s.findOrCreateCrop(cropName)
+		if err != nil {
 			return fmt.Errorf("failed to add crop %s: %w", cropName, err)
 		}
+		if linked[*cropID] {
+			continue
+		}
+		if err := s.linkCropToFarmer(farmerID, *cropID); err != nil {
+			return fmt.Errorf("failed to add crop %s: %w", cropName, err)
+		}
+		linked[*cropID] = true
 	}
 	return nil
 }
 
 // GetFarmerCrops retrieves all crops for a farmer
 func (s *ProfileService) GetFarmerCrops(farmerID int64) ([]models.Crop, error) {
-	var farmerCrops []models.FarmerCropWithDetails
-	_, err := s.client.From("farmer_crops").
-		Select("*, crops(*)", "", false).
-		Eq("farmer_id", fmt.Sprintf("%d", farmerID)).
-		ExecuteTo(&farmerCrops)
-	
-	if err != nil {
-		return nil, err
-	}
-
-	var crops []models.Crop
-	for _, fc := range farmerCrops {
-		if fc.Crop != nil {
-			crops = append(crops, *fc.Crop)
-		}
-	}
+	return s.store.FarmerCrops.CropsForFarmer(farmerID)
+}
 
-	return crops, nil
+// RemoveFarmerCrops removes every crop linked to a farmer
+func (s *ProfileService) RemoveFarmerCrops(farmerID int64) error {
+	return s.store.FarmerCrops.DeleteByFarmer(farmerID)
 }
 
 // createExtensionOfficerRecord creates an extension officer record
 func (s *ProfileService) createExtensionOfficerRecord(authUserID uuid.UUID, username string, signupData *SignupData) error {
-	// Generate a new ID for the extension officer record
-	officerID := generateExtensionOfficerID()
-
 	// Set defaults if not provided
 	phoneNumber := signupData.PhoneNumber
 	var assignedLocationID *int64
@@ -281,39 +346,84 @@ func (s *ProfileService) createExtensionOfficerRecord(authUserID uuid.UUID, user
 		assignedLocationID = &signupData.AssignedLocationID
 	}
 
+	// The ID is left zero for the database to assign
 	officer := models.ExtensionOfficer{
-		ID:                 officerID,
 		AuthUserID:         &authUserID, // Link to auth user
 		Name:               username,
 		PhoneNumber:        phoneNumber,
 		AssignedLocationID: assignedLocationID,
 	}
 
-	var result []models.ExtensionOfficer
-	_, err := s.client.From("extension_officers").Insert(officer, false, "", "", "").ExecuteTo(&result)
+	_, err := s.store.Officers.Create(officer)
 	return err
 }
 
-// generateFarmerID generates a new farmer ID (bigint)
-func generateFarmerID() int64 {
-	// For now, use timestamp-based ID
-	// In production, you might want to use a proper sequence
-	return time.Now().UnixNano() / 1000000 // Convert to milliseconds
-}
-
-// generateExtensionOfficerID generates a new extension officer ID (bigint)
-func generateExtensionOfficerID() int64 {
-	// For now, use timestamp-based ID
-	// In production, you might want to use a proper sequence
-	return time.Now().UnixNano() / 1000000 // Convert to milliseconds
-}
-
 // Error definitions
 var (
-	ErrSupabaseConfigMissing = &ServiceError{Code: "SUPABASE_CONFIG_MISSING", Message: "Supabase configuration missing"}
-	ErrProfileCreationFailed = &ServiceError{Code: "PROFILE_CREATION_FAILED", Message: "Failed to create user profile"}
-	ErrProfileNotFound       = &ServiceError{Code: "PROFILE_NOT_FOUND", Message: "User profile not found"}
-	ErrRoleNotFound          = &ServiceError{Code: "ROLE_NOT_FOUND", Message: "Role not found"}
+	ErrProfileCreationFailed     = &ServiceError{Code: "PROFILE_CREATION_FAILED", Message: "Failed to create user profile"}
+	ErrProfileNotFound           = &ServiceError{Code: "PROFILE_NOT_FOUND", Message: "User profile not found"}
+	ErrRoleNotFound              = &ServiceError{Code: "ROLE_NOT_FOUND", Message: "Role not found"}
+	ErrFarmerNotFound            = &ServiceError{Code: "FARMER_NOT_FOUND", Message: "Farmer not found"}
+	ErrFarmerCreationFailed      = &ServiceError{Code: "FARMER_CREATION_FAILED", Message: "Failed to create farmer"}
+	ErrFarmerNameRequired        = &ServiceError{Code: "FARMER_NAME_REQUIRED", Message: "Farmer name is required"}
+	ErrFarmerAlreadyLinked       = &ServiceError{Code: "FARMER_ALREADY_LINKED", Message: "This farmer is already linked to an account"}
+	ErrFarmerPhoneLinked         = &ServiceError{Code: "FARMER_PHONE_LINKED", Message: "The phone number of a farmer with an account can't be changed"}
+	ErrHarvestCropRequired       = &ServiceError{Code: "HARVEST_CROP_REQUIRED", Message: "Harvest crop_id is required"}
+	ErrHarvestQuantityInvalid    = &ServiceError{Code: "HARVEST_QUANTITY_INVALID", Message: "Harvest quantity must be greater than zero"}
+	ErrHarvestCreationFailed     = &ServiceError{Code: "HARVEST_CREATION_FAILED", Message: "Failed to record harvest"}
+	ErrHarvestGroupByInvalid     = &ServiceError{Code: "HARVEST_GROUP_BY_INVALID", Message: "group_by must be 'season' or 'location'"}
+	ErrCropNotFound              = &ServiceError{Code: "CROP_NOT_FOUND", Message: "Crop not found"}
+	ErrCropAlreadyExists         = &ServiceError{Code: "CROP_ALREADY_EXISTS", Message: "A crop with this name already exists"}
+	ErrCropNameRequired          = &ServiceError{Code: "CROP_NAME_REQUIRED", Message: "Crop name is required"}
+	ErrCropMergeInvalid          = &ServiceError{Code: "CROP_MERGE_INVALID", Message: "Merge needs a canonical crop and at least one different duplicate crop"}
+	ErrLocationNotFound          = &ServiceError{Code: "LOCATION_NOT_FOUND", Message: "Location not found"}
+	ErrLocationNameRequired      = &ServiceError{Code: "LOCATION_NAME_REQUIRED", Message: "Location name is required"}
+	ErrSearchModeInvalid         = &ServiceError{Code: "SEARCH_MODE_INVALID", Message: "match must be 'prefix' or 'fuzzy'"}
+	ErrOfficerNotFound           = &ServiceError{Code: "OFFICER_NOT_FOUND", Message: "No extension officer record for this user"}
+	ErrOfficerLocationUnassigned = &ServiceError{Code: "OFFICER_LOCATION_UNASSIGNED", Message: "Extension officer has no assigned location"}
+	ErrFarmerOutsideLocation     = &ServiceError{Code: "FARMER_OUTSIDE_LOCATION", Message: "Farmer is not in your assigned location"}
+	ErrVisitNoteRequired         = &ServiceError{Code: "VISIT_NOTE_REQUIRED", Message: "Visit note is required"}
+	ErrSupabaseConfigMissing     = &ServiceError{Code: "MISSING_CONFIG", Message: "Supabase configuration missing"}
+	ErrServiceRoleKeyMissing     = &ServiceError{Code: "SERVICE_ROLE_KEY_MISSING", Message: "SUPABASE_SERVICE_ROLE_KEY is required for this operation"}
+	ErrSignupRoleInvalid         = &ServiceError{Code: "SIGNUP_ROLE_INVALID", Message: "role must be 'farmer' or 'extension_officer'"}
+	ErrSignupAuthFailed          = &ServiceError{Code: "SIGNUP_AUTH_FAILED", Message: "Failed to create user account"}
+	ErrSignupProfileFailed       = &ServiceError{Code: "SIGNUP_PROFILE_FAILED", Message: "Failed to create user profile; the account was not created"}
+	ErrSessionIDMissing          = &ServiceError{Code: "SESSION_ID_MISSING", Message: "Token has no session_id claim"}
+	ErrSessionRevoked            = &ServiceError{Code: "SESSION_REVOKED", Message: "Session has been signed out"}
+	ErrSignupRollbackFailed      = &ServiceError{Code: "SIGNUP_ROLLBACK_FAILED", Message: "Failed to create user profile; the account will be repaired automatically"}
+	ErrJWTSecretMissing          = &ServiceError{Code: "MISSING_CONFIG", Message: "JWT_SECRET is required for this operation"}
+	ErrWhatsAppNotConfigured     = &ServiceError{Code: "WHATSAPP_NOT_CONFIGURED", Message: "WHATSAPP_INSTANCE_ID and WHATSAPP_TOKEN are required for this operation"}
+	ErrOTPPhoneInvalid           = &ServiceError{Code: "OTP_PHONE_INVALID", Message: "A valid phone_number is required"}
+	ErrOTPPhoneNotRegistered     = &ServiceError{Code: "OTP_PHONE_NOT_REGISTERED", Message: "No farmer is registered with this WhatsApp number"}
+	ErrOTPDeliveryFailed         = &ServiceError{Code: "OTP_DELIVERY_FAILED", Message: "Failed to send the code over WhatsApp"}
+	ErrOTPInvalid                = &ServiceError{Code: "OTP_INVALID", Message: "Code is invalid or has expired"}
+	ErrOTPAttemptsExceeded       = &ServiceError{Code: "OTP_ATTEMPTS_EXCEEDED", Message: "Too many wrong codes; request a new one"}
+	ErrOTPAccountFailed          = &ServiceError{Code: "OTP_ACCOUNT_FAILED", Message: "Failed to create an account for this farmer"}
+	ErrOTPAccountMismatch        = &ServiceError{Code: "OTP_ACCOUNT_MISMATCH", Message: "This number is not the confirmed phone of the farmer's account"}
+	ErrPasswordTooShort          = &ServiceError{Code: "PASSWORD_TOO_SHORT", Message: "Password must be at least 6 characters"}
+	ErrPasswordIncorrect         = &ServiceError{Code: "PASSWORD_INCORRECT", Message: "Current password is incorrect"}
+	ErrPasswordNotSet            = &ServiceError{Code: "PASSWORD_NOT_SET", Message: "This account signs in with a WhatsApp code and has no password"}
+	ErrPasswordUpdateFailed      = &ServiceError{Code: "PASSWORD_UPDATE_FAILED", Message: "Failed to update the password"}
+	ErrPasswordResetInvalid      = &ServiceError{Code: "PASSWORD_RESET_INVALID", Message: "Reset code is invalid or has expired"}
+	ErrPasswordResetLocked       = &ServiceError{Code: "PASSWORD_RESET_ATTEMPTS_EXCEEDED", Message: "Too many wrong reset codes; request a new one"}
+	ErrPasswordResetUndelivered  = &ServiceError{Code: "PASSWORD_RESET_DELIVERY_FAILED", Message: "Failed to send the reset code over WhatsApp"}
+	ErrAPIKeyInvalid             = &ServiceError{Code: "API_KEY_INVALID", Message: "API key is invalid, revoked or expired"}
+	ErrAPIKeyNotFound            = &ServiceError{Code: "API_KEY_NOT_FOUND", Message: "API key not found"}
+	ErrAPIKeyNameRequired        = &ServiceError{Code: "API_KEY_NAME_REQUIRED", Message: "API key name is required"}
+	ErrAPIKeyScopesInvalid       = &ServiceError{Code: "API_KEY_SCOPES_INVALID", Message: "scopes must list at least one known scope"}
+	ErrAPIKeyExpiryInvalid       = &ServiceError{Code: "API_KEY_EXPIRY_INVALID", Message: "expires_at must be in the future"}
+	ErrAPIKeyOwnerNotFound       = &ServiceError{Code: "API_KEY_OWNER_NOT_FOUND", Message: "owner_id must be a user with a profile"}
+	ErrIdentifierInvalid         = &ServiceError{Code: "IDENTIFIER_INVALID", Message: "Enter a username, email address or phone number"}
+	ErrUsernameInvalid           = &ServiceError{Code: "USERNAME_INVALID", Message: "Usernames can't contain @ or spaces, or be a phone number"}
+	ErrUsernameTaken             = &ServiceError{Code: "USERNAME_TAKEN", Message: "This username is already taken"}
+	ErrEmailInvalid              = &ServiceError{Code: "EMAIL_INVALID", Message: "A valid email address is required"}
+	ErrSignInFailed              = &ServiceError{Code: "SIGNIN_FAILED", Message: "Invalid login credentials"}
+	ErrSignInLocked              = &ServiceError{Code: "SIGNIN_LOCKED", Message: "Too many failed sign-ins; try again later"}
+	ErrUnlockTargetRequired      = &ServiceError{Code: "UNLOCK_TARGET_REQUIRED", Message: "identifier or ip_address is required"}
+	ErrUserSelfModification      = &ServiceError{Code: "USER_SELF_MODIFICATION", Message: "Admins can't change the role of, or disable, their own account"}
+	ErrLocationIDInvalid         = &ServiceError{Code: "LOCATION_ID_INVALID", Message: "assigned_location_id must be a positive number or null"}
+	ErrAccountDisabled           = &ServiceError{Code: "ACCOUNT_DISABLED", Message: "This account has been disabled"}
+	ErrAuditRangeInvalid         = &ServiceError{Code: "AUDIT_RANGE_INVALID", Message: "from must not be after to"}
 )
 
 // ServiceError represents a service error
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// reconcilePageSize is how many auth accounts are fetched per admin API call
+const reconcilePageSize = 100
+
+// ReconcileResult summarises one reconciliation pass
+type ReconcileResult struct {
+	Checked         int      `json:"checked"`
+	ProfilesCreated int      `json:"profiles_created"`
+	RecordsCreated  int      `json:"records_created"`
+	EmailsRecorded  int      `json:"emails_recorded"`
+	Failed          int      `json:"failed"`
+	Errors          []string `json:"errors,omitempty"`
+}
+
+// Reconciler repairs auth accounts left without a profile or role record,
+// e.g. by signups from before signup was atomic or whose rollback failed. It also
+// records the sign-in email of profiles created before emails were recorded.
+type Reconciler struct {
+	store *repository.Store
+	auth  AuthProvider
+}
+
+// NewReconciler creates a new reconciler
+func NewReconciler(store *repository.Store, auth AuthProvider) *Reconciler {
+	return &Reconciler{store: store, auth: auth}
+}
+
+// Run checks every auth account once. A failure for one account is recorded in the result
+// and doesn't stop the pass; the returned error is only for failing to list accounts.
+func (r *Reconciler) Run() (*ReconcileResult, error) {
+	result := &ReconcileResult{}
+
+	for page := 1; ; page++ {
+		users, err := r.auth.ListUsers(page, reconcilePageSize)
+		if err != nil {
+			return result, err
+		}
+		if len(users) == 0 {
+			return result, nil
+		}
+
+		for _, user := range users {
+			result.Checked++
+			if err := r.repair(user, result); err != nil {
+				result.Failed++
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", user.ID, err))
+			}
+		}
+
+		if len(users) < reconcilePageSize {
+			return result, nil
+		}
+	}
+}
+
+// Start runs a reconciliation pass every interval until stop is closed
+func (r *Reconciler) Start(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			result, err := r.Run()
+			if err != nil {
+				slog.Error("Reconcile: listing auth users failed", "error", err)
+				continue
+			}
+			if result.ProfilesCreated > 0 || result.RecordsCreated > 0 || result.EmailsRecorded > 0 || result.Failed > 0 {
+				slog.Info("Reconcile finished", "checked", result.Checked, "profiles_created", result.ProfilesCreated,
+					"records_created", result.RecordsCreated, "emails_recorded", result.EmailsRecorded, "failed", result.Failed)
+			}
+		}
+	}
+}
+
+// repair creates whatever the account is missing, using the metadata stored at signup
+func (r *Reconciler) repair(user models.AuthUser, result *ReconcileResult) error {
+	profiles := NewProfileService(r.store)
+	authUserID := user.ID.String()
+
+	username, roleName, signupData := signupDataFromMetadata(user.UserMetadata)
+	if username == "" {
+		username = fallbackUsername(user)
+	}
+	if user.Email != nil {
+		signupData.Email = *user.Email
+	}
+
+	profile, err := profiles.GetUserProfile(authUserID)
+	if err == ErrProfileNotFound {
+		// Accounts created for farmers signing in with a WhatsApp code belong to an existing
+		// farmer record. Users can write their own metadata, so the farmer must have the
+		// phone number the account confirmed; LinkFarmerAccount refuses farmers with an account.
+		if farmerID, ok := farmerIDFromMetadata(user.UserMetadata); ok {
+			farmer, err := r.store.Farmers.Get(farmerID)
+			if err == repository.ErrNotFound {
+				return ErrFarmerNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if !confirmedPhoneMatches(&user, farmer.PhoneNumber) {
+				return fmt.Errorf("farmer %d does not have the account's confirmed phone number", farmerID)
+			}
+			if _, err := profiles.LinkFarmerAccount(farmerID, user.ID, username, farmer.PhoneNumber, AuditContext{}); err != nil {
+				return err
+			}
+			result.ProfilesCreated++
+			return nil
+		}
+		// Users can write their own metadata, so it only ever grants a role signup offers
+		if !signupRoles[roleName] {
+			if roleName != "" {
+				slog.Warn("Reconcile: ignoring a role signup doesn't offer", "user_id", user.ID, "role", roleName)
+			}
+			roleName = "farmer"
+		}
+		if _, err := profiles.CreateUserProfile(authUserID, username, roleName, signupData, AuditContext{}); err != nil {
+			return err
+		}
+		result.ProfilesCreated++
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Profiles from before sign-in emails were recorded get the account's email
+	if profile.Email == nil && user.Email != nil && *user.Email != "" {
+		if err := r.store.Profiles.SetEmail(profile.ID, strings.ToLower(*user.Email)); err != nil {
+			return err
+		}
+		result.EmailsRecorded++
+	}
+
+	// The profile exists; make sure its role record does too
+	roleName, err = profiles.GetUserRoleName(authUserID)
+	if err == ErrRoleNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if profile.DisplayName != nil && *profile.DisplayName != "" {
+		username = *profile.DisplayName
+	}
+
+	missing, err := roleRecordMissing(r.store, user.ID, roleName)
+	if err != nil || !missing {
+		return err
+	}
+	if err := profiles.createRoleSpecificRecord(authUserID, roleName, username, signupData); err != nil {
+		return err
+	}
+	result.RecordsCreated++
+	return nil
+}
+
+// roleRecordMissing reports whether a farmer or extension officer has no record for their role
+func roleRecordMissing(store *repository.Store, authUserID uuid.UUID, roleName string) (bool, error) {
+	switch roleName {
+	case "farmer":
+		farmers, err := store.Farmers.ListByAuthUserIDs([]uuid.UUID{authUserID})
+		return len(farmers) == 0, err
+	case "extension_officer":
+		_, err := store.Officers.GetByAuthUserID(authUserID.String())
+		if err == repository.ErrNotFound {
+			return true, nil
+		}
+		return false, err
+	default:
+		return false, nil
+	}
+}
+
+// fallbackUsername derives a name for accounts whose metadata has none
+func fallbackUsername(user models.AuthUser) string {
+	if user.Email != nil {
+		if local, _, ok := strings.Cut(*user.Email, "@"); ok && local != "" {
+			return local
+		}
+	}
+	if user.Phone != nil && *user.Phone != "" {
+		return *user.Phone
+	}
+	return user.ID.String()
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// sessionCacheTTL is how long a revocation lookup is trusted. Revocations made through
+// this server apply at once; ones made by another instance apply within the TTL.
+const sessionCacheTTL = 10 * time.Second
+
+// Session cache cleanup
+const (
+	// sessionSweepInterval is how often expired lookups are dropped from the cache
+	sessionSweepInterval = time.Minute
+	// sessionCacheMaxEntries triggers an early sweep when either cache grows past it
+	sessionCacheMaxEntries = 10000
+)
+
+// SessionService records signed-out sessions and checks tokens against them.
+// Supabase keeps accepting an access token until it expires, so revocation is enforced here.
+type SessionService struct {
+	store *repository.Store
+
+	mu        sync.Mutex
+	sessions  map[string]sessionCacheEntry // Keyed by session ID
+	cutoffs   map[string]sessionCacheEntry // Keyed by auth user ID
+	nextSweep time.Time
+}
+
+// sessionCacheEntry is a cached revocation lookup
+type sessionCacheEntry struct {
+	revoked   bool      // For sessions
+	cutoff    time.Time // For users; zero when they never signed out everywhere
+	expiresAt time.Time
+}
+
+// NewSessionService creates a new session service
+func NewSessionService(store *repository.Store) *SessionService {
+	return &SessionService{
+		store:    store,
+		sessions: map[string]sessionCacheEntry{},
+		cutoffs:  map[string]sessionCacheEntry{},
+	}
+}
+
+// Revoke signs out one session; its tokens are rejected until expiresAt, when they expire anyway
+func (s *SessionService) Revoke(authUserID, sessionID string, expiresAt time.Time) error {
+	userUUID, err := uuid.Parse(authUserID)
+	if err != nil {
+		return err
+	}
+	sessionUUID, err := uuid.Parse(sessionID)
+	if err != nil {
+		return ErrSessionIDMissing
+	}
+
+	err = s.store.Sessions.Revoke(models.RevokedSession{
+		SessionID:  sessionUUID,
+		AuthUserID: userUUID,
+		ExpiresAt:  expiresAt,
+		RevokedAt:  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.remember(s.sessions, sessionID, sessionCacheEntry{revoked: true, expiresAt: expiresAt})
+
+	// Revoked sessions are only needed until their tokens expire
+	if err := s.store.Sessions.DeleteExpired(time.Now()); err != nil {
+		slog.Warn("Failed to delete expired revoked sessions", "error", err)
+	}
+	return nil
+}
+
+// RevokeAll signs out every session of a user by rejecting all tokens issued before now
+func (s *SessionService) RevokeAll(authUserID string) error {
+	userUUID, err := uuid.Parse(authUserID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.store.Sessions.SetCutoff(models.SessionCutoff{AuthUserID: userUUID, RevokedBefore: now}); err != nil {
+		return err
+	}
+
+	s.remember(s.cutoffs, authUserID, sessionCacheEntry{cutoff: now, expiresAt: now.Add(sessionCacheTTL)})
+	return nil
+}
+
+// SessionRevoked reports whether a token was signed out, either through its session or
+// because it was issued before the user signed out everywhere. Token issue times have
+// one second precision, so tokens issued in the same second as signing out everywhere
+// are rejected too.
+func (s *SessionService) SessionRevoked(authUserID, sessionID string, issuedAt time.Time) (bool, error) {
+	if sessionID != "" {
+		revoked, err := s.sessionRevoked(sessionID)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	cutoff, err := s.cutoff(authUserID)
+	if err != nil {
+		return false, err
+	}
+	return issuedAt.Before(cutoff), nil
+}
+
+// sessionRevoked looks up one session, using the cache when the entry is still fresh
+func (s *SessionService) sessionRevoked(sessionID string) (bool, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.sessions[sessionID]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.revoked, nil
+	}
+
+	sessionUUID, err := uuid.Parse(sessionID)
+	if err != nil {
+		return false, nil
+	}
+	revoked, err := s.store.Sessions.IsRevoked(sessionUUID)
+	if err != nil {
+		return false, err
+	}
+
+	s.remember(s.sessions, sessionID, sessionCacheEntry{revoked: revoked, expiresAt: now.Add(sessionCacheTTL)})
+	return revoked, nil
+}
+
+// cutoff looks up when a user last signed out everywhere, using the cache when the entry is still fresh
+func (s *SessionService) cutoff(authUserID string) (time.Time, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.cutoffs[authUserID]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.cutoff, nil
+	}
+
+	userUUID, err := uuid.Parse(authUserID)
+	if err != nil {
+		return time.Time{}, nil
+	}
+	var cutoff time.Time
+	stored, err := s.store.Sessions.GetCutoff(userUUID)
+	if err != nil && err != repository.ErrNotFound {
+		return time.Time{}, err
+	}
+	if stored != nil {
+		cutoff = stored.RevokedBefore
+	}
+
+	s.remember(s.cutoffs, authUserID, sessionCacheEntry{cutoff: cutoff, expiresAt: now.Add(sessionCacheTTL)})
+	return cutoff, nil
+}
+
+// remember caches a lookup, first dropping expired entries when a sweep is due or the
+// cache has grown too large
+func (s *SessionService) remember(cache map[string]sessionCacheEntry, key string, entry sessionCacheEntry) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.After(s.nextSweep) || len(cache) > sessionCacheMaxEntries {
+		s.sweep(now)
+	}
+	cache[key] = entry
+}
+
+// sweep drops expired entries from both caches; the caller holds the lock. A cache still
+// too large is emptied, since every entry can be looked up again in the store.
+func (s *SessionService) sweep(now time.Time) {
+	for _, cache := range []map[string]sessionCacheEntry{s.sessions, s.cutoffs} {
+		for key, entry := range cache {
+			if !now.Before(entry.expiresAt) {
+				delete(cache, key)
+			}
+		}
+		if len(cache) > sessionCacheMaxEntries {
+			clear(cache)
+		}
+	}
+	s.nextSweep = now.Add(sessionSweepInterval)
+}
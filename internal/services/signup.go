@@ -0,0 +1,105 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/okoye-dev/flux-server/internal/models"
+)
+
+// signupRoles are the roles users may pick for themselves at signup
+var signupRoles = map[string]bool{
+	"farmer":            true,
+	"extension_officer": true,
+}
+
+// SignupService registers users in the auth provider together with their profile
+type SignupService struct {
+	auth     AuthProvider
+	profiles *ProfileService
+}
+
+// NewSignupService creates a new signup service
+func NewSignupService(auth AuthProvider, profiles *ProfileService) *SignupService {
+	return &SignupService{auth: auth, profiles: profiles}
+}
+
+// Signup creates the auth account and then the profile with its role record.
+// It is all-or-nothing: when the profile can't be created the auth account is deleted again.
+// Failures are reported per stage as ErrSignupRoleInvalid, ErrSignupAuthFailed,
+// ErrSignupProfileFailed or ErrSignupRollbackFailed.
+func (s *SignupService) Signup(email, password, username, roleName string, signupData *SignupData, actx AuditContext) (*models.AuthUser, error) {
+	if roleName == "" {
+		roleName = "farmer"
+	}
+	if signupData == nil {
+		signupData = &SignupData{}
+	}
+	signupData.Email = email
+
+	// Check the role before the account exists, so a bad role never needs compensating
+	if !signupRoles[roleName] {
+		return nil, ErrSignupRoleInvalid
+	}
+	if _, err := s.profiles.GetRoleIDByName(roleName); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSignupProfileFailed, err)
+	}
+
+	user, err := s.auth.SignUp(email, password, signupMetadata(username, roleName, signupData))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSignupAuthFailed, err)
+	}
+
+	if _, err := s.profiles.CreateUserProfile(user.ID.String(), username, roleName, signupData, actx); err != nil {
+		if deleteErr := s.auth.DeleteUser(user.ID); deleteErr != nil {
+			// The reconciliation job will create the missing profile later
+			slog.Error("Signup failed to remove auth user after profile error", "user_id", user.ID, "profile_error", err, "error", deleteErr)
+			return nil, fmt.Errorf("%w: %v", ErrSignupRollbackFailed, err)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrSignupProfileFailed, err)
+	}
+
+	return user, nil
+}
+
+// signupMetadata is stored on the auth account so reconciliation can rebuild a missing profile
+func signupMetadata(username, roleName string, signupData *SignupData) map[string]any {
+	metadata := map[string]any{
+		"username": username,
+		"role":     roleName,
+	}
+	if signupData.PhoneNumber != "" {
+		metadata["phone_number"] = signupData.PhoneNumber
+	}
+	if signupData.CropType != "" {
+		metadata["crop_type"] = signupData.CropType
+	}
+	if signupData.LocationID != 0 {
+		metadata["location_id"] = signupData.LocationID
+	}
+	if signupData.Language != "" {
+		metadata["language"] = signupData.Language
+	}
+	if signupData.AssignedLocationID != 0 {
+		metadata["assigned_location_id"] = signupData.AssignedLocationID
+	}
+	return metadata
+}
+
+// signupDataFromMetadata reads back what signupMetadata stored
+func signupDataFromMetadata(metadata map[string]any) (username, roleName string, signupData *SignupData) {
+	username, _ = metadata["username"].(string)
+	roleName, _ = metadata["role"].(string)
+	signupData = &SignupData{}
+	signupData.PhoneNumber, _ = metadata["phone_number"].(string)
+	signupData.CropType, _ = metadata["crop_type"].(string)
+	signupData.Language, _ = metadata["language"].(string)
+	// JSON numbers decode as float64
+	if locationID, ok := metadata["location_id"].(float64); ok {
+		signupData.LocationID = int64(locationID)
+	}
+	if assignedLocationID, ok := metadata["assigned_location_id"].(float64); ok {
+		signupData.AssignedLocationID = int64(assignedLocationID)
+	}
+	return username, roleName, signupData
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/supabase-community/gotrue-go/types"
+	"github.com/supabase-community/supabase-go"
+)
+
+// AuthProvider manages accounts in the auth provider (Supabase Auth)
+type AuthProvider interface {
+	SignUp(email, password string, metadata map[string]any) (*models.AuthUser, error)
+	DeleteUser(userID uuid.UUID) error
+	// ListUsers returns one page of accounts; an empty page means there are no more
+	ListUsers(page, perPage int) ([]models.AuthUser, error)
+}
+
+// AuthSession holds the tokens of a signed-in session
+type AuthSession struct {
+	User         models.AuthUser
+	AccessToken  string
+	RefreshToken string
+	TokenType    string
+	ExpiresIn    int
+}
+
+// SupabaseAuth implements AuthProvider with Supabase Auth (GoTrue).
+// Deleting and listing users go through the admin API and need the service role key.
+type SupabaseAuth struct {
+	client         *supabase.Client
+	url            string
+	anonKey        string
+	serviceRoleKey string
+	httpClient     *http.Client
+}
+
+// NewSupabaseAuth creates a Supabase Auth provider; serviceRoleKey may be empty if only SignUp is used
+func NewSupabaseAuth(supabaseURL, anonKey, serviceRoleKey string) (*SupabaseAuth, error) {
+	if supabaseURL == "" || anonKey == "" {
+		return nil, ErrSupabaseConfigMissing
+	}
+
+	client, err := supabase.NewClient(supabaseURL, anonKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SupabaseAuth{
+		client:         client,
+		url:            strings.TrimRight(supabaseURL, "/"),
+		anonKey:        anonKey,
+		serviceRoleKey: serviceRoleKey,
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// SignUp creates an account with an email and password
+func (a *SupabaseAuth) SignUp(email, password string, metadata map[string]any) (*models.AuthUser, error) {
+	response, err := a.client.Auth.Signup(types.SignupRequest{
+		Email:    email,
+		Password: password,
+		Data:     metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user := toAuthUser(response.User)
+	return &user, nil
+}
+
+// SignIn starts a session with an email and password
+func (a *SupabaseAuth) SignIn(email, password string) (*AuthSession, error) {
+	response, err := a.client.Auth.SignInWithEmailPassword(email, password)
+	if err != nil {
+		return nil, err
+	}
+	return toAuthSession(response.Session), nil
+}
+
+// Refresh exchanges a refresh token for a new access token; refresh tokens are single use
+func (a *SupabaseAuth) Refresh(refreshToken string) (*AuthSession, error) {
+	response, err := a.client.Auth.RefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	return toAuthSession(response.Session), nil
+}
+
+// SignOut revokes the refresh tokens of the access token's session, or of every
+// session of its user when everywhere is set. Access tokens stay valid until they expire.
+func (a *SupabaseAuth) SignOut(accessToken string, everywhere bool) error {
+	scope := "local"
+	if everywhere {
+		scope = "global"
+	}
+	return a.do(http.MethodPost, "/auth/v1/logout?scope="+scope, accessToken, nil)
+}
+
+// CreatePhoneUser creates a confirmed account for a phone number through the admin API,
+// for users who proved they own the number some other way, e.g. with a WhatsApp code
+func (a *SupabaseAuth) CreatePhoneUser(phone string, metadata map[string]any) (*models.AuthUser, error) {
+	if a.serviceRoleKey == "" {
+		return nil, ErrServiceRoleKeyMissing
+	}
+
+	response, err := a.client.Auth.WithToken(a.serviceRoleKey).AdminCreateUser(types.AdminCreateUserRequest{
+		Phone:        phone,
+		PhoneConfirm: true,
+		UserMetadata: metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user := toAuthUser(response.User)
+	return &user, nil
+}
+
+// UpdatePassword sets a new password for the user the access token belongs to
+func (a *SupabaseAuth) UpdatePassword(accessToken, newPassword string) error {
+	_, err := a.client.Auth.WithToken(accessToken).UpdateUser(types.UpdateUserRequest{Password: &newPassword})
+	return err
+}
+
+// SetPassword sets a user's password through the admin API, without their current password
+func (a *SupabaseAuth) SetPassword(userID uuid.UUID, newPassword string) error {
+	if a.serviceRoleKey == "" {
+		return ErrServiceRoleKeyMissing
+	}
+	_, err := a.client.Auth.WithToken(a.serviceRoleKey).AdminUpdateUser(types.AdminUpdateUserRequest{UserID: userID, Password: newPassword})
+	return err
+}
+
+// DeleteUser removes an account through the admin API
+func (a *SupabaseAuth) DeleteUser(userID uuid.UUID) error {
+	if a.serviceRoleKey == "" {
+		return ErrServiceRoleKeyMissing
+	}
+	return a.client.Auth.WithToken(a.serviceRoleKey).AdminDeleteUser(types.AdminDeleteUserRequest{UserID: userID})
+}
+
+// GetUser reads an account through the admin API
+func (a *SupabaseAuth) GetUser(userID uuid.UUID) (*models.AuthUser, error) {
+	if a.serviceRoleKey == "" {
+		return nil, ErrServiceRoleKeyMissing
+	}
+	resp, err := a.client.Auth.WithToken(a.serviceRoleKey).AdminGetUser(types.AdminGetUserRequest{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	user := toAuthUser(resp.User)
+	return &user, nil
+}
+
+// SetBanned bans an account through the admin API, which stops it signing in or refreshing
+// tokens, or lifts the ban
+func (a *SupabaseAuth) SetBanned(userID uuid.UUID, banned bool) error {
+	if a.serviceRoleKey == "" {
+		return ErrServiceRoleKeyMissing
+	}
+	duration := types.BanDurationNone()
+	if banned {
+		duration = types.BanDurationTime(accountBanDuration)
+	}
+	_, err := a.client.Auth.WithToken(a.serviceRoleKey).AdminUpdateUser(types.AdminUpdateUserRequest{UserID: userID, BanDuration: &duration})
+	return err
+}
+
+// ListUsers pages through the admin API's user list.
+// The GoTrue client only fetches the first page, so this calls the endpoint directly.
+func (a *SupabaseAuth) ListUsers(page, perPage int) ([]models.AuthUser, error) {
+	if a.serviceRoleKey == "" {
+		return nil, ErrServiceRoleKeyMissing
+	}
+
+	query := url.Values{}
+	query.Set("page", fmt.Sprintf("%d", page))
+	query.Set("per_page", fmt.Sprintf("%d", perPage))
+
+	var body types.AdminListUsersResponse
+	if err := a.do(http.MethodGet, "/auth/v1/admin/users?"+query.Encode(), a.serviceRoleKey, &body); err != nil {
+		return nil, err
+	}
+
+	users := make([]models.AuthUser, 0, len(body.Users))
+	for _, user := range body.Users {
+		users = append(users, toAuthUser(user))
+	}
+	return users, nil
+}
+
+// do calls a GoTrue endpoint directly, for the options the GoTrue client doesn't expose.
+// The response body is decoded into out unless out is nil.
+func (a *SupabaseAuth) do(method, path, bearerToken string, out any) error {
+	req, err := http.NewRequest(method, a.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apikey", a.anonKey)
+	req.Header.Set("Authorization", "Bearer "+bearerToken)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s failed with status %d", method, strings.SplitN(path, "?", 2)[0], resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// toAuthSession converts a GoTrue session
+func toAuthSession(session types.Session) *AuthSession {
+	return &AuthSession{
+		User:         toAuthUser(session.User),
+		AccessToken:  session.AccessToken,
+		RefreshToken: session.RefreshToken,
+		TokenType:    session.TokenType,
+		ExpiresIn:    session.ExpiresIn,
+	}
+}
+
+// toAuthUser converts a GoTrue user
+func toAuthUser(user types.User) models.AuthUser {
+	authUser := models.AuthUser{
+		ID:               user.ID,
+		ConfirmedAt:      user.EmailConfirmedAt,
+		PhoneConfirmedAt: user.PhoneConfirmedAt,
+		CreatedAt:        user.CreatedAt,
+		LastSignInAt:     user.LastSignInAt,
+		BannedUntil:      user.BannedUntil,
+		UserMetadata:     user.UserMetadata,
+	}
+	if user.Email != "" {
+		email := user.Email
+		authUser.Email = &email
+	}
+	if user.Phone != "" {
+		phone := user.Phone
+		authUser.Phone = &phone
+	}
+	return authUser
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
+)
+
+// Audited user management actions
+const (
+	AuditUserRoleChanged         = "user.role_changed"
+	AuditUserDisabled            = "user.disabled"
+	AuditUserEnabled             = "user.enabled"
+	AuditOfficerLocationAssigned = "officer.location_assigned"
+)
+
+// accountBanDuration is how long Supabase bans a disabled account; it has no permanent ban
+const accountBanDuration = 100 * 365 * 24 * time.Hour
+
+// AccountAdmin reads and bans accounts in the auth provider
+type AccountAdmin interface {
+	GetUser(userID uuid.UUID) (*models.AuthUser, error)
+	// SetBanned stops an account signing in or refreshing tokens, or lifts the ban
+	SetBanned(userID uuid.UUID, banned bool) error
+}
+
+// UserFilter narrows down user searches
+type UserFilter struct {
+	Query    string // Prefix of the username, email or phone number
+	Role     string
+	Disabled *bool
+}
+
+// UserAdminService lets admins find users, change their role, disable their account and
+// assign extension officers to a location. Users are identified by their auth user ID.
+type UserAdminService struct {
+	store    *repository.Store
+	auth     AccountAdmin
+	sessions *SessionService
+	audit    *AuditService
+}
+
+// NewUserAdminService creates a new user admin service; auth may be nil when no account is read or banned
+func NewUserAdminService(store *repository.Store, auth AccountAdmin, sessions *SessionService) *UserAdminService {
+	return &UserAdminService{
+		store:    store,
+		auth:     auth,
+		sessions: sessions,
+		audit:    NewAuditService(store),
+	}
+}
+
+// ListUsers returns a page of the users matching the filter, newest first, with their role
+func (s *UserAdminService) ListUsers(filter UserFilter, page, perPage int) ([]models.UserProfileWithRole, int64, error) {
+	roles, err := s.rolesByID()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	profileFilter := repository.ProfileFilter{Query: filter.Query, Disabled: filter.Disabled}
+	if filter.Role != "" {
+		role, err := s.role(filter.Role)
+		if err != nil {
+			return nil, 0, err
+		}
+		profileFilter.RoleID = &role.ID
+	}
+
+	profiles, total, err := s.store.Profiles.Search(profileFilter, page, perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users := make([]models.UserProfileWithRole, 0, len(profiles))
+	for _, profile := range profiles {
+		users = append(users, withRole(profile, roles))
+	}
+	return users, total, nil
+}
+
+// GetUser returns a user's profile along with their account
+func (s *UserAdminService) GetUser(authUserID string) (*models.UserProfileWithAuth, error) {
+	profile, err := s.profile(authUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := s.auth.GetUser(*profile.AuthUserID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.UserProfileWithAuth{UserProfile: *profile, AuthUser: account}, nil
+}
+
+// SetRole gives a user another role, creating the farmer or extension officer record the
+// role needs. Records of a previous role are kept, so switching back restores them.
+func (s *UserAdminService) SetRole(authUserID, roleName string, actx AuditContext) (*models.UserProfileWithRole, error) {
+	profile, err := s.profile(authUserID)
+	if err != nil {
+		return nil, err
+	}
+	if isActor(actx, profile) {
+		return nil, ErrUserSelfModification
+	}
+
+	role, err := s.role(roleName)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.rolesByID()
+	if err != nil {
+		return nil, err
+	}
+	previous := withRole(*profile, roles)
+	if profile.RoleID != nil && *profile.RoleID == role.ID {
+		return &previous, nil
+	}
+
+	err = s.store.Transact(func(tx *repository.Store) error {
+		if err := tx.Profiles.SetRole(profile.ID, role.ID); err != nil {
+			return err
+		}
+
+		missing, err := roleRecordMissing(tx, *profile.AuthUserID, role.Name)
+		if err != nil || !missing {
+			return err
+		}
+		signupData := &SignupData{}
+		if profile.Phone != nil {
+			signupData.PhoneNumber = *profile.Phone
+		}
+		username := ""
+		if profile.DisplayName != nil {
+			username = *profile.DisplayName
+		}
+		return NewProfileService(tx).createRoleSpecificRecord(authUserID, role.Name, username, signupData)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]any{"role": role.Name}
+	if previous.Role != nil {
+		details["previous_role"] = previous.Role.Name
+	}
+	s.audit.Record(actx, AuditUserRoleChanged, profile.AuthUserID, details)
+
+	profile.RoleID = &role.ID
+	updated := withRole(*profile, roles)
+	return &updated, nil
+}
+
+// SetDisabled disables or re-enables a user's account. A disabled account is banned from
+// signing in, its sessions are signed out and its API keys are revoked; enabling it again
+// lifts the ban but doesn't restore the keys.
+func (s *UserAdminService) SetDisabled(authUserID string, disabled bool, actx AuditContext) (*models.UserProfileWithRole, error) {
+	profile, err := s.profile(authUserID)
+	if err != nil {
+		return nil, err
+	}
+	if isActor(actx, profile) {
+		return nil, ErrUserSelfModification
+	}
+	roles, err := s.rolesByID()
+	if err != nil {
+		return nil, err
+	}
+	if (profile.DisabledAt != nil) == disabled {
+		unchanged := withRole(*profile, roles)
+		return &unchanged, nil
+	}
+
+	// Ban the account first: should recording it fail, retrying bans it again harmlessly
+	if err := s.auth.SetBanned(*profile.AuthUserID, disabled); err != nil {
+		return nil, err
+	}
+
+	var disabledAt *time.Time
+	if disabled {
+		now := time.Now()
+		disabledAt = &now
+	}
+	if err := s.store.Profiles.SetDisabled(profile.ID, disabledAt); err != nil {
+		return nil, err
+	}
+	profile.DisabledAt = disabledAt
+
+	action := AuditUserEnabled
+	details := map[string]any{}
+	if disabled {
+		action = AuditUserDisabled
+		if err := s.sessions.RevokeAll(authUserID); err != nil {
+			return nil, err
+		}
+		revoked, err := revokeOwnedAPIKeys(s.store, *profile.AuthUserID)
+		if err != nil {
+			return nil, err
+		}
+		details["api_keys_revoked"] = revoked
+	}
+	s.audit.Record(actx, action, profile.AuthUserID, details)
+
+	updated := withRole(*profile, roles)
+	return &updated, nil
+}
+
+// AssignOfficerLocation assigns an extension officer to a location; nil unassigns them
+func (s *UserAdminService) AssignOfficerLocation(authUserID string, locationID *int64, actx AuditContext) (*models.ExtensionOfficer, error) {
+	if locationID != nil && *locationID < 1 {
+		return nil, ErrLocationIDInvalid
+	}
+
+	officer, err := s.store.Officers.GetByAuthUserID(authUserID)
+	if err == repository.ErrNotFound {
+		return nil, ErrOfficerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.store.Officers.SetAssignedLocation(officer.ID, locationID)
+	if err == repository.ErrNotFound {
+		return nil, ErrOfficerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actx, AuditOfficerLocationAssigned, officer.AuthUserID, map[string]any{
+		"officer_id":                    officer.ID,
+		"assigned_location_id":          locationID,
+		"previous_assigned_location_id": officer.AssignedLocationID,
+	})
+	return updated, nil
+}
+
+// profile loads the profile of an auth user
+func (s *UserAdminService) profile(authUserID string) (*models.UserProfile, error) {
+	if _, err := uuid.Parse(authUserID); err != nil {
+		return nil, ErrProfileNotFound
+	}
+	profile, err := s.store.Profiles.GetByAuthUserID(authUserID)
+	if err == repository.ErrNotFound {
+		return nil, ErrProfileNotFound
+	}
+	return profile, err
+}
+
+// role loads a role by name
+func (s *UserAdminService) role(name string) (*models.Role, error) {
+	role, err := s.store.Roles.GetByName(name)
+	if err == repository.ErrNotFound {
+		return nil, ErrRoleNotFound
+	}
+	return role, err
+}
+
+// rolesByID loads every role; there are only a handful
+func (s *UserAdminService) rolesByID() (map[uuid.UUID]models.Role, error) {
+	roles, err := s.store.Roles.List()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]models.Role, len(roles))
+	for _, role := range roles {
+		byID[role.ID] = role
+	}
+	return byID, nil
+}
+
+// withRole pairs a profile with its role, if it has a known one
+func withRole(profile models.UserProfile, roles map[uuid.UUID]models.Role) models.UserProfileWithRole {
+	user := models.UserProfileWithRole{UserProfile: profile}
+	if profile.RoleID != nil {
+		if role, ok := roles[*profile.RoleID]; ok {
+			user.Role = &role
+		}
+	}
+	return user
+}
+
+// isActor reports whether the profile belongs to the admin making the change
+func isActor(actx AuditContext, profile *models.UserProfile) bool {
+	return actx.ActorID != nil && profile.AuthUserID != nil && *actx.ActorID == *profile.AuthUserID
+}
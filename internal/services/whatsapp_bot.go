@@ -1,10 +1,13 @@
 package services
 
 import (
-	"log"
+	"log/slog"
+	"strings"
+	"sync"
 
 	chatbot "github.com/green-api/whatsapp-chatbot-golang"
 	"github.com/okoye-dev/flux-server/internal/bot"
+	"github.com/okoye-dev/flux-server/internal/repository"
 )
 
 // WhatsAppBot represents the WhatsApp bot service
@@ -12,43 +15,139 @@ type WhatsAppBot struct {
 	bot       *chatbot.Bot
 	aiService *bot.AIService
 	mainScene *bot.MainBotScene
+	states    *lockedStateManager
 }
 
-// NewWhatsAppBot creates a new WhatsApp bot instance
-func NewWhatsAppBot(instanceID, token string) *WhatsAppBot {
+// NewWhatsAppBot creates a new WhatsApp bot instance; auth reads and deletes the accounts of
+// farmers who delete their data from WhatsApp
+func NewWhatsAppBot(instanceID, token string, store *repository.Store, auth AccountManager) *WhatsAppBot {
 	chatbotInstance := chatbot.NewBot(instanceID, token)
 	
-	// Initialize AI service
-	aiService := bot.NewAIService()
+	// Conversations are read and dropped from API requests too, so access is serialized
+	states := &lockedStateManager{StateManager: chatbotInstance.StateManager}
+	chatbotInstance.StateManager = states
+	
+	w := &WhatsAppBot{
+		bot:       chatbotInstance,
+		aiService: bot.NewAIService(),
+		states:    states,
+	}
 	
 	// Initialize main scene with all sub-scenes
-	mainScene := bot.NewMainBotScene(aiService)
+	privacy := NewPrivacyService(store, auth, w, NewSessionService(store))
+	w.mainScene = bot.NewMainBotScene(w.aiService, NewFeedbackService(store), NewFarmerService(store), privacy)
 	
 	// Set the main scene as the start scene
-	chatbotInstance.SetStartScene(*mainScene)
+	chatbotInstance.SetStartScene(*w.mainScene)
 	
-	return &WhatsAppBot{
-		bot:       chatbotInstance,
-		aiService: aiService,
-		mainScene: mainScene,
+	return w
+}
+
+// Conversation returns a copy of the bot's state for the chat with a phone number, or nil
+func (w *WhatsAppBot) Conversation(phoneNumber string) map[string]any {
+	return w.states.copyStateData(chatIDFromPhone(phoneNumber))
+}
+
+// ForgetConversation drops the bot's state for the chat with a phone number
+func (w *WhatsAppBot) ForgetConversation(phoneNumber string) {
+	w.states.Delete(chatIDFromPhone(phoneNumber))
+}
+
+// chatIDFromPhone turns a phone number ("+2348012345678") into a WhatsApp chat ID ("2348012345678@c.us")
+func chatIDFromPhone(phoneNumber string) string {
+	return strings.TrimPrefix(phoneNumber, "+") + "@c.us"
+}
+
+// lockedStateManager guards the bot's state manager, which isn't safe for concurrent use
+type lockedStateManager struct {
+	mu sync.Mutex
+	chatbot.StateManager
+}
+
+func (m *lockedStateManager) Get(stateID string) chatbot.State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.StateManager.Get(stateID)
+}
+
+func (m *lockedStateManager) Create(stateID string) chatbot.State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.StateManager.Create(stateID)
+}
+
+func (m *lockedStateManager) Delete(stateID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.StateManager.Delete(stateID)
+}
+
+func (m *lockedStateManager) GetStateData(stateID string) map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.StateManager.GetStateData(stateID)
+}
+
+func (m *lockedStateManager) SetStateData(stateID string, stateData map[string]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.StateManager.SetStateData(stateID, stateData)
+}
+
+func (m *lockedStateManager) UpdateStateData(stateID string, stateData map[string]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.StateManager.UpdateStateData(stateID, stateData)
+}
+
+func (m *lockedStateManager) DeleteStateData(stateID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.StateManager.DeleteStateData(stateID)
+}
+
+func (m *lockedStateManager) ActivateNextScene(stateID string, scene chatbot.Scene) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.StateManager.ActivateNextScene(stateID, scene)
+}
+
+func (m *lockedStateManager) GetCurrentScene(stateID string) chatbot.Scene {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.StateManager.GetCurrentScene(stateID)
+}
+
+// copyStateData returns a copy of a chat's state data, or nil when there is none
+func (m *lockedStateManager) copyStateData(stateID string) map[string]any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data := m.StateManager.GetStateData(stateID)
+	if len(data) == 0 {
+		return nil
+	}
+	copied := make(map[string]any, len(data))
+	for key, value := range data {
+		copied[key] = value
 	}
+	return copied
 }
 
 // Start starts the WhatsApp bot using Green API polling
 func (w *WhatsAppBot) Start() {
-	log.Println("Starting WhatsApp bot with Green API polling...")
+	slog.Info("Starting WhatsApp bot with Green API polling")
 	
 	// Handle errors from the bot
 	go func() {
 		for err := range w.bot.ErrorChannel {
 			if err != nil {
-				log.Printf("WhatsApp bot error: %v", err)
+				slog.Error("WhatsApp bot error", "error", err)
 			}
 		}
 	}()
 	
 	// Start receiving notifications using Green API polling
 	w.bot.StartReceivingNotifications()
-	log.Println("WhatsApp bot started successfully and polling for messages...")
+	slog.Info("WhatsApp bot started and polling for messages")
 }
 
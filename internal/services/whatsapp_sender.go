@@ -0,0 +1,56 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	greenapi "github.com/green-api/whatsapp-api-client-golang-v2"
+)
+
+// defaultGreenAPIURL is the Green API host the chatbot library uses
+const defaultGreenAPIURL = "https://api.green-api.com"
+
+// MessageSender delivers a text message to a phone number
+type MessageSender interface {
+	SendText(phoneNumber, message string) error
+}
+
+// WhatsAppSender sends messages through the Green API instance the bot runs on
+type WhatsAppSender struct {
+	api greenapi.GreenAPI
+}
+
+// NewWhatsAppSender creates a sender for a Green API instance; an empty apiURL uses the default host
+func NewWhatsAppSender(apiURL, instanceID, token string) (*WhatsAppSender, error) {
+	if instanceID == "" || token == "" {
+		return nil, ErrWhatsAppNotConfigured
+	}
+	if apiURL == "" {
+		apiURL = defaultGreenAPIURL
+	}
+
+	return &WhatsAppSender{api: greenapi.GreenAPI{
+		APIURL:           strings.TrimRight(apiURL, "/"),
+		MediaURL:         "https://media.green-api.com",
+		IDInstance:       instanceID,
+		APITokenInstance: token,
+	}}, nil
+}
+
+// SendText sends a text message to the WhatsApp account of a phone number
+func (s *WhatsAppSender) SendText(phoneNumber, message string) error {
+	variants := phoneNumberVariants(phoneNumber)
+	if variants == nil {
+		return fmt.Errorf("invalid phone number %q", phoneNumber)
+	}
+
+	// Chat IDs are the digits of the number, e.g. "2348012345678@c.us"
+	response, err := s.api.Sending().SendMessage(variants[1]+"@c.us", message)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("green API sendMessage failed with status %d", response.StatusCode)
+	}
+	return nil
+}
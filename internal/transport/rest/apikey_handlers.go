@@ -0,0 +1,67 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+)
+
+// APIKeysHandler handles service API keys (GET list, POST create; admins only)
+func (h *Handler) APIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		keys, err := h.apiKeys.ListKeys()
+		if err != nil {
+			WriteServiceError(w, err, "Failed to list API keys")
+			return
+		}
+
+		response := APIKeysListResponse{APIKeys: make([]APIKeyResponse, 0, len(keys))}
+		for _, key := range keys {
+			response.APIKeys = append(response.APIKeys, newAPIKeyResponse(key))
+		}
+		WriteSuccessResponse(w, http.StatusOK, MsgAPIKeysRetrieved, response)
+	case http.MethodPost:
+		var req models.CreateAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+			return
+		}
+
+		created, err := h.apiKeys.CreateKey(req, auditContext(r))
+		if err != nil {
+			WriteServiceError(w, err, "Failed to create API key")
+			return
+		}
+
+		response := newAPIKeyResponse(created.APIKey)
+		response.Key = created.Key
+		WriteSuccessResponse(w, http.StatusCreated, MsgAPIKeyCreated, response)
+	default:
+		WriteMethodNotAllowedError(w)
+	}
+}
+
+// APIKeyHandler revokes a service API key (admins only)
+func (h *Handler) APIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	keyID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		WriteBadRequestError(w, MsgInvalidAPIKeyID, "")
+		return
+	}
+
+	key, err := h.apiKeys.RevokeKey(keyID, auditContext(r))
+	if err != nil {
+		WriteServiceError(w, err, "Failed to revoke API key")
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgAPIKeyRevoked, newAPIKeyResponse(*key))
+}
@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/services"
+)
+
+// AuditLogHandler lists audit events, newest first, filtered by ?actor_id, ?target_id,
+// ?action (a prefix), ?request_id, ?ip_address, ?from and ?to (admins only)
+func (h *Handler) AuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	filter, ok := parseAuditFilter(w, r)
+	if !ok {
+		return
+	}
+
+	page, perPage := parsePagination(r)
+	events, total, err := h.audit.List(filter, page, perPage)
+	if err != nil {
+		WriteServiceError(w, err, "Failed to list audit events")
+		return
+	}
+	if events == nil {
+		events = []models.AuditEvent{}
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgAuditLogRetrieved, AuditLogResponse{
+		Events:     events,
+		Pagination: newPagination(page, perPage, total),
+	})
+}
+
+// parseAuditFilter reads the audit log query parameters, writing a 400 response when one is malformed
+func parseAuditFilter(w http.ResponseWriter, r *http.Request) (services.AuditFilter, bool) {
+	query := r.URL.Query()
+	filter := services.AuditFilter{
+		Action:    strings.TrimSpace(query.Get("action")),
+		RequestID: strings.TrimSpace(query.Get("request_id")),
+		IPAddress: strings.TrimSpace(query.Get("ip_address")),
+	}
+
+	for name, target := range map[string]**uuid.UUID{"actor_id": &filter.ActorID, "target_id": &filter.TargetID} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		id, err := uuid.Parse(value)
+		if err != nil {
+			WriteBadRequestError(w, MsgInvalidRequest, name+" must be a UUID")
+			return filter, false
+		}
+		*target = &id
+	}
+
+	from, err := parseDateParam(r, "from", false)
+	if err != nil {
+		WriteBadRequestError(w, MsgInvalidRequest, err.Error())
+		return filter, false
+	}
+	to, err := parseDateParam(r, "to", true)
+	if err != nil {
+		WriteBadRequestError(w, MsgInvalidRequest, err.Error())
+		return filter, false
+	}
+
+	filter.From = from
+	filter.To = to
+	return filter, true
+}
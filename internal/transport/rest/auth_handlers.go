@@ -4,13 +4,19 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/config"
+	"github.com/okoye-dev/flux-server/internal/identity"
+	"github.com/okoye-dev/flux-server/internal/logging"
+	"github.com/okoye-dev/flux-server/internal/metrics"
+	"github.com/okoye-dev/flux-server/internal/middleware"
 	"github.com/okoye-dev/flux-server/internal/services"
-	"github.com/supabase-community/gotrue-go/types"
-	"github.com/supabase-community/supabase-go"
 )
 
 // UserClaims represents JWT claims
@@ -20,16 +26,17 @@ type UserClaims struct {
 	jwt.RegisteredClaims
 }
 
-// SignupHandler handles user signup with username/password only
-func SignupHandler(w http.ResponseWriter, r *http.Request) {
+// SignupHandler handles user signup with a username, password and optional real email.
+// The auth account, profile and role record are created together or not at all.
+func (h *Handler) SignupHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		WriteMethodNotAllowedError(w)
 		return
 	}
 
 	var req SignupRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
 		return
 	}
 
@@ -45,42 +52,16 @@ func SignupHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get Supabase configuration
-	supabaseURL := os.Getenv("SUPABASE_URL")
-	supabaseAnonKey := os.Getenv("SUPABASE_ANON_KEY")
-
-	if supabaseURL == "" || supabaseAnonKey == "" {
-		http.Error(w, "Supabase configuration missing", http.StatusInternalServerError)
-		return
-	}
-
-	// Create Supabase client with anon key
-	client, err := supabase.NewClient(supabaseURL, supabaseAnonKey, nil)
+	auth, err := newSupabaseAuth()
 	if err != nil {
-		http.Error(w, "Failed to create Supabase client", http.StatusInternalServerError)
+		WriteMissingConfigError(w, "SUPABASE_URL and SUPABASE_ANON_KEY are required")
 		return
 	}
 
-	// Create user in Supabase auth.users table using admin API
-	// We'll use username@fluxapp.com as the email format for Supabase
-	email := req.Username + "@fluxapp.com"
-	
-	authResponse, err := client.Auth.Signup(types.SignupRequest{
-		Email:    email,
-		Password: req.Password,
-		Data: map[string]interface{}{
-			"username": req.Username,
-		},
-	})
+	// Accounts without a real email get a synthetic one for the username
+	email, err := h.newIdentityService(auth).SignupEmail(req.Username, req.Email)
 	if err != nil {
-		http.Error(w, "Failed to create user: "+err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	// Create user profile automatically
-	profileService, err := services.NewProfileService()
-	if err != nil {
-		http.Error(w, "Failed to create profile service: "+err.Error(), http.StatusInternalServerError)
+		WriteServiceError(w, err, MsgFailedToCreateUser)
 		return
 	}
 
@@ -93,96 +74,477 @@ func SignupHandler(w http.ResponseWriter, r *http.Request) {
 		AssignedLocationID: req.AssignedLocationID,
 	}
 
-	_, err = profileService.CreateUserProfile(authResponse.User.ID.String(), req.Username, req.Role, signupData)
+	actx := auditContext(r)
+	signupService := services.NewSignupService(auth, services.NewProfileService(h.store))
+	user, err := signupService.Signup(email, req.Password, req.Username, req.Role, signupData, actx)
 	if err != nil {
-		// Log error but don't fail signup - user is created in auth
-		// TODO: Add proper logging
-		_ = err
+		WriteServiceError(w, err, MsgFailedToCreateUser)
+		return
 	}
+	h.audit.Record(actx, services.AuditSignedUp, &user.ID, map[string]any{"username": req.Username})
 
-	// Return the response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(AuthResponse{
+	WriteAuthResponse(w, http.StatusCreated, AuthResponse{
 		User: UserInfo{
-			ID:        authResponse.User.ID.String(),
+			ID:        user.ID.String(),
 			Username:  req.Username,
-			CreatedAt: authResponse.User.CreatedAt,
+			CreatedAt: user.CreatedAt,
 		},
 		AccessToken: "", // No token on signup, user needs to sign in
 		TokenType:   "",
 		ExpiresIn:   0,
-		Message:     "User created successfully. Profile will be created automatically. Please sign in to get your access token.",
+		Message:     MsgUserCreatedSuccessfully,
 	})
 }
 
-// SigninHandler handles user signin with username/password only
-func SigninHandler(w http.ResponseWriter, r *http.Request) {
+// newSupabaseAuth creates the Supabase Auth provider from the environment.
+// The service role key is optional here; without it a failed signup can't be rolled back.
+func newSupabaseAuth() (*services.SupabaseAuth, error) {
+	return services.NewSupabaseAuth(
+		os.Getenv("SUPABASE_URL"),
+		os.Getenv("SUPABASE_ANON_KEY"),
+		os.Getenv("SUPABASE_SERVICE_ROLE_KEY"),
+	)
+}
+
+// newWhatsAppSender creates the sender for the bot's Green API instance from the environment
+func newWhatsAppSender() (*services.WhatsAppSender, error) {
+	return services.NewWhatsAppSender(
+		os.Getenv("API_URL"),
+		os.Getenv("WHATSAPP_INSTANCE_ID"),
+		os.Getenv("WHATSAPP_TOKEN"),
+	)
+}
+
+// SigninHandler handles user signin with a username, email address or phone number and password
+func (h *Handler) SigninHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		WriteMethodNotAllowedError(w)
 		return
 	}
 
 	var req SigninRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
 		return
 	}
 
 	// Validate required fields
-	if req.Username == "" || req.Password == "" {
-		http.Error(w, "Username and password are required", http.StatusBadRequest)
+	identifier := loginIdentifier(req.Identifier, req.Username)
+	if identifier == "" || req.Password == "" {
+		WriteBadRequestError(w, MsgIdentifierPasswordRequired, "")
 		return
 	}
 
-	// Get Supabase configuration
-	supabaseURL := os.Getenv("SUPABASE_URL")
-	supabaseAnonKey := os.Getenv("SUPABASE_ANON_KEY")
+	auth, err := newSupabaseAuth()
+	if err != nil {
+		WriteMissingConfigError(w, "SUPABASE_URL and SUPABASE_ANON_KEY are required")
+		return
+	}
 
-	if supabaseURL == "" || supabaseAnonKey == "" {
-		http.Error(w, "Supabase configuration missing", http.StatusInternalServerError)
+	// Refuse sign-ins to locked out accounts and IP addresses before checking the password
+	identities := h.newIdentityService(auth)
+	lockout := services.NewLockoutService(h.store, identities)
+	actx := auditContext(r)
+	if wait, err := lockout.Check(identifier, actx.IPAddress); err != nil {
+		if errors.Is(err, services.ErrSignInLocked) {
+			setRetryAfter(w, wait)
+		}
+		WriteServiceError(w, err, MsgFailedToSignIn)
 		return
 	}
 
-	// Create Supabase client with anon key
-	client, err := supabase.NewClient(supabaseURL, supabaseAnonKey, nil)
+	session, err := identities.SignIn(identifier, req.Password)
+	if errors.Is(err, services.ErrSignInFailed) {
+		metrics.AuthAttempts.Inc("password", "failure")
+		h.audit.Record(actx, services.AuditSignInFailed, signInTarget(identities, identifier), map[string]any{"method": "password", "identifier": identity.Mask(identifier)})
+		lockout.RecordFailure(identifier, actx)
+		// The reason would tell callers whether an account exists, so only the log gets it
+		logging.FromContext(r.Context()).Info("Sign-in failed", "reason", err)
+		WriteAuthError(w, MsgFailedToSignIn, services.ErrSignInFailed.Message)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Failed to create Supabase client", http.StatusInternalServerError)
+		WriteServiceError(w, err, MsgFailedToSignIn)
 		return
 	}
+	lockout.RecordSuccess(identifier)
+	h.recordSignIn(actx, session, "password")
+
+	WriteAuthResponse(w, http.StatusOK, newAuthResponse(session, req.Username, MsgSignInSuccessful))
+}
 
-	// Sign in using the email format (username@fluxapp.com)
-	email := req.Username + "@fluxapp.com"
-	authResponse, err := client.Auth.SignInWithEmailPassword(email, req.Password)
+// RefreshHandler exchanges a refresh token for a new access token and refresh token
+func (h *Handler) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+		return
+	}
+	if req.RefreshToken == "" {
+		WriteBadRequestError(w, MsgRefreshTokenRequired, "")
+		return
+	}
+
+	auth, err := newSupabaseAuth()
+	if err != nil {
+		WriteMissingConfigError(w, "SUPABASE_URL and SUPABASE_ANON_KEY are required")
+		return
+	}
+
+	session, err := auth.Refresh(req.RefreshToken)
 	if err != nil {
-		http.Error(w, "Failed to sign in user: "+err.Error(), http.StatusUnauthorized)
+		metrics.AuthAttempts.Inc("refresh", "failure")
+		WriteAuthError(w, MsgFailedToRefreshToken, err.Error())
 		return
 	}
 
-	// Extract username from user metadata
-	username := req.Username
-	if userMetadata := authResponse.User.UserMetadata; userMetadata != nil {
-		if metaUsername, exists := userMetadata["username"]; exists {
-			if usernameStr, ok := metaUsername.(string); ok {
-				username = usernameStr
-			}
+	// Refreshed tokens keep their session, so a signed-out session must not be revived
+	claims, err := middleware.ParseToken(session.AccessToken)
+	if err != nil {
+		WriteInvalidTokenError(w)
+		return
+	}
+	revoked, err := h.sessions.SessionRevoked(claims.Sub, claims.SessionID, time.Unix(claims.Iat, 0))
+	if err != nil {
+		WriteInternalServerError(w, MsgFailedToRefreshToken, err.Error())
+		return
+	}
+	if revoked {
+		metrics.AuthAttempts.Inc("refresh", "failure")
+		if err := auth.SignOut(session.AccessToken, false); err != nil {
+			logging.FromContext(r.Context()).Warn("Failed to sign out revoked session", "session_id", claims.SessionID, "error", err)
 		}
+		WriteServiceError(w, services.ErrSessionRevoked, MsgFailedToRefreshToken)
+		return
+	}
+	metrics.AuthAttempts.Inc("refresh", "success")
+
+	WriteAuthResponse(w, http.StatusOK, newAuthResponse(session, "", MsgTokenRefreshed))
+}
+
+// OTPRequestHandler sends a one-time sign-in code to a farmer's WhatsApp number
+func (h *Handler) OTPRequestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowedError(w)
+		return
 	}
 
-	// Return the response with access token
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(AuthResponse{
+	var req OTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+		return
+	}
+	if req.PhoneNumber == "" {
+		WriteBadRequestError(w, MsgPhoneNumberRequired, "")
+		return
+	}
+
+	otp, err := h.newOTPService()
+	if err != nil {
+		WriteServiceError(w, err, MsgFailedToSendOTP)
+		return
+	}
+
+	if err := otp.RequestCode(req.PhoneNumber); err != nil {
+		WriteServiceError(w, err, MsgFailedToSendOTP)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgOTPSent, nil)
+}
+
+// OTPVerifyHandler signs a farmer in with the code sent to their WhatsApp number.
+// The session belongs to the account linked to the farmer record the bot registered.
+func (h *Handler) OTPVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	var req OTPVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+		return
+	}
+	if req.PhoneNumber == "" || req.Code == "" {
+		WriteBadRequestError(w, MsgPhoneNumberAndCodeRequired, "")
+		return
+	}
+
+	otp, err := h.newOTPService()
+	if err != nil {
+		WriteServiceError(w, err, MsgFailedToVerifyOTP)
+		return
+	}
+
+	actx := auditContext(r)
+	session, err := otp.VerifyCode(req.PhoneNumber, req.Code, actx)
+	if errors.Is(err, services.ErrOTPInvalid) || errors.Is(err, services.ErrOTPAttemptsExceeded) {
+		metrics.AuthAttempts.Inc("whatsapp_code", "failure")
+		h.audit.Record(actx, services.AuditSignInFailed, nil, map[string]any{"method": "whatsapp_code"})
+	}
+	if err != nil {
+		WriteServiceError(w, err, MsgFailedToVerifyOTP)
+		return
+	}
+	h.recordSignIn(actx, session, "whatsapp_code")
+
+	WriteAuthResponse(w, http.StatusOK, newAuthResponse(session, "", MsgSignInSuccessful))
+}
+
+// signInTarget returns the account a failed sign-in was for, or nil when the identifier
+// matches no profile
+func signInTarget(identities *services.IdentityService, identifier string) *uuid.UUID {
+	profile, err := identities.FindProfile(identifier)
+	if err != nil {
+		return nil
+	}
+	return profile.AuthUserID
+}
+
+// recordSignIn records a successful sign-in in the metrics and the audit log, as an action
+// of the signed-in user
+func (h *Handler) recordSignIn(actx services.AuditContext, session *services.AuthSession, method string) {
+	metrics.AuthAttempts.Inc(method, "success")
+	actx.ActorID = &session.User.ID
+	h.audit.Record(actx, services.AuditSignInSucceeded, &session.User.ID, map[string]any{"method": method})
+}
+
+// newOTPService creates the OTP service from the environment; codes are sent through
+// the Green API instance of the WhatsApp bot
+func (h *Handler) newOTPService() (*services.OTPService, error) {
+	auth, err := newSupabaseAuth()
+	if err != nil {
+		return nil, err
+	}
+	sender, err := newWhatsAppSender()
+	if err != nil {
+		return nil, err
+	}
+
+	// Codes are signed in with HS256 tokens, so they need the shared secret even when
+	// Supabase's own tokens are verified with JWKS keys
+	supabase := config.Load().Supabase
+	if supabase.JWTSecret == "" {
+		return nil, services.ErrJWTSecretMissing
+	}
+
+	// The auth middleware only accepts tokens with the configured issuer and audience
+	return services.NewOTPService(h.store, auth, sender, supabase.JWTSecret, supabase.JWTIssuer, supabase.JWTAudience), nil
+}
+
+// ChangePasswordHandler sets a new password after checking the current one (protected route)
+func (h *Handler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	claims, ok := middleware.GetClaims(r)
+	if !ok {
+		WriteInternalServerError(w, MsgUserIDNotFound, "")
+		return
+	}
+	authUserID, err := uuid.Parse(claims.Sub)
+	if err != nil {
+		WriteInvalidTokenError(w)
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+		return
+	}
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		WriteBadRequestError(w, MsgPasswordsRequired, "")
+		return
+	}
+
+	auth, err := newSupabaseAuth()
+	if err != nil {
+		WriteMissingConfigError(w, "SUPABASE_URL and SUPABASE_ANON_KEY are required")
+		return
+	}
+
+	passwords := services.NewPasswordService(h.store, auth, nil, nil, h.sessions, "")
+	err = passwords.ChangePassword(authUserID, claims.Email, req.CurrentPassword, req.NewPassword, auditContext(r))
+	if err != nil {
+		WriteServiceError(w, err, MsgFailedToChangePassword)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgPasswordChanged, nil)
+}
+
+// PasswordResetHandler sends a password reset code to the WhatsApp number on the user's
+// profile. It answers the same whether or not the user exists.
+func (h *Handler) PasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	var req PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+		return
+	}
+	identifier := loginIdentifier(req.Identifier, req.Username)
+	if identifier == "" {
+		WriteBadRequestError(w, MsgIdentifierRequired, "")
+		return
+	}
+
+	passwords, err := h.newPasswordResetService()
+	if err != nil {
+		WriteServiceError(w, err, MsgFailedToResetPassword)
+		return
+	}
+
+	if err := passwords.RequestReset(identifier, auditContext(r)); err != nil {
+		WriteServiceError(w, err, MsgFailedToResetPassword)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgPasswordResetRequested, nil)
+}
+
+// PasswordResetConfirmHandler sets a new password with a reset code and signs the user
+// out everywhere
+func (h *Handler) PasswordResetConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	var req PasswordResetConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+		return
+	}
+	identifier := loginIdentifier(req.Identifier, req.Username)
+	if identifier == "" || req.Code == "" || req.NewPassword == "" {
+		WriteBadRequestError(w, MsgResetFieldsRequired, "")
+		return
+	}
+
+	passwords, err := h.newPasswordResetService()
+	if err != nil {
+		WriteServiceError(w, err, MsgFailedToResetPassword)
+		return
+	}
+
+	if err := passwords.ConfirmReset(identifier, req.Code, req.NewPassword, auditContext(r)); err != nil {
+		WriteServiceError(w, err, MsgFailedToResetPassword)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgPasswordReset, nil)
+}
+
+// newPasswordResetService creates the password service from the environment; reset codes
+// are sent through the Green API instance of the WhatsApp bot
+func (h *Handler) newPasswordResetService() (*services.PasswordService, error) {
+	auth, err := newSupabaseAuth()
+	if err != nil {
+		return nil, err
+	}
+	sender, err := newWhatsAppSender()
+	if err != nil {
+		return nil, err
+	}
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		return nil, services.ErrJWTSecretMissing
+	}
+
+	return services.NewPasswordService(h.store, auth, h.newIdentityService(auth), sender, h.sessions, jwtSecret), nil
+}
+
+// newIdentityService creates the identity service with the synthetic email domains from the environment
+func (h *Handler) newIdentityService(auth services.SignInProvider) *services.IdentityService {
+	supabase := config.Load().Supabase
+	return services.NewIdentityService(h.store, auth, identity.NewDomain(supabase.EmailDomain, supabase.LegacyEmailDomains...))
+}
+
+// SignoutHandler signs out the caller's current session (protected route)
+func (h *Handler) SignoutHandler(w http.ResponseWriter, r *http.Request) {
+	h.signout(w, r, false)
+}
+
+// SignoutAllHandler signs out every session of the caller (protected route)
+func (h *Handler) SignoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	h.signout(w, r, true)
+}
+
+// signout revokes the caller's session, or all of their sessions, here and in Supabase.
+// The local revocation is what stops access tokens; Supabase revokes the refresh tokens.
+func (h *Handler) signout(w http.ResponseWriter, r *http.Request, everywhere bool) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	claims, ok := middleware.GetClaims(r)
+	if !ok {
+		WriteInternalServerError(w, MsgUserIDNotFound, "")
+		return
+	}
+	accessToken, _ := middleware.GetAccessToken(r)
+
+	var err error
+	message := MsgSignedOut
+	action := services.AuditSignedOut
+	if everywhere {
+		err = h.sessions.RevokeAll(claims.Sub)
+		message = MsgSignedOutEverywhere
+		action = services.AuditSignedOutEverywhere
+	} else {
+		err = h.sessions.Revoke(claims.Sub, claims.SessionID, time.Unix(claims.Exp, 0))
+	}
+	if err != nil {
+		WriteServiceError(w, err, "Failed to sign out")
+		return
+	}
+	actx := auditContext(r)
+	h.audit.Record(actx, action, actx.ActorID, nil)
+
+	// Supabase refuses new tokens for the session once its refresh tokens are revoked;
+	// failing that, refreshed tokens are still rejected because they keep the session ID
+	if auth, err := newSupabaseAuth(); err == nil {
+		if err := auth.SignOut(accessToken, everywhere); err != nil {
+			logging.FromContext(r.Context()).Warn("Failed to revoke Supabase refresh tokens", "user_id", claims.Sub, "error", err)
+		}
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, message, nil)
+}
+
+// newAuthResponse builds the response for a signed-in session, taking the username
+// from the account metadata when it has one
+func newAuthResponse(session *services.AuthSession, username, message string) AuthResponse {
+	if metaUsername, ok := session.User.UserMetadata["username"].(string); ok {
+		username = metaUsername
+	}
+
+	return AuthResponse{
 		User: UserInfo{
-			ID:        authResponse.User.ID.String(),
+			ID:        session.User.ID.String(),
 			Username:  username,
-			CreatedAt: authResponse.User.CreatedAt,
+			CreatedAt: session.User.CreatedAt,
 		},
-		AccessToken: authResponse.AccessToken,
-		TokenType:   authResponse.TokenType,
-		ExpiresIn:   authResponse.ExpiresIn,
-		Message:     "Sign in successful",
-	})
+		AccessToken:  session.AccessToken,
+		RefreshToken: session.RefreshToken,
+		TokenType:    session.TokenType,
+		ExpiresIn:    session.ExpiresIn,
+		Message:      message,
+	}
 }
 
 // generateUserID generates a random user ID (not used with Supabase auth)
@@ -0,0 +1,162 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/services"
+)
+
+// CropsHandler handles the crop catalogue (GET search, POST create for admins)
+func (h *Handler) CropsHandler(w http.ResponseWriter, r *http.Request) {
+	catalogueService := services.NewCatalogueService(h.store)
+
+	switch r.Method {
+	case http.MethodGet:
+		page, perPage := parsePagination(r)
+		crops, total, err := catalogueService.ListCrops(parseCatalogueSearch(r), page, perPage)
+		if err != nil {
+			WriteServiceError(w, err, "Failed to list crops")
+			return
+		}
+
+		WriteSuccessResponse(w, http.StatusOK, MsgCropsRetrieved, CropsListResponse{
+			Crops:      crops,
+			Pagination: newPagination(page, perPage, total),
+		})
+	case http.MethodPost:
+		var req models.CreateCropRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+			return
+		}
+
+		crop, err := catalogueService.CreateCrop(req, auditContext(r))
+		if err != nil {
+			WriteServiceError(w, err, "Failed to create crop")
+			return
+		}
+
+		WriteSuccessResponse(w, http.StatusCreated, MsgCropCreated, crop)
+	default:
+		WriteMethodNotAllowedError(w)
+	}
+}
+
+// CropHandler handles a single crop
+func (h *Handler) CropHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	cropID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		WriteBadRequestError(w, MsgInvalidCropID, "")
+		return
+	}
+
+	catalogueService := services.NewCatalogueService(h.store)
+
+	crop, err := catalogueService.GetCrop(cropID)
+	if err != nil {
+		WriteServiceError(w, err, "Failed to get crop")
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgCropRetrieved, crop)
+}
+
+// CropMergeHandler merges duplicate crops into one canonical crop (admins only)
+func (h *Handler) CropMergeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	var req models.MergeCropsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+		return
+	}
+
+	catalogueService := services.NewCatalogueService(h.store)
+
+	result, err := catalogueService.MergeCrops(req, auditContext(r))
+	if err != nil {
+		WriteServiceError(w, err, "Failed to merge crops")
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgCropsMerged, result)
+}
+
+// LocationsHandler handles the location catalogue (GET search, POST create for admins)
+func (h *Handler) LocationsHandler(w http.ResponseWriter, r *http.Request) {
+	catalogueService := services.NewCatalogueService(h.store)
+
+	switch r.Method {
+	case http.MethodGet:
+		page, perPage := parsePagination(r)
+		locations, total, err := catalogueService.ListLocations(parseCatalogueSearch(r), page, perPage)
+		if err != nil {
+			WriteServiceError(w, err, "Failed to list locations")
+			return
+		}
+
+		WriteSuccessResponse(w, http.StatusOK, MsgLocationsRetrieved, LocationsListResponse{
+			Locations:  locations,
+			Pagination: newPagination(page, perPage, total),
+		})
+	case http.MethodPost:
+		var req models.CreateLocationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+			return
+		}
+
+		location, err := catalogueService.CreateLocation(req, auditContext(r))
+		if err != nil {
+			WriteServiceError(w, err, "Failed to create location")
+			return
+		}
+
+		WriteSuccessResponse(w, http.StatusCreated, MsgLocationCreated, location)
+	default:
+		WriteMethodNotAllowedError(w)
+	}
+}
+
+// LocationHandler handles a single location
+func (h *Handler) LocationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	locationID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		WriteBadRequestError(w, MsgInvalidLocationID, "")
+		return
+	}
+
+	catalogueService := services.NewCatalogueService(h.store)
+
+	location, err := catalogueService.GetLocation(locationID)
+	if err != nil {
+		WriteServiceError(w, err, "Failed to get location")
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgLocationRetrieved, location)
+}
+
+// parseCatalogueSearch reads the q and match query parameters
+func parseCatalogueSearch(r *http.Request) services.CatalogueSearch {
+	return services.CatalogueSearch{
+		Query: r.URL.Query().Get("q"),
+		Mode:  r.URL.Query().Get("match"),
+	}
+}
@@ -0,0 +1,110 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/services"
+)
+
+// FarmersHandler handles the farmers collection (GET list, POST create)
+func (h *Handler) FarmersHandler(w http.ResponseWriter, r *http.Request) {
+	farmerService := services.NewFarmerService(h.store)
+
+	switch r.Method {
+	case http.MethodGet:
+		listFarmers(w, r, farmerService)
+	case http.MethodPost:
+		createFarmer(w, r, farmerService)
+	default:
+		WriteMethodNotAllowedError(w)
+	}
+}
+
+// FarmerHandler handles a single farmer (GET, PUT, PATCH, DELETE)
+func (h *Handler) FarmerHandler(w http.ResponseWriter, r *http.Request) {
+	farmerID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		WriteBadRequestError(w, MsgInvalidFarmerID, "")
+		return
+	}
+
+	farmerService := services.NewFarmerService(h.store)
+
+	switch r.Method {
+	case http.MethodGet:
+		farmer, err := farmerService.GetFarmer(farmerID)
+		if err != nil {
+			WriteServiceError(w, err, "Failed to get farmer")
+			return
+		}
+		WriteSuccessResponse(w, http.StatusOK, MsgFarmerRetrieved, farmer)
+	case http.MethodPut:
+		var req models.CreateFarmerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+			return
+		}
+		farmer, err := farmerService.ReplaceFarmer(farmerID, req, auditContext(r))
+		if err != nil {
+			WriteServiceError(w, err, "Failed to update farmer")
+			return
+		}
+		WriteSuccessResponse(w, http.StatusOK, MsgFarmerUpdated, farmer)
+	case http.MethodPatch:
+		var req models.UpdateFarmerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+			return
+		}
+		farmer, err := farmerService.UpdateFarmer(farmerID, req, auditContext(r))
+		if err != nil {
+			WriteServiceError(w, err, "Failed to update farmer")
+			return
+		}
+		WriteSuccessResponse(w, http.StatusOK, MsgFarmerUpdated, farmer)
+	case http.MethodDelete:
+		if err := farmerService.DeleteFarmer(farmerID, auditContext(r)); err != nil {
+			WriteServiceError(w, err, "Failed to delete farmer")
+			return
+		}
+		WriteSuccessResponse(w, http.StatusOK, MsgFarmerDeleted, nil)
+	default:
+		WriteMethodNotAllowedError(w)
+	}
+}
+
+// listFarmers writes a paginated list of farmers with their crops
+func listFarmers(w http.ResponseWriter, r *http.Request, farmerService *services.FarmerService) {
+	page, perPage := parsePagination(r)
+
+	farmers, total, err := farmerService.ListFarmers(page, perPage)
+	if err != nil {
+		WriteServiceError(w, err, "Failed to list farmers")
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgFarmersRetrieved, FarmersListResponse{
+		Farmers:    farmers,
+		Pagination: newPagination(page, perPage, total),
+	})
+}
+
+// createFarmer creates a farmer from the request body
+func createFarmer(w http.ResponseWriter, r *http.Request, farmerService *services.FarmerService) {
+	var req models.CreateFarmerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+		return
+	}
+
+	farmer, err := farmerService.CreateFarmer(req, auditContext(r))
+	if err != nil {
+		WriteServiceError(w, err, "Failed to create farmer")
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusCreated, MsgFarmerCreated, farmer)
+}
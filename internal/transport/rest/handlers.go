@@ -3,16 +3,46 @@ package rest
 import (
 	"encoding/json"
 	"io"
-	"log"
 	"net/http"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/config"
+	"github.com/okoye-dev/flux-server/internal/logging"
 	"github.com/okoye-dev/flux-server/internal/middleware"
-	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/repository"
 	"github.com/okoye-dev/flux-server/internal/services"
 )
 
+// Handler serves the API routes that read or write application data
+type Handler struct {
+	store         *repository.Store
+	sessions      *services.SessionService
+	apiKeys       *services.APIKeyService
+	roles         *middleware.RoleAuthorizer
+	audit         *services.AuditService
+	limiter       *middleware.RateLimiter    // Nil when rate limiting is disabled
+	clientIPs     *middleware.ClientIPResolver
+	metricsToken  string                     // Empty when /metrics is not served
+	conversations services.ConversationStore // Nil when the WhatsApp bot is disabled
+}
+
+// NewHandler creates a handler backed by the given store; conversations is the WhatsApp
+// bot's conversation state, or nil when the bot is disabled
+func NewHandler(store *repository.Store, conversations services.ConversationStore) *Handler {
+	cfg := config.Load()
+	return &Handler{
+		store:         store,
+		sessions:      services.NewSessionService(store),
+		apiKeys:       services.NewAPIKeyService(store, apiKeyScopes()),
+		roles:         newRoleAuthorizer(store),
+		audit:         services.NewAuditService(store),
+		limiter:       newRateLimiter(cfg.RateLimit),
+		clientIPs:     newClientIPResolver(cfg.Server.TrustedProxies),
+		metricsToken:  cfg.Metrics.Token,
+		conversations: conversations,
+	}
+}
+
 // HealthHandler handles health check requests
 func HealthHandler(w http.ResponseWriter, r *http.Request) {
 	response := HealthResponse{
@@ -26,7 +56,7 @@ func HealthHandler(w http.ResponseWriter, r *http.Request) {
 
 
 // ProfileHandler handles user profile requests (protected route)
-func ProfileHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ProfileHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r)
 	if !ok {
 		WriteInternalServerError(w, MsgUserIDNotFound, "")
@@ -36,21 +66,12 @@ func ProfileHandler(w http.ResponseWriter, r *http.Request) {
 	userEmail, _ := middleware.GetUserEmail(r)
 
 	// Fetch profile data from user_profiles table
-	profileService, err := services.NewProfileService()
-	if err != nil {
-		WriteInternalServerError(w, "Failed to create profile service", err.Error())
-		return
-	}
+	profileService := services.NewProfileService(h.store)
 
 	profile, err := profileService.GetUserProfile(userID)
 	if err != nil {
-		// If profile not found, create a placeholder
-		authUserID, _ := uuid.Parse(userID)
-		profile = &models.UserProfile{
-			AuthUserID:  &authUserID,
-			DisplayName: &userEmail, // Default to username
-			Metadata:    map[string]any{},
-		}
+		WriteServiceError(w, err, "Failed to get user profile")
+		return
 	}
 
 	response := ProfileResponse{
@@ -88,10 +109,12 @@ func WhatsAppWebhookHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	logger := logging.FromContext(r.Context())
+
 	// Read the request body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.Printf("Error reading webhook body: %v", err)
+		logger.Warn("Error reading webhook body", "error", err)
 		http.Error(w, "Bad request", http.StatusBadRequest)
 		return
 	}
@@ -99,12 +122,13 @@ func WhatsAppWebhookHandler(w http.ResponseWriter, r *http.Request) {
 	// Parse the webhook data
 	var webhookData map[string]interface{}
 	if err := json.Unmarshal(body, &webhookData); err != nil {
-		log.Printf("Error parsing webhook JSON: %v", err)
+		logger.Warn("Error parsing webhook JSON", "error", err)
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("WhatsApp webhook received: %+v", webhookData)
+	// Only the notification type is logged; the payload holds the message and sender's number
+	logger.Debug("WhatsApp webhook received", "type_webhook", webhookData["typeWebhook"])
 
 	// Note: Bot is now using Green API polling instead of webhooks
 	// This endpoint is kept for compatibility but doesn't process messages
@@ -112,13 +136,43 @@ func WhatsAppWebhookHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
-// NewRouter creates and returns a new HTTP router with all routes
-func NewRouter() *http.ServeMux {
+// roleCacheTTL is how long a resolved user role is trusted before re-reading user_profiles
+const roleCacheTTL = 30 * time.Second
+
+// NewRouter creates and returns a new HTTP router with all routes; conversations may be nil
+func NewRouter(store *repository.Store, conversations services.ConversationStore) *http.ServeMux {
+	return newRouter(NewHandler(store, conversations))
+}
+
+// newRouter registers the routes served by h
+func newRouter(h *Handler) *http.ServeMux {
 	mux := http.NewServeMux()
+	// authenticate validates the bearer token and rejects signed-out sessions, then applies the per-user rate limit
+	authenticateToken := middleware.NewAuthMiddleware(h.sessions)
+	authenticate := func(next http.Handler) http.Handler {
+		return authenticateToken(h.limiter.PerUser(next))
+	}
+	// apiKeys accepts X-API-Key service keys on routes that name the scope they need
+	apiKeys := newAPIKeyAuthenticator(h.apiKeys)
+	
+	// requireAuth chains authentication with a permission check; API keys granted the permission pass too
+	requireAuth := func(permission middleware.Permission, handler http.HandlerFunc) http.Handler {
+		return apiKeys.Allow(permission, h.limiter.PerUser(handler))(authenticate(h.roles.RequirePermission(permission)(handler)))
+	}
+	
+	// authenticateOrKey lets any signed-in user through, or an API key granted the scope
+	authenticateOrKey := func(scope middleware.Permission, handler http.HandlerFunc) http.Handler {
+		return apiKeys.Allow(scope, h.limiter.PerUser(handler))(authenticate(handler))
+	}
 	
 	// Public endpoints
 	mux.HandleFunc("/health", HealthHandler)
 	mux.HandleFunc("/webhook/whatsapp", WhatsAppWebhookHandler)
+	
+	// Prometheus metrics, for scrapers holding METRICS_TOKEN
+	if h.metricsToken != "" {
+		mux.HandleFunc("GET /metrics", h.MetricsHandler)
+	}
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		response := RootResponse{
 			Message: MsgWelcomeToFlux,
@@ -127,25 +181,96 @@ func NewRouter() *http.ServeMux {
 		WriteRootResponse(w, response)
 	})
 	
-	// Authentication endpoints (username/password only)
-	mux.HandleFunc("/auth/signup", SignupHandler)
-	mux.HandleFunc("/auth/signin", SigninHandler)
+	// Catalogue endpoints (public reads, admin-only writes)
+	mux.HandleFunc("/crops", h.CropsHandler)
+	mux.Handle("POST /crops", requireAuth(middleware.PermManageCatalogue, h.CropsHandler))
+	mux.Handle("/crops/merge", requireAuth(middleware.PermManageCatalogue, h.CropMergeHandler))
+	mux.HandleFunc("/crops/{id}", h.CropHandler)
+	mux.HandleFunc("/locations", h.LocationsHandler)
+	mux.Handle("POST /locations", requireAuth(middleware.PermManageCatalogue, h.LocationsHandler))
+	mux.HandleFunc("/locations/{id}", h.LocationHandler)
+	
+	// Authentication endpoints (username, email or phone number with a password, or a WhatsApp code for bot-registered farmers)
+	mux.HandleFunc("/auth/signup", h.SignupHandler)
+	mux.HandleFunc("/auth/signin", h.SigninHandler)
+	mux.HandleFunc("/auth/refresh", h.RefreshHandler)
+	mux.HandleFunc("/auth/otp/request", h.OTPRequestHandler)
+	mux.HandleFunc("/auth/otp/verify", h.OTPVerifyHandler)
+	mux.HandleFunc("/auth/password/reset", h.PasswordResetHandler)
+	mux.HandleFunc("/auth/password/reset/confirm", h.PasswordResetConfirmHandler)
+	mux.Handle("/auth/password", authenticate(http.HandlerFunc(h.ChangePasswordHandler)))
+	mux.Handle("/auth/signout", authenticate(http.HandlerFunc(h.SignoutHandler)))
+	mux.Handle("/auth/signout-all", authenticate(http.HandlerFunc(h.SignoutAllHandler)))
 	
 	// Protected endpoints (require authentication)
-	mux.Handle("/profile", middleware.AuthMiddleware(http.HandlerFunc(ProfileHandler)))
-	mux.Handle("/protected", middleware.AuthMiddleware(http.HandlerFunc(ProtectedDataHandler)))
+	mux.Handle("/profile", authenticate(http.HandlerFunc(h.ProfileHandler)))
+	mux.Handle("/protected", authenticate(http.HandlerFunc(ProtectedDataHandler)))
+	
+	// Data subject requests (export or delete everything stored about the caller)
+	mux.Handle("/me/export", authenticate(http.HandlerFunc(h.ExportHandler)))
+	mux.Handle("/me", authenticate(http.HandlerFunc(h.DeleteAccountHandler)))
+	
+	// Farmer endpoints (admins, or an API key with farmers:read / farmers:write)
+	mux.Handle("/farmers", requireAuth(middleware.PermWriteFarmers, h.FarmersHandler))
+	mux.Handle("GET /farmers", requireAuth(middleware.PermReadFarmers, h.FarmersHandler))
+	mux.Handle("/farmers/{id}", requireAuth(middleware.PermWriteFarmers, h.FarmerHandler))
+	mux.Handle("GET /farmers/{id}", requireAuth(middleware.PermReadFarmers, h.FarmerHandler))
+	
+	// Extension officer workspace (scoped to the officer's assigned location)
+	mux.Handle("/officer/farmers", requireAuth(middleware.PermOfficerWorkspace, h.OfficerFarmersHandler))
+	mux.Handle("/officer/farmers/{id}", requireAuth(middleware.PermOfficerWorkspace, h.OfficerFarmerHandler))
+	mux.Handle("/officer/farmers/{id}/notes", requireAuth(middleware.PermOfficerWorkspace, h.OfficerVisitNotesHandler))
+	
+	// Harvest endpoints (require authentication, or an API key acting as its owner)
+	mux.Handle("/harvests", authenticateOrKey(middleware.PermReadHarvests, h.HarvestsHandler))
+	mux.Handle("POST /harvests", requireAuth(middleware.PermRecordHarvests, h.HarvestsHandler))
+	mux.Handle("/harvests/reports", requireAuth(middleware.PermViewReports, h.HarvestReportHandler))
+	
+	// Service API key management (admins only)
+	mux.Handle("/admin/api-keys", requireAuth(middleware.PermManageAPIKeys, h.APIKeysHandler))
+	mux.Handle("/admin/api-keys/{id}", requireAuth(middleware.PermManageAPIKeys, h.APIKeyHandler))
+	
+	// Sign-in lockouts (admins only)
+	mux.Handle("/admin/lockouts", requireAuth(middleware.PermManageUsers, h.LockoutsHandler))
+	mux.Handle("/admin/lockouts/unlock", requireAuth(middleware.PermManageUsers, h.UnlockHandler))
+	
+	// User management (admins only)
+	mux.Handle("/admin/users", requireAuth(middleware.PermManageUsers, h.UsersHandler))
+	mux.Handle("/admin/users/{id}", requireAuth(middleware.PermManageUsers, h.UserHandler))
+	mux.Handle("/admin/users/{id}/role", requireAuth(middleware.PermManageUsers, h.UserRoleHandler))
+	mux.Handle("/admin/users/{id}/disable", requireAuth(middleware.PermManageUsers, h.DisableUserHandler))
+	mux.Handle("/admin/users/{id}/enable", requireAuth(middleware.PermManageUsers, h.EnableUserHandler))
+	mux.Handle("/admin/users/{id}/location", requireAuth(middleware.PermManageUsers, h.OfficerLocationHandler))
+	
+	// Audit log (admins and API keys with the audit_log:read scope)
+	mux.Handle("/admin/audit-log", requireAuth(middleware.PermViewAuditLog, h.AuditLogHandler))
 	
 	return mux
 }
 
-// NewSecureRouter creates a router with security middleware applied
-func NewSecureRouter() http.Handler {
-	mux := NewRouter()
+// NewSecureRouter creates a router with security middleware applied; conversations may be nil
+func NewSecureRouter(store *repository.Store, conversations services.ConversationStore) http.Handler {
+	h := NewHandler(store, conversations)
+	mux := newRouter(h)
+	// route names the pattern a request matched, for rate limits and logs
+	route := func(r *http.Request) string {
+		_, pattern := mux.Handler(r)
+		return pattern
+	}
 	
 	// Apply security middleware in order
 	handler := middleware.SecurityHeadersMiddleware(mux)
-	handler = middleware.CORSMiddleware([]string{"http://localhost:3000", "http://localhost:3002", "http://localhost:8080"})(handler)
-	handler = middleware.RateLimitMiddleware(60)(handler) // 60 requests per minute
+	// Per client IP, with tighter limits on the routes configured in RATE_LIMIT_ROUTES
+	handler = h.limiter.PerClient(route)(handler)
+	// CORS goes outside the rate limit, so preflights are free and web apps can read 429 responses
+	handler = middleware.CORSMiddleware(config.Load().CORS)(handler)
+	// Log every request under its request ID
+	handler = middleware.AccessLogMiddleware(route)(handler)
+	// Count requests and their latency for /metrics, including the ones rejected above
+	handler = middleware.MetricsMiddleware(route)(handler)
+	handler = middleware.RequestIDMiddleware(handler)
+	// Resolve the client IP behind trusted proxies first, so every layer sees the same address
+	handler = h.clientIPs.Middleware(handler)
 	
 	return handler
 }
\ No newline at end of file
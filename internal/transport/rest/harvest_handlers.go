@@ -0,0 +1,150 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/middleware"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/services"
+)
+
+// HarvestsHandler handles the caller's harvests (GET list, POST record)
+func (h *Handler) HarvestsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		WriteInternalServerError(w, MsgUserIDNotFound, "")
+		return
+	}
+
+	harvestService := services.NewHarvestService(h.store)
+
+	switch r.Method {
+	case http.MethodGet:
+		filter, ok := parseHarvestFilter(w, r)
+		if !ok {
+			return
+		}
+
+		page, perPage := parsePagination(r)
+		harvests, total, err := harvestService.ListUserHarvests(userID, filter, page, perPage)
+		if err != nil {
+			WriteServiceError(w, err, "Failed to list harvests")
+			return
+		}
+
+		WriteSuccessResponse(w, http.StatusOK, MsgHarvestsRetrieved, FarmHarvestsListResponse{
+			FarmHarvests: harvests,
+			Pagination:   newPagination(page, perPage, total),
+		})
+	case http.MethodPost:
+		var req models.CreateFarmHarvestRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+			return
+		}
+
+		harvest, err := harvestService.RecordHarvest(userID, req)
+		if err != nil {
+			WriteServiceError(w, err, "Failed to record harvest")
+			return
+		}
+
+		WriteSuccessResponse(w, http.StatusCreated, MsgHarvestRecorded, harvest)
+	default:
+		WriteMethodNotAllowedError(w)
+	}
+}
+
+// HarvestReportHandler handles aggregated harvest reports for extension officers and admins
+func (h *Handler) HarvestReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	filter, ok := parseHarvestFilter(w, r)
+	if !ok {
+		return
+	}
+
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = services.HarvestGroupBySeason
+	}
+
+	locationID, ok := h.reportLocation(w, r)
+	if !ok {
+		return
+	}
+
+	harvestService := services.NewHarvestService(h.store)
+
+	entries, err := harvestService.HarvestReport(groupBy, locationID, filter)
+	if err != nil {
+		WriteServiceError(w, err, "Failed to generate harvest report")
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgHarvestReportGenerated, HarvestReportResponse{
+		GroupBy: groupBy,
+		Entries: entries,
+	})
+}
+
+// reportLocation returns the location a caller's harvest reports are limited to: an extension
+// officer's assigned location, or nil for admins. API keys report as their owner.
+func (h *Handler) reportLocation(w http.ResponseWriter, r *http.Request) (*int64, bool) {
+	role, ok := middleware.GetUserRole(r)
+	if !ok {
+		userID, _ := middleware.GetUserID(r)
+		var err error
+		if role, err = h.roles.Role(userID); err != nil {
+			WriteInternalServerError(w, "Failed to resolve user role", err.Error())
+			return nil, false
+		}
+	}
+	if role == middleware.RoleAdmin {
+		return nil, true
+	}
+
+	_, officer, ok := h.resolveOfficer(w, r)
+	if !ok {
+		return nil, false
+	}
+	if officer.AssignedLocationID == nil {
+		WriteServiceError(w, services.ErrOfficerLocationUnassigned, "")
+		return nil, false
+	}
+	return officer.AssignedLocationID, true
+}
+
+// parseHarvestFilter reads the crop_id, from and to query parameters
+func parseHarvestFilter(w http.ResponseWriter, r *http.Request) (services.HarvestFilter, bool) {
+	var filter services.HarvestFilter
+
+	if cropID := r.URL.Query().Get("crop_id"); cropID != "" {
+		parsed, err := uuid.Parse(cropID)
+		if err != nil {
+			WriteBadRequestError(w, MsgInvalidCropID, "")
+			return filter, false
+		}
+		filter.CropID = &parsed
+	}
+
+	from, err := parseDateParam(r, "from", false)
+	if err != nil {
+		WriteBadRequestError(w, MsgInvalidRequest, err.Error())
+		return filter, false
+	}
+	to, err := parseDateParam(r, "to", true)
+	if err != nil {
+		WriteBadRequestError(w, MsgInvalidRequest, err.Error())
+		return filter, false
+	}
+
+	filter.From = from
+	filter.To = to
+	return filter, true
+}
@@ -2,8 +2,20 @@ package rest
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/config"
+	"github.com/okoye-dev/flux-server/internal/middleware"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/ratelimit"
+	"github.com/okoye-dev/flux-server/internal/repository"
+	"github.com/okoye-dev/flux-server/internal/services"
 )
 
 // HTTP Response Helpers
@@ -174,3 +186,322 @@ func WriteInvalidTokenError(w http.ResponseWriter) {
 func WriteMissingConfigError(w http.ResponseWriter, config string) {
 	WriteErrorResponse(w, http.StatusInternalServerError, ErrCodeMissingConfig, MsgSupabaseConfigMissing, config)
 }
+
+// WriteServiceError maps a service error onto the matching HTTP status and error code
+func WriteServiceError(w http.ResponseWriter, err error, message string) {
+	var serviceErr *services.ServiceError
+	if !errors.As(err, &serviceErr) {
+		WriteInternalServerError(w, message, err.Error())
+		return
+	}
+
+	statusCode, ok := serviceErrorStatus[serviceErr.Code]
+	if !ok {
+		statusCode = http.StatusInternalServerError
+	}
+	// Wrapped errors carry the underlying cause, e.g. the Supabase message for a failed signup
+	details := ""
+	if err != error(serviceErr) {
+		details = err.Error()
+	}
+	WriteErrorResponse(w, statusCode, serviceErr.Code, serviceErr.Message, details)
+}
+
+// serviceErrorStatus maps service error codes to HTTP status codes
+var serviceErrorStatus = map[string]int{
+	services.ErrProfileNotFound.Code:           http.StatusNotFound,
+	services.ErrRoleNotFound.Code:              http.StatusNotFound,
+	services.ErrFarmerNotFound.Code:            http.StatusNotFound,
+	services.ErrFarmerNameRequired.Code:        http.StatusBadRequest,
+	services.ErrFarmerAlreadyLinked.Code:       http.StatusConflict,
+	services.ErrFarmerPhoneLinked.Code:         http.StatusConflict,
+	services.ErrFarmerCreationFailed.Code:      http.StatusInternalServerError,
+	services.ErrHarvestCropRequired.Code:       http.StatusBadRequest,
+	services.ErrHarvestQuantityInvalid.Code:    http.StatusBadRequest,
+	services.ErrHarvestCreationFailed.Code:     http.StatusInternalServerError,
+	services.ErrHarvestGroupByInvalid.Code:     http.StatusBadRequest,
+	services.ErrCropNotFound.Code:              http.StatusNotFound,
+	services.ErrCropAlreadyExists.Code:         http.StatusConflict,
+	services.ErrCropNameRequired.Code:          http.StatusBadRequest,
+	services.ErrCropMergeInvalid.Code:          http.StatusBadRequest,
+	services.ErrLocationNotFound.Code:          http.StatusNotFound,
+	services.ErrLocationNameRequired.Code:      http.StatusBadRequest,
+	services.ErrSearchModeInvalid.Code:         http.StatusBadRequest,
+	services.ErrSupabaseConfigMissing.Code:     http.StatusInternalServerError,
+	services.ErrServiceRoleKeyMissing.Code:     http.StatusInternalServerError,
+	services.ErrSignupRoleInvalid.Code:         http.StatusBadRequest,
+	services.ErrSignupAuthFailed.Code:          http.StatusBadRequest,
+	services.ErrSignupProfileFailed.Code:       http.StatusInternalServerError,
+	services.ErrSessionIDMissing.Code:          http.StatusBadRequest,
+	services.ErrSessionRevoked.Code:            http.StatusUnauthorized,
+	services.ErrSignupRollbackFailed.Code:      http.StatusInternalServerError,
+	services.ErrWhatsAppNotConfigured.Code:     http.StatusInternalServerError,
+	services.ErrOTPPhoneInvalid.Code:           http.StatusBadRequest,
+	services.ErrOTPPhoneNotRegistered.Code:     http.StatusNotFound,
+	services.ErrOTPDeliveryFailed.Code:         http.StatusBadGateway,
+	services.ErrOTPInvalid.Code:                http.StatusUnauthorized,
+	services.ErrOTPAttemptsExceeded.Code:       http.StatusTooManyRequests,
+	services.ErrOTPAccountFailed.Code:          http.StatusInternalServerError,
+	services.ErrOTPAccountMismatch.Code:        http.StatusForbidden,
+	services.ErrPasswordTooShort.Code:          http.StatusBadRequest,
+	services.ErrPasswordIncorrect.Code:         http.StatusUnauthorized,
+	services.ErrPasswordNotSet.Code:            http.StatusBadRequest,
+	services.ErrPasswordUpdateFailed.Code:      http.StatusInternalServerError,
+	services.ErrPasswordResetInvalid.Code:      http.StatusBadRequest,
+	services.ErrPasswordResetLocked.Code:       http.StatusTooManyRequests,
+	services.ErrPasswordResetUndelivered.Code:  http.StatusBadGateway,
+	services.ErrAPIKeyInvalid.Code:             http.StatusUnauthorized,
+	services.ErrAPIKeyNotFound.Code:            http.StatusNotFound,
+	services.ErrAPIKeyNameRequired.Code:        http.StatusBadRequest,
+	services.ErrAPIKeyScopesInvalid.Code:       http.StatusBadRequest,
+	services.ErrAPIKeyExpiryInvalid.Code:       http.StatusBadRequest,
+	services.ErrAPIKeyOwnerNotFound.Code:       http.StatusNotFound,
+	services.ErrIdentifierInvalid.Code:         http.StatusBadRequest,
+	services.ErrUsernameInvalid.Code:           http.StatusBadRequest,
+	services.ErrUsernameTaken.Code:             http.StatusConflict,
+	services.ErrEmailInvalid.Code:              http.StatusBadRequest,
+	services.ErrSignInFailed.Code:              http.StatusUnauthorized,
+	services.ErrSignInLocked.Code:              http.StatusTooManyRequests,
+	services.ErrUnlockTargetRequired.Code:      http.StatusBadRequest,
+	services.ErrOfficerNotFound.Code:           http.StatusNotFound,
+	services.ErrOfficerLocationUnassigned.Code: http.StatusConflict,
+	services.ErrFarmerOutsideLocation.Code:     http.StatusForbidden,
+	services.ErrVisitNoteRequired.Code:         http.StatusBadRequest,
+	services.ErrUserSelfModification.Code:      http.StatusConflict,
+	services.ErrLocationIDInvalid.Code:         http.StatusBadRequest,
+	services.ErrAccountDisabled.Code:           http.StatusForbidden,
+	services.ErrAuditRangeInvalid.Code:         http.StatusBadRequest,
+}
+
+// Request Helpers
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// parsePagination reads the page and per_page query parameters, falling back to sane defaults
+func parsePagination(r *http.Request) (page, perPage int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	perPage, err = strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = defaultPerPage
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	return page, perPage
+}
+
+// newPagination builds pagination metadata for a page of results
+func newPagination(page, perPage int, total int64) Pagination {
+	totalPages := int((total + int64(perPage) - 1) / int64(perPage))
+	return Pagination{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+	}
+}
+
+// parseDateParam reads an optional date query parameter in YYYY-MM-DD or RFC3339 form.
+// Bare dates used as an upper bound are moved to the end of that day.
+func parseDateParam(r *http.Request, name string, endOfDay bool) (*time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a YYYY-MM-DD or RFC3339 date", name)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
+
+// profileRoleResolver resolves caller roles from user_profiles and roles for the RBAC middleware
+type profileRoleResolver struct {
+	store *repository.Store
+}
+
+// ResolveRole returns the user's role name, or an empty role if they have no profile or role
+func (p profileRoleResolver) ResolveRole(authUserID string) (string, error) {
+	role, err := services.NewProfileService(p.store).GetUserRoleName(authUserID)
+	if errors.Is(err, services.ErrProfileNotFound) || errors.Is(err, services.ErrRoleNotFound) {
+		return "", nil
+	}
+	return role, err
+}
+
+// newRoleAuthorizer creates the RBAC middleware, answering with the standard error envelope
+func newRoleAuthorizer(store *repository.Store) *middleware.RoleAuthorizer {
+	return middleware.NewRoleAuthorizer(profileRoleResolver{store: store}, roleCacheTTL, middleware.AuthorizationErrors{
+		Unauthorized: func(w http.ResponseWriter) { WriteUnauthorizedError(w, "") },
+		Forbidden:    func(w http.ResponseWriter) { WriteForbiddenError(w, "") },
+		Internal: func(w http.ResponseWriter, err error) {
+			WriteInternalServerError(w, "Failed to resolve user role", err.Error())
+		},
+	})
+}
+
+// apiKeyVerifier verifies API keys for the API key middleware
+type apiKeyVerifier struct {
+	keys *services.APIKeyService
+}
+
+// VerifyAPIKey returns the key, or nil if it is unknown, revoked or expired
+func (v apiKeyVerifier) VerifyAPIKey(rawKey string) (*middleware.APIKey, error) {
+	key, err := v.keys.Authenticate(rawKey)
+	if errors.Is(err, services.ErrAPIKeyInvalid) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := make([]middleware.Permission, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		scopes = append(scopes, middleware.Permission(scope))
+	}
+	return &middleware.APIKey{
+		ID:      key.ID.String(),
+		Name:    key.Name,
+		OwnerID: key.OwnerID.String(),
+		Scopes:  scopes,
+	}, nil
+}
+
+// newAPIKeyAuthenticator creates the API key middleware, answering with the standard error envelope
+func newAPIKeyAuthenticator(keys *services.APIKeyService) *middleware.APIKeyAuthenticator {
+	return middleware.NewAPIKeyAuthenticator(apiKeyVerifier{keys: keys}, middleware.AuthorizationErrors{
+		Unauthorized: func(w http.ResponseWriter) { WriteServiceError(w, services.ErrAPIKeyInvalid, "") },
+		Forbidden:    func(w http.ResponseWriter) { WriteForbiddenError(w, "API key is not granted the scope this route requires") },
+		Internal: func(w http.ResponseWriter, err error) {
+			WriteInternalServerError(w, "Failed to verify API key", err.Error())
+		},
+	})
+}
+
+// newRateLimiter creates the rate limit middleware from the configuration, answering
+// rejected requests with the standard error envelope. It returns nil, limiting nothing,
+// when rate limiting is disabled.
+func newRateLimiter(cfg config.RateLimitConfig) *middleware.RateLimiter {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	// The configuration is validated at startup, so this only fails in tests and tools
+	policies, err := rateLimitPolicies(cfg)
+	if err != nil {
+		slog.Error("Rate limit configuration error, rate limiting disabled", "error", err)
+		return nil
+	}
+
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.Store == "redis" {
+		redisStore, err := ratelimit.NewRedisStore(cfg.RedisURL)
+		if err != nil {
+			slog.Error("Rate limit configuration error, limiting in memory instead", "error", err)
+		} else {
+			store = redisStore
+		}
+	}
+
+	return middleware.NewRateLimiter(store, policies, func(w http.ResponseWriter) {
+		WriteErrorResponse(w, http.StatusTooManyRequests, ErrCodeRateLimited, MsgRateLimited, "")
+	})
+}
+
+// newClientIPResolver creates the client IP middleware for the configured trusted proxies
+func newClientIPResolver(trustedProxies []string) *middleware.ClientIPResolver {
+	// The configuration is validated at startup, so this only fails in tests and tools
+	resolver, err := middleware.NewClientIPResolver(trustedProxies)
+	if err != nil {
+		slog.Error("Trusted proxy configuration error, ignoring forwarding headers", "error", err)
+		resolver, _ = middleware.NewClientIPResolver(nil)
+	}
+	return resolver
+}
+
+// rateLimitPolicies parses the configured rate limits
+func rateLimitPolicies(cfg config.RateLimitConfig) (middleware.RateLimitPolicies, error) {
+	client, err := ratelimit.ParsePolicy(cfg.Client)
+	if err != nil {
+		return middleware.RateLimitPolicies{}, err
+	}
+	user, err := ratelimit.ParsePolicy(cfg.User)
+	if err != nil {
+		return middleware.RateLimitPolicies{}, err
+	}
+	routes, err := ratelimit.ParseRoutePolicies(cfg.Routes)
+	if err != nil {
+		return middleware.RateLimitPolicies{}, err
+	}
+	return middleware.RateLimitPolicies{Client: client, User: user, Routes: routes}, nil
+}
+
+// apiKeyScopes lists the scopes API keys may be granted
+func apiKeyScopes() []string {
+	scopes := make([]string, 0, len(middleware.APIKeyScopes))
+	for _, scope := range middleware.APIKeyScopes {
+		scopes = append(scopes, string(scope))
+	}
+	return scopes
+}
+
+// newAPIKeyResponse describes an API key without its hash
+func newAPIKeyResponse(key models.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		OwnerID:    key.OwnerID,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedBy:  key.CreatedBy,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+// setRetryAfter tells the client how long to wait, rounded up to whole seconds
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+}
+
+// loginIdentifier returns the identifier a user signs in with, falling back to the
+// username field older clients send
+func loginIdentifier(identifier, username string) string {
+	if identifier != "" {
+		return identifier
+	}
+	return username
+}
+
+// auditContext describes the caller of a request for the audit log
+func auditContext(r *http.Request) services.AuditContext {
+	actx := services.AuditContext{
+		IPAddress: middleware.ClientIP(r),
+		UserAgent: r.UserAgent(),
+		RequestID: middleware.RequestID(r),
+	}
+	if userID, ok := middleware.GetUserID(r); ok {
+		if actorID, err := uuid.Parse(userID); err == nil {
+			actx.ActorID = &actorID
+		}
+	}
+	return actx
+}
@@ -0,0 +1,55 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/services"
+)
+
+// LockoutsHandler lists the accounts and IP addresses locked out of signing in (admins only)
+func (h *Handler) LockoutsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	lockouts, err := h.newLockoutService().ListLocked()
+	if err != nil {
+		WriteServiceError(w, err, "Failed to list sign-in lockouts")
+		return
+	}
+	if lockouts == nil {
+		lockouts = []models.LoginAttempt{}
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgLockoutsRetrieved, LockoutsListResponse{Lockouts: lockouts})
+}
+
+// UnlockHandler lifts the lockout of an account, an IP address or both, and forgets their
+// failed sign-ins (admins only)
+func (h *Handler) UnlockHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	var req UnlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+		return
+	}
+
+	if err := h.newLockoutService().Unlock(req.Identifier, req.IPAddress, auditContext(r)); err != nil {
+		WriteServiceError(w, err, "Failed to lift sign-in lockout")
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgUnlocked, nil)
+}
+
+// newLockoutService creates the lockout service; it only looks accounts up, so needs no auth provider
+func (h *Handler) newLockoutService() *services.LockoutService {
+	return services.NewLockoutService(h.store, h.newIdentityService(nil))
+}
@@ -0,0 +1,22 @@
+package rest
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/okoye-dev/flux-server/internal/metrics"
+)
+
+// MetricsHandler serves the server's metrics in the Prometheus text format to callers
+// presenting METRICS_TOKEN as a bearer token
+func (h *Handler) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.metricsToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+		WriteUnauthorizedError(w, MsgInvalidMetricsToken)
+		return
+	}
+
+	metrics.Default.Handler().ServeHTTP(w, r)
+}
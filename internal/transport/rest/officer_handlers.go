@@ -0,0 +1,123 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/okoye-dev/flux-server/internal/middleware"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/services"
+)
+
+// OfficerFarmersHandler lists the farmers in the calling officer's assigned location
+func (h *Handler) OfficerFarmersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	officerService, officer, ok := h.resolveOfficer(w, r)
+	if !ok {
+		return
+	}
+
+	page, perPage := parsePagination(r)
+	farmers, total, err := officerService.ListAssignedFarmers(officer, page, perPage)
+	if err != nil {
+		WriteServiceError(w, err, "Failed to list farmers")
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgFarmersRetrieved, OfficerFarmersListResponse{
+		Farmers:    farmers,
+		Pagination: newPagination(page, perPage, total),
+	})
+}
+
+// OfficerFarmerHandler returns one farmer in the calling officer's assigned location
+func (h *Handler) OfficerFarmerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	farmerID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		WriteBadRequestError(w, MsgInvalidFarmerID, "")
+		return
+	}
+
+	officerService, officer, ok := h.resolveOfficer(w, r)
+	if !ok {
+		return
+	}
+
+	farmer, err := officerService.GetAssignedFarmer(officer, farmerID)
+	if err != nil {
+		WriteServiceError(w, err, "Failed to get farmer")
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgFarmerRetrieved, farmer)
+}
+
+// OfficerVisitNotesHandler lists (GET) or adds (POST) visit notes for a farmer in the officer's location
+func (h *Handler) OfficerVisitNotesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	farmerID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		WriteBadRequestError(w, MsgInvalidFarmerID, "")
+		return
+	}
+
+	officerService, officer, ok := h.resolveOfficer(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		notes, err := officerService.ListVisitNotes(officer, farmerID)
+		if err != nil {
+			WriteServiceError(w, err, "Failed to list visit notes")
+			return
+		}
+
+		WriteSuccessResponse(w, http.StatusOK, MsgVisitNotesRetrieved, VisitNotesListResponse{VisitNotes: notes})
+		return
+	}
+
+	var req models.CreateVisitNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+		return
+	}
+
+	note, err := officerService.AddVisitNote(officer, farmerID, req)
+	if err != nil {
+		WriteServiceError(w, err, "Failed to add visit note")
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusCreated, MsgVisitNoteAdded, note)
+}
+
+// resolveOfficer loads the extension officer record of the authenticated caller.
+// The officer's role and location always come from the database, never from the request.
+func (h *Handler) resolveOfficer(w http.ResponseWriter, r *http.Request) (*services.OfficerService, *models.ExtensionOfficer, bool) {
+	userID, _ := middleware.GetUserID(r)
+
+	officerService := services.NewOfficerService(h.store)
+
+	officer, err := officerService.GetOfficerByAuthUserID(userID)
+	if err != nil {
+		WriteServiceError(w, err, "Failed to load extension officer")
+		return nil, nil, false
+	}
+
+	return officerService, officer, true
+}
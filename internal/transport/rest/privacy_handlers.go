@@ -0,0 +1,125 @@
+package rest
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/okoye-dev/flux-server/internal/logging"
+	"github.com/okoye-dev/flux-server/internal/middleware"
+	"github.com/okoye-dev/flux-server/internal/models"
+	"github.com/okoye-dev/flux-server/internal/services"
+)
+
+// ExportHandler returns everything stored about the caller, as JSON or, with
+// ?format=zip, as a ZIP archive with a JSON file per section (protected route)
+func (h *Handler) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		WriteBadRequestError(w, MsgExportFormatInvalid, "")
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		WriteInternalServerError(w, MsgUserIDNotFound, "")
+		return
+	}
+
+	export, err := h.newPrivacyService(nil).ExportAccount(userID, confirmedPhone(r), auditContext(r))
+	if err != nil {
+		WriteServiceError(w, err, "Failed to export personal data")
+		return
+	}
+
+	if format == "zip" {
+		writeExportZip(w, r, export)
+		return
+	}
+	WriteSuccessResponse(w, http.StatusOK, MsgDataExported, export)
+}
+
+// DeleteAccountHandler deletes the caller's account and everything stored about them (protected route)
+func (h *Handler) DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		WriteInternalServerError(w, MsgUserIDNotFound, "")
+		return
+	}
+
+	// Deleting the account needs the service role key
+	auth, err := newSupabaseAuth()
+	if err != nil {
+		WriteServiceError(w, err, "Failed to delete account")
+		return
+	}
+
+	if err := h.newPrivacyService(auth).DeleteAccount(userID, confirmedPhone(r), auditContext(r)); err != nil {
+		WriteServiceError(w, err, "Failed to delete account")
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgAccountDeleted, nil)
+}
+
+// newPrivacyService creates the privacy service; auth may be nil when nothing is deleted
+func (h *Handler) newPrivacyService(auth services.AccountManager) *services.PrivacyService {
+	return services.NewPrivacyService(h.store, auth, h.conversations, h.sessions)
+}
+
+// confirmedPhone returns the phone number the caller's token vouches for, which WhatsApp
+// sign-in sets; the phone number on a profile was typed in at signup and isn't checked
+func confirmedPhone(r *http.Request) string {
+	if claims, ok := middleware.GetClaims(r); ok {
+		return claims.Phone
+	}
+	return ""
+}
+
+// writeExportZip writes an export as a ZIP download, one JSON file per section
+func writeExportZip(w http.ResponseWriter, r *http.Request, export *models.PersonalDataExport) {
+	sections := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", export.Profile},
+		{"extension_officer.json", export.Officer},
+		{"farmers.json", export.Farmers},
+		{"harvests.json", export.Harvests},
+		{"feedback.json", export.Feedback},
+		{"bot_conversation.json", export.BotConversation},
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="flux-data-export-%s.zip"`, export.ExportedAt.Format("20060102")))
+	w.WriteHeader(http.StatusOK)
+
+	archive := zip.NewWriter(w)
+	for _, section := range sections {
+		file, err := archive.Create(section.name)
+		if err == nil {
+			encoder := json.NewEncoder(file)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(section.data)
+		}
+		if err != nil {
+			// The status is already sent, so the client gets a truncated archive
+			logging.FromContext(r.Context()).Error("Failed to write data export archive", "error", err)
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to write data export archive", "error", err)
+	}
+}
@@ -3,6 +3,7 @@ package rest
 import (
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/okoye-dev/flux-server/internal/models"
 )
 
@@ -42,11 +43,12 @@ type Pagination struct {
 
 // AuthResponse represents authentication response
 type AuthResponse struct {
-	User        UserInfo `json:"user"`
-	AccessToken string   `json:"access_token"`
-	TokenType   string   `json:"token_type"`
-	ExpiresIn   int      `json:"expires_in"`
-	Message     string   `json:"message"`
+	User         UserInfo `json:"user"`
+	AccessToken  string   `json:"access_token"`
+	RefreshToken string   `json:"refresh_token,omitempty"`
+	TokenType    string   `json:"token_type"`
+	ExpiresIn    int      `json:"expires_in"`
+	Message      string   `json:"message"`
 }
 
 // UserInfo represents user information in auth responses
@@ -61,6 +63,8 @@ type SignupRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=20"`
 	Password string `json:"password" validate:"required,min=6"`
 	Role     string `json:"role,omitempty"` // Optional role name: "farmer", "extension_officer", defaults to "farmer"`
+	// Optional real email address; without one the account gets a synthetic username@<AUTH_EMAIL_DOMAIN> email
+	Email string `json:"email,omitempty"`
 	
 	// Optional farmer-specific fields
 	PhoneNumber string `json:"phone_number,omitempty"`
@@ -72,10 +76,49 @@ type SignupRequest struct {
 	AssignedLocationID int64 `json:"assigned_location_id,omitempty"`
 }
 
-// SigninRequest represents signin request
+// SigninRequest represents signin request. Identifier is a username, email address or
+// phone number; Username is still accepted from older clients.
 type SigninRequest struct {
-	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	Identifier string `json:"identifier"`
+	Username   string `json:"username"`
+	Password   string `json:"password" validate:"required"`
+}
+
+// RefreshRequest represents the refresh token request payload
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// ChangePasswordRequest represents the change password request payload
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6"`
+}
+
+// PasswordResetRequest represents the request for a password reset code. Identifier is a
+// username, email address or phone number; Username is still accepted from older clients.
+type PasswordResetRequest struct {
+	Identifier string `json:"identifier"`
+	Username   string `json:"username"`
+}
+
+// PasswordResetConfirmRequest represents the password reset payload
+type PasswordResetConfirmRequest struct {
+	Identifier  string `json:"identifier"`
+	Username    string `json:"username"`
+	Code        string `json:"code" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
+}
+
+// OTPRequest represents the request for a WhatsApp sign-in code
+type OTPRequest struct {
+	PhoneNumber string `json:"phone_number" validate:"required"`
+}
+
+// OTPVerifyRequest represents the sign-in code verification payload
+type OTPVerifyRequest struct {
+	PhoneNumber string `json:"phone_number" validate:"required"`
+	Code        string `json:"code" validate:"required"`
 }
 
 // Profile Response Types
@@ -151,8 +194,8 @@ type FarmHarvestWithDetailsResponse struct {
 
 // FarmersListResponse represents farmers list response
 type FarmersListResponse struct {
-	Farmers    []models.Farmer `json:"farmers"`
-	Pagination Pagination      `json:"pagination"`
+	Farmers    []models.FarmerWithCrops `json:"farmers"`
+	Pagination Pagination               `json:"pagination"`
 }
 
 // ExtensionOfficersListResponse represents extension officers list response
@@ -187,8 +230,78 @@ type CropsListResponse struct {
 
 // FarmHarvestsListResponse represents farm harvests list response
 type FarmHarvestsListResponse struct {
-	FarmHarvests []models.FarmHarvest `json:"farm_harvests"`
-	Pagination   Pagination           `json:"pagination"`
+	FarmHarvests []models.FarmHarvestWithDetails `json:"farm_harvests"`
+	Pagination   Pagination                      `json:"pagination"`
+}
+
+// OfficerFarmersListResponse represents the farmers in an extension officer's location
+type OfficerFarmersListResponse struct {
+	Farmers    []models.OfficerFarmerSummary `json:"farmers"`
+	Pagination Pagination                    `json:"pagination"`
+}
+
+// VisitNotesListResponse represents a farmer's visit notes
+type VisitNotesListResponse struct {
+	VisitNotes []models.FarmerVisitNote `json:"visit_notes"`
+}
+
+// HarvestReportResponse represents aggregated harvest totals
+type HarvestReportResponse struct {
+	GroupBy string                      `json:"group_by"`
+	Entries []models.HarvestReportEntry `json:"entries"`
+}
+
+// APIKeyResponse represents an API key; the key itself is only included when it is created
+type APIKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	OwnerID    uuid.UUID  `json:"owner_id"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedBy  *uuid.UUID `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Key        string     `json:"key,omitempty"`
+}
+
+// APIKeysListResponse represents a list of API keys
+type APIKeysListResponse struct {
+	APIKeys []APIKeyResponse `json:"api_keys"`
+}
+
+// UnlockRequest represents the request to lift a sign-in lockout; either field may be empty, but not both
+type UnlockRequest struct {
+	Identifier string `json:"identifier"` // A username, email address or phone number
+	IPAddress  string `json:"ip_address"`
+}
+
+// LockoutsListResponse represents the sign-in lockouts in force
+type LockoutsListResponse struct {
+	Lockouts []models.LoginAttempt `json:"lockouts"`
+}
+
+// UsersListResponse represents a page of users with their roles
+type UsersListResponse struct {
+	Users      []models.UserProfileWithRole `json:"users"`
+	Pagination Pagination                   `json:"pagination"`
+}
+
+// SetRoleRequest represents the request to change a user's role
+type SetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// AssignLocationRequest represents the request to assign an extension officer to a location; null unassigns them
+type AssignLocationRequest struct {
+	AssignedLocationID *int64 `json:"assigned_location_id"`
+}
+
+// AuditLogResponse represents a page of audit events
+type AuditLogResponse struct {
+	Events     []models.AuditEvent `json:"events"`
+	Pagination Pagination          `json:"pagination"`
 }
 
 // Health Response Types
@@ -224,7 +337,7 @@ type ValidationErrorResponse struct {
 
 // Common Response Messages
 const (
-	MsgUserCreatedSuccessfully     = "User created successfully. Profile will be created automatically. Please sign in to get your access token."
+	MsgUserCreatedSuccessfully     = "User created successfully. Please sign in to get your access token."
 	MsgSignInSuccessful           = "Sign in successful"
 	MsgWelcomeToProfile           = "Welcome to your profile"
 	MsgProtectedData              = "This is protected data"
@@ -233,6 +346,7 @@ const (
 	MsgInvalidRequest             = "Invalid request"
 	MsgUnauthorized               = "Unauthorized"
 	MsgForbidden                  = "Forbidden"
+	MsgInvalidMetricsToken        = "A valid metrics token is required"
 	MsgNotFound                   = "Not found"
 	MsgInternalServerError        = "Internal server error"
 	MsgUsernamePasswordRequired   = "Username and password are required"
@@ -244,6 +358,66 @@ const (
 	MsgAuthorizationHeaderRequired = "Authorization header is required"
 	MsgInvalidAuthorizationFormat = "Invalid authorization header format"
 	MsgInvalidOrExpiredToken      = "Invalid or expired token"
+	MsgFarmersRetrieved           = "Farmers retrieved successfully"
+	MsgFarmerRetrieved            = "Farmer retrieved successfully"
+	MsgFarmerCreated              = "Farmer created successfully"
+	MsgFarmerUpdated              = "Farmer updated successfully"
+	MsgFarmerDeleted              = "Farmer deleted successfully"
+	MsgInvalidFarmerID            = "Invalid farmer ID"
+	MsgInvalidRequestBody         = "Invalid request body"
+	MsgHarvestRecorded            = "Harvest recorded successfully"
+	MsgHarvestsRetrieved          = "Harvests retrieved successfully"
+	MsgHarvestReportGenerated     = "Harvest report generated successfully"
+	MsgInvalidCropID              = "Invalid crop ID"
+	MsgCropsRetrieved             = "Crops retrieved successfully"
+	MsgCropRetrieved              = "Crop retrieved successfully"
+	MsgCropCreated                = "Crop created successfully"
+	MsgCropsMerged                = "Crops merged successfully"
+	MsgLocationsRetrieved         = "Locations retrieved successfully"
+	MsgLocationRetrieved          = "Location retrieved successfully"
+	MsgLocationCreated            = "Location created successfully"
+	MsgInvalidLocationID          = "Invalid location ID"
+	MsgVisitNotesRetrieved        = "Visit notes retrieved successfully"
+	MsgVisitNoteAdded             = "Visit note added successfully"
+	MsgTokenRefreshed             = "Token refreshed successfully"
+	MsgFailedToRefreshToken       = "Failed to refresh token"
+	MsgRefreshTokenRequired       = "Refresh token is required"
+	MsgSignedOut                  = "Signed out successfully"
+	MsgSignedOutEverywhere        = "Signed out of every session"
+	MsgOTPSent                    = "If a farmer is registered with this number, a sign-in code was sent to it over WhatsApp"
+	MsgFailedToSendOTP            = "Failed to send sign-in code"
+	MsgFailedToVerifyOTP          = "Failed to verify sign-in code"
+	MsgPhoneNumberRequired        = "Phone number is required"
+	MsgPhoneNumberAndCodeRequired = "Phone number and code are required"
+	MsgPasswordChanged            = "Password changed successfully"
+	MsgFailedToChangePassword     = "Failed to change password"
+	MsgPasswordsRequired          = "Current and new password are required"
+	MsgPasswordResetRequested     = "If the account has a phone number, a reset code was sent to it over WhatsApp"
+	MsgPasswordReset              = "Password reset successfully. Please sign in with your new password."
+	MsgFailedToResetPassword      = "Failed to reset password"
+	MsgIdentifierRequired         = "Username, email address or phone number is required"
+	MsgIdentifierPasswordRequired = "Username, email address or phone number and password are required"
+	MsgResetFieldsRequired        = "Username, email address or phone number, code and new password are required"
+	MsgAPIKeyCreated              = "API key created successfully. Store the key now; it is not shown again."
+	MsgAPIKeysRetrieved           = "API keys retrieved successfully"
+	MsgAPIKeyRevoked              = "API key revoked successfully"
+	MsgInvalidAPIKeyID            = "Invalid API key ID"
+	MsgLockoutsRetrieved          = "Sign-in lockouts retrieved successfully"
+	MsgUnlocked                   = "Sign-in lockout lifted successfully"
+	MsgDataExported               = "Personal data exported successfully"
+	MsgAccountDeleted             = "Account and personal data deleted successfully"
+	MsgExportFormatInvalid        = "format must be json or zip"
+	MsgUsersRetrieved             = "Users retrieved successfully"
+	MsgUserRetrieved              = "User retrieved successfully"
+	MsgRoleChanged                = "Role changed successfully"
+	MsgUserDisabled               = "User disabled successfully"
+	MsgUserEnabled                = "User enabled successfully"
+	MsgOfficerLocationAssigned    = "Extension officer location assigned successfully"
+	MsgRoleRequired               = "Role is required"
+	MsgInvalidUserID              = "Invalid user ID"
+	MsgAuditLogRetrieved          = "Audit log retrieved successfully"
+	MsgDisabledFilterInvalid      = "disabled must be true or false"
+	MsgRateLimited                = "Too many requests; try again later"
 )
 
 // Common Error Codes
@@ -259,4 +433,5 @@ const (
 	ErrCodeMissingConfig      = "MISSING_CONFIG"
 	ErrCodeUserNotFound       = "USER_NOT_FOUND"
 	ErrCodeProfileNotFound    = "PROFILE_NOT_FOUND"
+	ErrCodeRateLimited        = "RATE_LIMITED"
 )
@@ -0,0 +1,191 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/okoye-dev/flux-server/internal/services"
+)
+
+// UsersHandler searches users by ?q (username, email or phone prefix), ?role and ?disabled (admins only)
+func (h *Handler) UsersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := services.UserFilter{
+		Query: strings.TrimSpace(query.Get("q")),
+		Role:  strings.TrimSpace(query.Get("role")),
+	}
+	if value := query.Get("disabled"); value != "" {
+		disabled, err := strconv.ParseBool(value)
+		if err != nil {
+			WriteBadRequestError(w, MsgDisabledFilterInvalid, "")
+			return
+		}
+		filter.Disabled = &disabled
+	}
+
+	page, perPage := parsePagination(r)
+	users, total, err := h.newUserAdminService(nil).ListUsers(filter, page, perPage)
+	if err != nil {
+		WriteServiceError(w, err, "Failed to list users")
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgUsersRetrieved, UsersListResponse{
+		Users:      users,
+		Pagination: newPagination(page, perPage, total),
+	})
+}
+
+// UserHandler returns a user's profile along with their account (admins only)
+func (h *Handler) UserHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	userID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// Reading the account needs the service role key
+	auth, err := newSupabaseAuth()
+	if err != nil {
+		WriteServiceError(w, err, "Failed to get user")
+		return
+	}
+
+	user, err := h.newUserAdminService(auth).GetUser(userID)
+	if err != nil {
+		WriteServiceError(w, err, "Failed to get user")
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgUserRetrieved, user)
+}
+
+// UserRoleHandler changes a user's role (admins only)
+func (h *Handler) UserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	userID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+		return
+	}
+	req.Role = strings.TrimSpace(req.Role)
+	if req.Role == "" {
+		WriteBadRequestError(w, MsgRoleRequired, "")
+		return
+	}
+
+	user, err := h.newUserAdminService(nil).SetRole(userID, req.Role, auditContext(r))
+	if err != nil {
+		WriteServiceError(w, err, "Failed to change role")
+		return
+	}
+
+	// The new role applies from the user's next request rather than when the cache expires
+	h.roles.Invalidate(userID)
+
+	WriteSuccessResponse(w, http.StatusOK, MsgRoleChanged, user)
+}
+
+// DisableUserHandler disables a user's account, signing them out everywhere (admins only)
+func (h *Handler) DisableUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, true)
+}
+
+// EnableUserHandler re-enables a disabled account (admins only)
+func (h *Handler) EnableUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, false)
+}
+
+func (h *Handler) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	userID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// Banning the account needs the service role key
+	auth, err := newSupabaseAuth()
+	if err != nil {
+		WriteServiceError(w, err, "Failed to update user")
+		return
+	}
+
+	user, err := h.newUserAdminService(auth).SetDisabled(userID, disabled, auditContext(r))
+	if err != nil {
+		WriteServiceError(w, err, "Failed to update user")
+		return
+	}
+
+	message := MsgUserEnabled
+	if disabled {
+		message = MsgUserDisabled
+	}
+	WriteSuccessResponse(w, http.StatusOK, message, user)
+}
+
+// OfficerLocationHandler assigns an extension officer to a location (admins only)
+func (h *Handler) OfficerLocationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		WriteMethodNotAllowedError(w)
+		return
+	}
+
+	userID, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req AssignLocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteBadRequestError(w, MsgInvalidRequestBody, err.Error())
+		return
+	}
+
+	officer, err := h.newUserAdminService(nil).AssignOfficerLocation(userID, req.AssignedLocationID, auditContext(r))
+	if err != nil {
+		WriteServiceError(w, err, "Failed to assign location")
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, MsgOfficerLocationAssigned, officer)
+}
+
+// newUserAdminService creates the user admin service; auth may be nil when no account is read or banned
+func (h *Handler) newUserAdminService(auth services.AccountAdmin) *services.UserAdminService {
+	return services.NewUserAdminService(h.store, auth, h.sessions)
+}
+
+// userIDFromPath returns the auth user ID in the path, answering with an error when it's malformed
+func userIDFromPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		WriteBadRequestError(w, MsgInvalidUserID, "")
+		return "", false
+	}
+	return userID.String(), true
+}